@@ -21,51 +21,57 @@ const noDesc = "[No Description]"
 
 // book - Aggregated settings. runbook settings and run settings are aggregated.
 type book struct {
-	desc             string
-	runners          map[string]any
-	vars             map[string]any
-	rawSteps         []map[string]any
-	debug            bool
-	ifCond           string
-	skipTest         bool
-	funcs            map[string]any
-	stepKeys         []string
-	path             string // runbook file path
-	httpRunners      map[string]*httpRunner
-	dbRunners        map[string]*dbRunner
-	grpcRunners      map[string]*grpcRunner
-	cdpRunners       map[string]*cdpRunner
-	sshRunners       map[string]*sshRunner
-	profile          bool
-	intervalStr      string
-	interval         time.Duration
-	loop             *Loop
-	concurrency      string
-	useMap           bool
-	t                *testing.T
-	included         bool
-	force            bool
-	failFast         bool
-	skipIncluded     bool
-	grpcNoTLS        bool
-	grpcProtos       []string
-	grpcImportPaths  []string
-	runID            string
-	runMatch         *regexp.Regexp
-	runSample        int
-	runShardIndex    int
-	runShardN        int
-	runShuffle       bool
-	runShuffleSeed   int64
-	runConcurrent    bool
-	runConcurrentMax int
-	runRandom        int
-	runnerErrs       map[string]error
-	beforeFuncs      []func(*RunResult) error
-	afterFuncs       []func(*RunResult) error
-	capturers        capturers
-	stdout           io.Writer
-	stderr           io.Writer
+	desc                 string
+	runners              map[string]any
+	vars                 map[string]any
+	rawSteps             []map[string]any
+	debug                bool
+	ifCond               string
+	skipTest             bool
+	funcs                map[string]any
+	stepKeys             []string
+	path                 string // runbook file path
+	httpRunners          map[string]*httpRunner
+	dbRunners            map[string]*dbRunner
+	grpcRunners          map[string]*grpcRunner
+	wsRunners            map[string]*wsRunner
+	cdpRunners           map[string]*cdpRunner
+	sshRunners           map[string]*sshRunner
+	profile              bool
+	intervalStr          string
+	interval             time.Duration
+	loop                 *Loop
+	concurrency          string
+	useMap               bool
+	t                    *testing.T
+	included             bool
+	force                bool
+	failFast             bool
+	skipIncluded         bool
+	grpcNoTLS            bool
+	grpcProtos           []string
+	grpcImportPaths      []string
+	grpcProtosets        []string
+	grpcBufImages        []string
+	runID                string
+	runMatch             *regexp.Regexp
+	runSample            int
+	runShardIndex        int
+	runShardN            int
+	runShuffle           bool
+	runShuffleSeed       int64
+	runConcurrent        bool
+	runConcurrentMax     int
+	runRandom            int
+	runnerErrs           map[string]error
+	beforeFuncs          []func(*RunResult) error
+	afterFuncs           []func(*RunResult) error
+	capturers            capturers
+	httpRequestEncoders  httpRequestEncoders
+	httpResponseDecoders httpResponseDecoders
+	openAPI3Coverage     *openAPI3Coverage
+	stdout               io.Writer
+	stderr               io.Writer
 	// skip some errors for `runn list`
 	loadOnly bool
 }
@@ -174,19 +180,25 @@ func (bk *book) parseRunner(k string, v any) error {
 	switch vv := v.(type) {
 	case string:
 		switch {
-		case strings.HasPrefix(vv, "https://") || strings.HasPrefix(vv, "http://"):
+		case strings.HasPrefix(vv, "https://") || strings.HasPrefix(vv, "http://") || strings.HasPrefix(vv, httpUnixScheme+"://"):
 			hc, err := newHTTPRunner(k, vv)
 			if err != nil {
 				return err
 			}
 			bk.httpRunners[k] = hc
-		case strings.HasPrefix(vv, "grpc://"):
+		case strings.HasPrefix(vv, "grpc://") || strings.HasPrefix(vv, grpcUnixScheme+"://"):
 			addr := strings.TrimPrefix(vv, "grpc://")
 			gc, err := newGrpcRunner(k, addr)
 			if err != nil {
 				return err
 			}
 			bk.grpcRunners[k] = gc
+		case isWSEndpoint(vv):
+			wc, err := newWSRunner(k, vv)
+			if err != nil {
+				return err
+			}
+			bk.wsRunners[k] = wc
 		case strings.HasPrefix(vv, "cdp://") || strings.HasPrefix(vv, "chrome://"):
 			remote := strings.TrimPrefix(strings.TrimPrefix(vv, "cdp://"), "chrome://")
 			cc, err := newCDPRunner(k, remote)
@@ -215,12 +227,20 @@ func (bk *book) parseRunner(k string, v any) error {
 		}
 		detect := false
 
-		// HTTP Runner
-		detect, err = bk.parseHTTPRunnerWithDetailed(k, tmp)
+		// WebSocket Runner
+		detect, err = bk.parseWSRunnerWithDetailed(k, tmp)
 		if err != nil {
 			return err
 		}
 
+		// HTTP Runner
+		if !detect {
+			detect, err = bk.parseHTTPRunnerWithDetailed(k, tmp)
+			if err != nil {
+				return err
+			}
+		}
+
 		// gRPC Runner
 		if !detect {
 			detect, err = bk.parseGRPCRunnerWithDetailed(k, tmp)
@@ -237,6 +257,14 @@ func (bk *book) parseRunner(k string, v any) error {
 			}
 		}
 
+		// DB Runner
+		if !detect {
+			detect, err = bk.parseDBRunnerWithDetailed(k, tmp)
+			if err != nil {
+				return err
+			}
+		}
+
 		if !detect {
 			return fmt.Errorf("cannot detect runner: %s", string(tmp))
 		}
@@ -299,6 +327,17 @@ func (bk *book) parseHTTPRunnerWithDetailed(name string, b []byte) (bool, error)
 		}
 	}
 	r.useCookie = c.UseCookie
+	r.auth, err = newHTTPAuth(c.Auth)
+	if err != nil {
+		return false, err
+	}
+	r.sign, err = newHTTPSign(c.Sign, c.Auth)
+	if err != nil {
+		return false, err
+	}
+	if err := r.setDialer(c.Proxy, c.Resolve); err != nil {
+		return false, err
+	}
 	hv, err := newHttpValidator(c)
 	if err != nil {
 		return false, err
@@ -354,10 +393,92 @@ func (bk *book) parseGRPCRunnerWithDetailed(name string, b []byte) (bool, error)
 	r.skipVerify = c.SkipVerify
 	r.importPaths = c.ImportPaths
 	r.protos = c.Protos
+	for _, p := range c.Protosets {
+		r.protosets = append(r.protosets, fp(p, root))
+	}
+	if c.BufImage != "" {
+		r.bufImages = []string{fp(c.BufImage, root)}
+	}
+	if err := r.setDialer(c.Proxy, c.Resolve); err != nil {
+		return false, err
+	}
+	if err := r.setConnConfig(c); err != nil {
+		return false, err
+	}
 	bk.grpcRunners[name] = r
 	return true, nil
 }
 
+func (bk *book) parseWSRunnerWithDetailed(name string, b []byte) (bool, error) {
+	c := &wsRunnerConfig{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return false, nil
+	}
+	if !isWSEndpoint(c.Endpoint) {
+		return false, nil
+	}
+	root, err := bk.generateOperatorRoot()
+	if err != nil {
+		return false, err
+	}
+	r, err := newWSRunner(name, c.Endpoint)
+	if err != nil {
+		return false, err
+	}
+	if c.CACert != "" {
+		b, err := readFile(fp(c.CACert, root))
+		if err != nil {
+			return false, err
+		}
+		r.cacert = b
+	}
+	if c.Cert != "" {
+		b, err := readFile(fp(c.Cert, root))
+		if err != nil {
+			return false, err
+		}
+		r.cert = b
+	}
+	if c.Key != "" {
+		b, err := readFile(fp(c.Key, root))
+		if err != nil {
+			return false, err
+		}
+		r.key = b
+	}
+	r.skipVerify = c.SkipVerify
+	bk.wsRunners[name] = r
+	return true, nil
+}
+
+func (bk *book) parseDBRunnerWithDetailed(name string, b []byte) (bool, error) {
+	c := &dbRunnerConfig{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return false, nil
+	}
+	if c.DSN == "" {
+		return false, nil
+	}
+	db, err := openDB(c.DSN, c.InitSQL, c.ReadOnly)
+	if err != nil {
+		return false, err
+	}
+	nx, err := nestTx(db)
+	if err != nil {
+		return false, err
+	}
+	r := &dbRunner{
+		name:    name,
+		client:  nx,
+		dialect: dbDialectOf(db),
+	}
+	if err := r.setConfig(db, c); err != nil {
+		return false, err
+	}
+	bk.dbRunners[name] = r
+	return true, nil
+}
+
 func (bk *book) parseSSHRunnerWithDetailed(name string, b []byte) (bool, error) {
 	c := &sshRunnerConfig{}
 	if err := yaml.Unmarshal(b, c); err != nil {
@@ -485,6 +606,9 @@ func (bk *book) merge(loaded *book) error {
 	for k, r := range loaded.grpcRunners {
 		bk.grpcRunners[k] = r
 	}
+	for k, r := range loaded.wsRunners {
+		bk.wsRunners[k] = r
+	}
 	for k, r := range loaded.cdpRunners {
 		bk.cdpRunners[k] = r
 	}
@@ -510,6 +634,8 @@ func (bk *book) merge(loaded *book) error {
 	bk.grpcNoTLS = loaded.grpcNoTLS
 	bk.grpcProtos = loaded.grpcProtos
 	bk.grpcImportPaths = loaded.grpcImportPaths
+	bk.grpcProtosets = loaded.grpcProtosets
+	bk.grpcBufImages = loaded.grpcBufImages
 	if loaded.intervalStr != "" {
 		bk.interval = loaded.interval
 	}
@@ -555,6 +681,7 @@ func newBook() *book {
 		httpRunners: map[string]*httpRunner{},
 		dbRunners:   map[string]*dbRunner{},
 		grpcRunners: map[string]*grpcRunner{},
+		wsRunners:   map[string]*wsRunner{},
 		cdpRunners:  map[string]*cdpRunner{},
 		sshRunners:  map[string]*sshRunner{},
 		interval:    0 * time.Second,
@@ -13,6 +13,7 @@ import (
 	"github.com/goccy/go-json"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/k1LoW/runn/testutil"
 	_ "github.com/lib/pq"
 )
 
@@ -151,3 +152,46 @@ func TestParseRunnerForHttpRunner(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRunnerForDBRunner(t *testing.T) {
+	_, dsn := testutil.SQLite(t)
+	tests := []struct {
+		v                 any
+		wantTimeout       time.Duration
+		wantReadOnly      bool
+		wantRollbackOnEnd bool
+		wantErr           bool
+	}{
+		{dsn, 0, false, false, false},
+		{map[string]any{"dsn": dsn}, 0, false, false, false},
+		{map[string]any{"dsn": dsn, "rollbackOnEnd": true}, 0, false, true, false},
+		{map[string]any{"dsn": dsn, "maxOpenConns": 2, "maxIdleConns": 1, "connMaxLifetime": "1m", "timeout": "10sec", "readOnly": true}, 10 * time.Second, true, false, false},
+		{map[string]any{"dsn": dsn, "timeout": "invalid"}, 0, false, false, true},
+		{map[string]any{"dsn": dsn, "maxOpenConns": -1}, 0, false, false, true},
+	}
+	for _, tt := range tests {
+		bk := newBook()
+		if err := bk.parseRunner("db", tt.v); err != nil {
+			if !tt.wantErr {
+				t.Error(err)
+			}
+			continue
+		}
+		if tt.wantErr {
+			t.Error("want error")
+		}
+		got, ok := bk.dbRunners["db"]
+		if !ok {
+			t.Fatal("db runner not found")
+		}
+		if got.timeout != tt.wantTimeout {
+			t.Errorf("got %v\nwant %v", got.timeout, tt.wantTimeout)
+		}
+		if got.readOnly != tt.wantReadOnly {
+			t.Errorf("got %v\nwant %v", got.readOnly, tt.wantReadOnly)
+		}
+		if got.rollbackOnEnd != tt.wantRollbackOnEnd {
+			t.Errorf("got %v\nwant %v", got.rollbackOnEnd, tt.wantRollbackOnEnd)
+		}
+	}
+}
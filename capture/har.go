@@ -0,0 +1,393 @@
+package capture
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goccy/go-json"
+	"github.com/k1LoW/runn"
+	"github.com/k1LoW/runn/version"
+	"go.uber.org/multierr"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_ runn.Capturer           = (*cHAR)(nil)
+	_ runn.HTTPTimingCapturer = (*cHAR)(nil)
+)
+
+const harVersion = "1.2"
+
+// cHAR is the capturer that writes HTTP requests and responses of each runbook run as HAR.
+// ref: http://www.softwareishard.com/blog/har-12-spec/
+type cHAR struct {
+	dir           string
+	currentTrails runn.Trails
+	errs          error
+	hars          sync.Map
+}
+
+type har struct {
+	Log *harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator *harCreator `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+	Comment string      `json:"comment,omitempty"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string       `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         *harRequest  `json:"request"`
+	Response        *harResponse `json:"response"`
+	Cache           struct{}     `json:"cache"`
+	Timings         *harTimings  `json:"timings"`
+	Comment         string       `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*harCookie    `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	QueryString []*harNameValue `json:"queryString"`
+	PostData    *harPostData    `json:"postData,omitempty"`
+	HeadersSize int             `json:"headersSize"`
+	BodySize    int             `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int             `json:"status"`
+	StatusText  string          `json:"statusText"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*harCookie    `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	Content     *harContent     `json:"content"`
+	RedirectURL string          `json:"redirectURL"`
+	HeadersSize int             `json:"headersSize"`
+	BodySize    int             `json:"bodySize"`
+}
+
+type harCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings is the timings of the entry in milliseconds. -1 means that the timing does not apply.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// HAR returns the capturer that writes a HAR file per runbook run to dir.
+// The HAR file is not written for the runbook run without HTTP requests.
+func HAR(dir string) *cHAR {
+	return &cHAR{
+		dir:  dir,
+		hars: sync.Map{},
+	}
+}
+
+func (c *cHAR) CaptureStart(trs runn.Trails, bookPath, desc string) {
+	c.hars.Store(trs[0], &har{
+		Log: &harLog{
+			Version: harVersion,
+			Creator: &harCreator{
+				Name:    version.Name,
+				Version: version.Version,
+			},
+			Entries: []*harEntry{},
+			Comment: desc,
+		},
+	})
+}
+
+func (c *cHAR) CaptureResult(trs runn.Trails, result *runn.RunResult) {
+	if !result.Skipped {
+		c.writeHAR(trs, result.Path)
+	}
+}
+
+func (c *cHAR) CaptureEnd(trs runn.Trails, bookPath, desc string) {}
+
+func (c *cHAR) CaptureHTTPRequest(name string, req *http.Request) {
+	h := c.currentHAR()
+	if h == nil {
+		return
+	}
+	r := &harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []*harCookie{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []*harNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	for _, ck := range req.Cookies() {
+		r.Cookies = append(r.Cookies, &harCookie{Name: ck.Name, Value: ck.Value})
+	}
+	q := req.URL.Query()
+	for _, k := range sortedKeys(q) {
+		for _, v := range q[k] {
+			r.QueryString = append(r.QueryString, &harNameValue{Name: k, Value: v})
+		}
+	}
+	var (
+		save io.ReadCloser
+		err  error
+	)
+	save, req.Body, err = drainBody(req.Body)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, err)
+		return
+	}
+	b, err := io.ReadAll(save)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, err)
+		return
+	}
+	if len(b) > 0 {
+		r.BodySize = len(b)
+		r.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(b),
+		}
+	}
+	h.Log.Entries = append(h.Log.Entries, &harEntry{
+		StartedDateTime: time.Now().Format(time.RFC3339Nano),
+		Request:         r,
+		Timings: &harTimings{
+			Blocked: -1,
+			DNS:     -1,
+			Connect: -1,
+			SSL:     -1,
+		},
+		Comment: name,
+	})
+}
+
+func (c *cHAR) CaptureHTTPResponse(name string, res *http.Response) {
+	e := c.latestEntry()
+	if e == nil {
+		return
+	}
+	r := &harResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Cookies:     []*harCookie{},
+		Headers:     harHeaders(res.Header),
+		RedirectURL: res.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	for _, ck := range res.Cookies() {
+		r.Cookies = append(r.Cookies, &harCookie{Name: ck.Name, Value: ck.Value})
+	}
+	var (
+		save io.ReadCloser
+		err  error
+	)
+	save, res.Body, err = drainBody(res.Body)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, err)
+		return
+	}
+	b, err := io.ReadAll(save)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, err)
+		return
+	}
+	r.BodySize = len(b)
+	r.Content = &harContent{
+		Size:     len(b),
+		MimeType: res.Header.Get("Content-Type"),
+	}
+	if utf8.Valid(b) {
+		r.Content.Text = string(b)
+	} else {
+		r.Content.Text = base64.StdEncoding.EncodeToString(b)
+		r.Content.Encoding = "base64"
+	}
+	e.Response = r
+}
+
+func (c *cHAR) CaptureHTTPTiming(name string, timing *runn.HTTPTiming, tlsInfo *runn.HTTPTLSInfo) {
+	e := c.latestEntry()
+	if e == nil || timing == nil {
+		return
+	}
+	t := &harTimings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		SSL:     -1,
+		Receive: msec(timing.Transfer),
+	}
+	if timing.DNS > 0 {
+		t.DNS = msec(timing.DNS)
+	}
+	if timing.Connect > 0 {
+		// The time of the TLS handshake is also included in connect.
+		t.Connect = msec(timing.Connect + timing.TLSHandshake)
+	}
+	if timing.TLSHandshake > 0 {
+		t.SSL = msec(timing.TLSHandshake)
+	}
+	if wait := timing.TTFB - timing.DNS - timing.Connect - timing.TLSHandshake; wait > 0 {
+		t.Wait = msec(wait)
+	}
+	e.Timings = t
+	e.Time = msec(timing.Total)
+}
+
+func (c *cHAR) CaptureGRPCStart(name string, typ runn.GRPCType, service, method string) {}
+func (c *cHAR) CaptureGRPCRequestHeaders(h map[string][]string)                         {}
+func (c *cHAR) CaptureGRPCRequestMessage(m map[string]any)                              {}
+func (c *cHAR) CaptureGRPCResponseStatus(s *status.Status)                              {}
+func (c *cHAR) CaptureGRPCResponseHeaders(h map[string][]string)                        {}
+func (c *cHAR) CaptureGRPCResponseMessage(m map[string]any)                             {}
+func (c *cHAR) CaptureGRPCResponseTrailers(t map[string][]string)                       {}
+func (c *cHAR) CaptureGRPCClientClose()                                                 {}
+func (c *cHAR) CaptureGRPCEnd(name string, typ runn.GRPCType, service, method string)   {}
+func (c *cHAR) CaptureCDPStart(name string)                                             {}
+func (c *cHAR) CaptureCDPAction(a runn.CDPAction)                                       {}
+func (c *cHAR) CaptureCDPResponse(a runn.CDPAction, res map[string]any)                 {}
+func (c *cHAR) CaptureCDPEnd(name string)                                               {}
+func (c *cHAR) CaptureSSHCommand(command string)                                        {}
+func (c *cHAR) CaptureSSHStdout(stdout string)                                          {}
+func (c *cHAR) CaptureSSHStderr(stderr string)                                          {}
+func (c *cHAR) CaptureDBStatement(name string, stmt string)                             {}
+func (c *cHAR) CaptureDBResponse(name string, res *runn.DBResponse)                     {}
+func (c *cHAR) CaptureExecCommand(command string)                                       {}
+func (c *cHAR) CaptureExecStdin(stdin string)                                           {}
+func (c *cHAR) CaptureExecStdout(stdout string)                                         {}
+func (c *cHAR) CaptureExecStderr(stderr string)                                         {}
+
+func (c *cHAR) SetCurrentTrails(trs runn.Trails) {
+	c.currentTrails = trs
+}
+
+func (c *cHAR) Errs() error {
+	return c.errs
+}
+
+func (c *cHAR) currentHAR() *har {
+	v, ok := c.hars.Load(c.currentTrails[0])
+	if !ok {
+		return nil
+	}
+	h, ok := v.(*har)
+	if !ok {
+		return nil
+	}
+	return h
+}
+
+func (c *cHAR) latestEntry() *harEntry {
+	h := c.currentHAR()
+	if h == nil || len(h.Log.Entries) == 0 {
+		return nil
+	}
+	return h.Log.Entries[len(h.Log.Entries)-1]
+}
+
+func (c *cHAR) writeHAR(trs runn.Trails, bookPath string) {
+	v, ok := c.hars.Load(trs[0])
+	if !ok {
+		return
+	}
+	h, ok := v.(*har)
+	if !ok || len(h.Log.Entries) == 0 {
+		return
+	}
+	for _, e := range h.Log.Entries {
+		if e.Response == nil {
+			// The request that failed to get the response
+			e.Response = &harResponse{
+				Cookies:     []*harCookie{},
+				Headers:     []*harNameValue{},
+				Content:     &harContent{},
+				HeadersSize: -1,
+				BodySize:    -1,
+			}
+		}
+	}
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		c.errs = multierr.Append(c.errs, err)
+		return
+	}
+	p := filepath.Join(c.dir, harFilename(bookPath))
+	if err := os.WriteFile(p, b, os.ModePerm); err != nil {
+		c.errs = multierr.Append(c.errs, err)
+		return
+	}
+}
+
+func harHeaders(h http.Header) []*harNameValue {
+	hs := []*harNameValue{}
+	for _, k := range sortedKeys(h) {
+		for _, v := range h[k] {
+			hs = append(hs, &harNameValue{Name: k, Value: v})
+		}
+	}
+	return hs
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func harFilename(bookPath string) string {
+	return strings.TrimSuffix(capturedFilename(bookPath), filepath.Ext(bookPath)) + ".har"
+}
@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/k1LoW/runn"
+	"github.com/k1LoW/runn/testutil"
+)
+
+func TestHAR(t *testing.T) {
+	book := filepath.Join(testutil.Testdata(), "book", "http.yml")
+	dir := t.TempDir()
+	hs := testutil.HTTPServer(t)
+	opts := []runn.Option{
+		runn.Book(book),
+		runn.HTTPRunner("req", hs.URL, hs.Client(), runn.MultipartBoundary(testutil.MultipartBoundary)),
+		runn.Capture(HAR(dir)),
+	}
+	o, err := runn.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, harFilename(book)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &har{}
+	if err := json.Unmarshal(b, h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Log.Version != harVersion {
+		t.Errorf("got %v\nwant %v", h.Log.Version, harVersion)
+	}
+	if want := 9; len(h.Log.Entries) != want {
+		t.Fatalf("got %v\nwant %v", len(h.Log.Entries), want)
+	}
+	post := h.Log.Entries[1]
+	if post.Request.Method != "POST" || post.Request.PostData == nil || post.Request.PostData.Text == "" {
+		t.Errorf("invalid request: %#v", post.Request)
+	}
+	if post.Response.Status != 201 {
+		t.Errorf("got %v\nwant %v", post.Response.Status, 201)
+	}
+	forbidden := h.Log.Entries[5]
+	if len(forbidden.Request.QueryString) != 1 || forbidden.Request.QueryString[0].Name != "token" {
+		t.Errorf("invalid queryString: %#v", forbidden.Request.QueryString)
+	}
+
+	// The HAR can be converted into the runbook again.
+	f, err := os.Open(filepath.Join(dir, harFilename(book)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = f.Close()
+	})
+	rb := runn.NewRunbook("")
+	if err := rb.AppendStepsFromHAR(f, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(rb.Steps) != len(h.Log.Entries) {
+		t.Errorf("got %v\nwant %v", len(rb.Steps), len(h.Log.Entries))
+	}
+}
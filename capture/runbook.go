@@ -2,9 +2,11 @@ package capture
 
 import (
 	"bytes"
+	"database/sql"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -18,7 +20,13 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-var _ runn.Capturer = (*cRunbook)(nil)
+var (
+	_ runn.Capturer                = (*cRunbook)(nil)
+	_ runn.HTTPEventCapturer       = (*cRunbook)(nil)
+	_ runn.HTTPTimingCapturer      = (*cRunbook)(nil)
+	_ runn.WSCapturer              = (*cRunbook)(nil)
+	_ runn.DBStatementArgsCapturer = (*cRunbook)(nil)
+)
 
 type cRunbook struct {
 	dir           string
@@ -35,10 +43,13 @@ type runbook struct {
 	Runners yaml.MapSlice   `yaml:"runners,omitempty"`
 	Steps   []yaml.MapSlice `yaml:"steps"`
 
+	currentHTTPEventIndex    int
 	currentGRPCType          runn.GRPCType
 	currentGRPCStatus        *status.Status
 	currentGRPCResponceIndex int
 	currentGRPCTestCond      []string
+	currentWSResponseIndex   int
+	currentWSTestCond        []string
 	currentExecTestCond      []string
 }
 
@@ -120,6 +131,7 @@ func (c *cRunbook) CaptureHTTPRequest(name string, req *http.Request) {
 	}
 
 	r.Steps = append(r.Steps, step)
+	r.currentHTTPEventIndex = 0
 }
 
 func (c *cRunbook) CaptureHTTPResponse(name string, res *http.Response) {
@@ -158,7 +170,10 @@ func (c *cRunbook) CaptureHTTPResponse(name string, res *http.Response) {
 		c.errs = multierr.Append(c.errs, fmt.Errorf("failed to drainBody: %w", err))
 		return
 	}
-	if strings.Contains(contentType, "json") {
+	switch {
+	case runn.IsStreamMediaType(contentType):
+		// The body of the streaming response is compared as events by CaptureHTTPResponseEvent.
+	case strings.Contains(contentType, "json"):
 		b, err := io.ReadAll(save)
 		if err != nil {
 			c.errs = multierr.Append(c.errs, fmt.Errorf("failed to io.ReadAll: %w", err))
@@ -170,8 +185,7 @@ func (c *cRunbook) CaptureHTTPResponse(name string, res *http.Response) {
 			return
 		}
 		cond = append(cond, fmt.Sprintf("compare(current.res.body, %s)", buf.String()))
-
-	} else {
+	default:
 		b, err := io.ReadAll(save)
 		if err != nil {
 			c.errs = multierr.Append(c.errs, fmt.Errorf("failed to io.ReadAll: %w", err))
@@ -183,6 +197,50 @@ func (c *cRunbook) CaptureHTTPResponse(name string, res *http.Response) {
 	r.replaceLatestStep(append(step, yaml.MapItem{Key: "test", Value: fmt.Sprintf("%s\n", strings.Join(cond, "\n&& "))}))
 }
 
+func (c *cRunbook) CaptureHTTPResponseEvent(name string, e map[string]any) {
+	r := c.currentRunbook()
+	if r == nil {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, fmt.Errorf("failed to json.Marshal: %w", err))
+		return
+	}
+	cond := fmt.Sprintf("compare(current.res.events[%d], %s)", r.currentHTTPEventIndex, string(b))
+	r.currentHTTPEventIndex += 1
+
+	// Read the same number of events when the runbook is run.
+	step := r.latestStep()
+	hb := httpHeadersAndBody(step)
+	stream := yaml.MapItem{Key: "stream", Value: yaml.MapSlice{{Key: "count", Value: r.currentHTTPEventIndex}}}
+	replaced := false
+	for i, item := range hb {
+		if item.Key == "stream" {
+			hb[i] = stream
+			replaced = true
+		}
+	}
+	if !replaced {
+		hb = append(hb, stream)
+	}
+	step = replaceHTTPHeadersAndBody(step, hb)
+	r.replaceLatestStep(appendTestCond(step, cond))
+}
+
+func (c *cRunbook) CaptureHTTPTiming(name string, timing *runn.HTTPTiming, tlsInfo *runn.HTTPTLSInfo) {
+	// Timing is not used for the test condition because it varies from run to run.
+	if tlsInfo == nil {
+		return
+	}
+	r := c.currentRunbook()
+	if r == nil {
+		return
+	}
+	cond := fmt.Sprintf("current.res.tls.version == %#v", tlsInfo.Version)
+	r.replaceLatestStep(appendTestCond(r.latestStep(), cond))
+}
+
 func (c *cRunbook) CaptureGRPCStart(name string, typ runn.GRPCType, service, method string) {
 	const dummyDsn = "[THIS IS gRPC RUNNER]"
 	if v, ok := c.runners[name]; ok {
@@ -302,6 +360,14 @@ func (c *cRunbook) CaptureGRPCEnd(name string, typ runn.GRPCType, service, metho
 	if cond != "" {
 		r.currentGRPCTestCond = append(r.currentGRPCTestCond, cond)
 	}
+	if details := runn.GRPCStatusDetails(r.currentGRPCStatus); len(details) > 0 {
+		b, err := json.Marshal(details)
+		if err != nil {
+			c.errs = multierr.Append(c.errs, fmt.Errorf("failed to json.Marshal: %w", err))
+		} else {
+			r.currentGRPCTestCond = append(r.currentGRPCTestCond, fmt.Sprintf("compare(current.res.details, %s)", string(b)))
+		}
+	}
 	if len(r.currentGRPCTestCond) == 0 {
 		return
 	}
@@ -312,6 +378,106 @@ func (c *cRunbook) CaptureGRPCEnd(name string, typ runn.GRPCType, service, metho
 	r.currentGRPCResponceIndex = 0
 }
 
+func (c *cRunbook) CaptureWSStart(name, endpoint string, subprotocols []string) {
+	const dummyEndpoint = "[THIS IS WebSocket RUNNER]"
+	if v, ok := c.runners[name]; ok {
+		c.setRunner(name, v)
+	} else {
+		c.setRunner(name, dummyEndpoint)
+	}
+	r := c.currentRunbook()
+	if r == nil {
+		return
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, fmt.Errorf("failed to url.Parse: %w", err))
+		return
+	}
+	hb := yaml.MapSlice{}
+	if len(subprotocols) > 0 {
+		hb = append(hb, yaml.MapItem{Key: "subprotocols", Value: subprotocols})
+	}
+	step := yaml.MapSlice{
+		{Key: name, Value: yaml.MapSlice{
+			{Key: u.RequestURI(), Value: hb},
+		}},
+	}
+	r.Steps = append(r.Steps, step)
+	r.currentWSTestCond = nil
+	r.currentWSResponseIndex = 0
+}
+
+func (c *cRunbook) CaptureWSRequestHeaders(h map[string][]string) {
+	if len(h) == 0 {
+		return
+	}
+	hh := map[string]string{}
+	for k, v := range h {
+		hh[k] = v[0]
+	}
+	r := c.currentRunbook()
+	step := r.latestStep()
+	hb := headersAndMessages(step)
+	hb = append(yaml.MapSlice{{Key: "headers", Value: hh}}, hb...)
+	step = replaceHeadersAndMessages(step, hb)
+	r.replaceLatestStep(step)
+}
+
+func (c *cRunbook) CaptureWSRequestMessage(m any) {
+	r := c.currentRunbook()
+	step := r.latestStep()
+	hb := c.appendOp(headersAndMessages(step), m)
+	step = replaceHeadersAndMessages(step, hb)
+	r.replaceLatestStep(step)
+}
+
+func (c *cRunbook) CaptureWSResponseHeaders(h map[string][]string) {
+	r := c.currentRunbook()
+	r.currentWSTestCond = append(r.currentWSTestCond, fmt.Sprintf("current.res.status == %d", http.StatusSwitchingProtocols))
+	// Other headers such as Sec-Websocket-Accept vary from run to run.
+	if v := http.Header(h).Get("Sec-Websocket-Protocol"); v != "" {
+		r.currentWSTestCond = append(r.currentWSTestCond, fmt.Sprintf("current.res.subprotocol == %#v", v))
+	}
+}
+
+func (c *cRunbook) CaptureWSResponseMessage(m any) {
+	r := c.currentRunbook()
+	step := r.latestStep()
+	hb := c.appendOp(headersAndMessages(step), runn.WSOpReceive)
+	step = replaceHeadersAndMessages(step, hb)
+	r.replaceLatestStep(step)
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		c.errs = multierr.Append(c.errs, fmt.Errorf("failed to json.Marshal: %w", err))
+		return
+	}
+	cond := fmt.Sprintf("compare(current.res.messages[%d], %s)", r.currentWSResponseIndex, string(b))
+	r.currentWSTestCond = append(r.currentWSTestCond, cond)
+	r.currentWSResponseIndex += 1
+}
+
+func (c *cRunbook) CaptureWSClientClose() {
+	r := c.currentRunbook()
+	step := r.latestStep()
+	hb := c.appendOp(headersAndMessages(step), runn.WSOpClose)
+	step = replaceHeadersAndMessages(step, hb)
+	r.replaceLatestStep(step)
+}
+
+func (c *cRunbook) CaptureWSEnd(name, endpoint string) {
+	r := c.currentRunbook()
+	if len(r.currentWSTestCond) == 0 {
+		return
+	}
+	step := r.latestStep()
+	step = append(step, yaml.MapItem{Key: "test", Value: fmt.Sprintf("%s\n", strings.Join(r.currentWSTestCond, "\n&& "))})
+	r.replaceLatestStep(step)
+	r.currentWSTestCond = nil
+	r.currentWSResponseIndex = 0
+}
+
 func (c *cRunbook) CaptureCDPStart(name string) {
 	// FIXME: not implemented
 }
@@ -338,6 +504,10 @@ func (c *cRunbook) CaptureSSHStderr(stderr string) {
 }
 
 func (c *cRunbook) CaptureDBStatement(name string, stmt string) {
+	c.CaptureDBStatementArgs(name, stmt, nil)
+}
+
+func (c *cRunbook) CaptureDBStatementArgs(name string, stmt string, args []any) {
 	const dummyDsn = "[THIS IS DB RUNNER]"
 	if v, ok := c.runners[name]; ok {
 		c.setRunner(name, v)
@@ -348,14 +518,31 @@ func (c *cRunbook) CaptureDBStatement(name string, stmt string) {
 	if r == nil {
 		return
 	}
+	q := yaml.MapSlice{
+		{Key: "query", Value: fmt.Sprintf("%s\n", stmt)},
+	}
+	if len(args) > 0 {
+		q = append(q, yaml.MapItem{Key: "args", Value: dbArgs(args)})
+	}
 	step := yaml.MapSlice{
-		{Key: name, Value: yaml.MapSlice{
-			{Key: "query", Value: fmt.Sprintf("%s\n", stmt)},
-		}},
+		{Key: name, Value: q},
 	}
 	r.Steps = append(r.Steps, step)
 }
 
+// dbArgs returns the args as the value of `args:`. The named args are returned as the map.
+func dbArgs(args []any) any {
+	named := yaml.MapSlice{}
+	for _, a := range args {
+		na, ok := a.(sql.NamedArg)
+		if !ok {
+			return args
+		}
+		named = append(named, yaml.MapItem{Key: na.Name, Value: na.Value})
+	}
+	return named
+}
+
 func (c *cRunbook) CaptureDBResponse(name string, res *runn.DBResponse) {
 	const threshold = 3
 
@@ -495,27 +682,20 @@ func (c *cRunbook) captureGRPCResponseMetadata(key string, m map[string][]string
 }
 
 func (c *cRunbook) appendOp(hb yaml.MapSlice, m any) yaml.MapSlice {
-	switch {
-	case len(hb) == 0 || (len(hb) == 1 && hb[0].Key == "headers"):
-		hb = append(hb, yaml.MapItem{Key: "messages", Value: []any{m}})
-	case hb[0].Key == "messages":
-		ms, ok := hb[0].Value.([]any)
-		if !ok {
-			c.errs = multierr.Append(c.errs, fmt.Errorf("failed to get hb[0].Value: %s", hb[0].Value))
-			return hb
+	for i, item := range hb {
+		if item.Key != "messages" {
+			continue
 		}
-		ms = append(ms, m)
-		hb[0].Value = ms
-	case hb[1].Key == "messages":
-		ms, ok := hb[1].Value.([]any)
+		ms, ok := item.Value.([]any)
 		if !ok {
-			c.errs = multierr.Append(c.errs, fmt.Errorf("failed to get hb[1].Value: %s", hb[1].Value))
+			c.errs = multierr.Append(c.errs, fmt.Errorf("failed to get hb[%d].Value: %s", i, item.Value))
 			return hb
 		}
 		ms = append(ms, m)
-		hb[1].Value = ms
+		hb[i].Value = ms
+		return hb
 	}
-	return hb
+	return append(hb, yaml.MapItem{Key: "messages", Value: []any{m}})
 }
 
 func (c *cRunbook) writeRunbook(trs runn.Trails, bookPath string) {
@@ -554,6 +734,32 @@ func (r *runbook) replaceLatestStep(rep yaml.MapSlice) {
 	r.Steps[len(r.Steps)-1] = rep
 }
 
+// appendTestCond appends the condition to the test of the step.
+func appendTestCond(step yaml.MapSlice, cond string) yaml.MapSlice {
+	for i, item := range step {
+		if item.Key != "test" {
+			continue
+		}
+		t, ok := item.Value.(string)
+		if !ok {
+			return step
+		}
+		step[i].Value = fmt.Sprintf("%s\n&& %s\n", strings.TrimSuffix(t, "\n"), cond)
+		return step
+	}
+	return append(step, yaml.MapItem{Key: "test", Value: fmt.Sprintf("%s\n", cond)})
+}
+
+func httpHeadersAndBody(step yaml.MapSlice) yaml.MapSlice {
+	hb, _ := step[0].Value.(yaml.MapSlice)[0].Value.(yaml.MapSlice)[0].Value.(yaml.MapSlice)
+	return hb
+}
+
+func replaceHTTPHeadersAndBody(step, hb yaml.MapSlice) yaml.MapSlice {
+	step[0].Value.(yaml.MapSlice)[0].Value.(yaml.MapSlice)[0].Value = hb
+	return step
+}
+
 func headersAndMessages(step yaml.MapSlice) yaml.MapSlice {
 	return step[0].Value.(yaml.MapSlice)[0].Value.(yaml.MapSlice)
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"testing"
 
@@ -19,8 +20,11 @@ func TestRunbook(t *testing.T) {
 	}{
 		{filepath.Join(testutil.Testdata(), "book", "http.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "http_multipart.yml")},
+		{filepath.Join(testutil.Testdata(), "book", "http_stream.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "grpc.yml")},
+		{filepath.Join(testutil.Testdata(), "book", "ws.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "db.yml")},
+		{filepath.Join(testutil.Testdata(), "book", "db_args.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "exec.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "include_main.yml")},
 	}
@@ -30,11 +34,13 @@ func TestRunbook(t *testing.T) {
 			dir := t.TempDir()
 			hs := testutil.HTTPServer(t)
 			gs := testutil.GRPCServer(t, false, false)
+			ws := testutil.WSServer(t)
 			db, _ := testutil.SQLite(t)
 			opts := []runn.Option{
 				runn.Book(tt.book),
 				runn.HTTPRunner("req", hs.URL, hs.Client(), runn.MultipartBoundary(testutil.MultipartBoundary)),
 				runn.GrpcRunner("greq", gs.Conn()),
+				runn.WSRunner("ws", "ws"+strings.TrimPrefix(ws.URL, "http")),
 				runn.DBRunner("db", db),
 				runn.Capture(Runbook(dir)),
 			}
@@ -66,7 +72,9 @@ func TestRunnable(t *testing.T) {
 	}{
 		{filepath.Join(testutil.Testdata(), "book", "http.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "http_multipart.yml")},
+		{filepath.Join(testutil.Testdata(), "book", "http_stream.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "grpc.yml")},
+		{filepath.Join(testutil.Testdata(), "book", "ws.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "db.yml")},
 		{filepath.Join(testutil.Testdata(), "book", "exec.yml")},
 	}
@@ -76,11 +84,13 @@ func TestRunnable(t *testing.T) {
 			dir := t.TempDir()
 			hs := testutil.HTTPServer(t)
 			gs := testutil.GRPCServer(t, false, false)
+			ws := testutil.WSServer(t)
 			db, _ := testutil.SQLite(t)
 			opts := []runn.Option{
 				runn.Book(tt.book),
 				runn.HTTPRunner("req", hs.URL, hs.Client(), runn.MultipartBoundary(testutil.MultipartBoundary)),
 				runn.GrpcRunner("greq", gs.Conn()),
+				runn.WSRunner("ws", "ws"+strings.TrimPrefix(ws.URL, "http")),
 				runn.DBRunner("db", db),
 				runn.Capture(Runbook(dir)),
 			}
@@ -97,6 +107,7 @@ func TestRunnable(t *testing.T) {
 					runn.Book(filepath.Join(dir, capturedFilename(tt.book))),
 					runn.HTTPRunner("req", hs.URL, hs.Client(), runn.MultipartBoundary(testutil.MultipartBoundary)),
 					runn.GrpcRunner("greq", gs.Conn()),
+					runn.WSRunner("ws", "ws"+strings.TrimPrefix(ws.URL, "http")),
 					runn.DBRunner("db", db),
 				}
 				o, err := runn.New(opts...)
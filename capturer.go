@@ -46,6 +46,33 @@ type Capturer interface {
 	Errs() error
 }
 
+// HTTPEventCapturer is the optional interface of Capturer to capture the events of the streaming HTTP response.
+type HTTPEventCapturer interface {
+	CaptureHTTPResponseEvent(name string, e map[string]any)
+}
+
+// HTTPTimingCapturer is the optional interface of Capturer to capture the timing and the TLS information of the HTTP request.
+type HTTPTimingCapturer interface {
+	CaptureHTTPTiming(name string, timing *HTTPTiming, tlsInfo *HTTPTLSInfo)
+}
+
+// WSCapturer is the optional interface of Capturer to capture the WebSocket connection and messages.
+type WSCapturer interface {
+	CaptureWSStart(name, endpoint string, subprotocols []string)
+	CaptureWSRequestHeaders(h map[string][]string)
+	CaptureWSRequestMessage(m any)
+	CaptureWSResponseHeaders(h map[string][]string)
+	CaptureWSResponseMessage(m any)
+	CaptureWSClientClose()
+	CaptureWSEnd(name, endpoint string)
+}
+
+// DBStatementArgsCapturer is the optional interface of Capturer to capture the DB statement with the bound args.
+// If the Capturer implements it, CaptureDBStatementArgs is called instead of CaptureDBStatement.
+type DBStatementArgsCapturer interface {
+	CaptureDBStatementArgs(name string, stmt string, args []any)
+}
+
 type capturers []Capturer
 
 func (cs capturers) captureStart(trs Trails, bookPath, desc string) {
@@ -78,6 +105,22 @@ func (cs capturers) captureHTTPResponse(name string, res *http.Response) {
 	}
 }
 
+func (cs capturers) captureHTTPResponseEvent(name string, e map[string]any) {
+	for _, c := range cs {
+		if ec, ok := c.(HTTPEventCapturer); ok {
+			ec.CaptureHTTPResponseEvent(name, e)
+		}
+	}
+}
+
+func (cs capturers) captureHTTPTiming(name string, timing *HTTPTiming, tlsInfo *HTTPTLSInfo) {
+	for _, c := range cs {
+		if tc, ok := c.(HTTPTimingCapturer); ok {
+			tc.CaptureHTTPTiming(name, timing, tlsInfo)
+		}
+	}
+}
+
 func (cs capturers) captureGRPCStart(name string, typ GRPCType, service, method string) {
 	for _, c := range cs {
 		c.CaptureGRPCStart(name, typ, service, method)
@@ -131,6 +174,62 @@ func (cs capturers) captureGRPCEnd(name string, typ GRPCType, service, method st
 	}
 }
 
+func (cs capturers) captureWSStart(name, endpoint string, subprotocols []string) {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSStart(name, endpoint, subprotocols)
+		}
+	}
+}
+
+func (cs capturers) captureWSRequestHeaders(h http.Header) {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSRequestHeaders(h)
+		}
+	}
+}
+
+func (cs capturers) captureWSRequestMessage(m any) {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSRequestMessage(m)
+		}
+	}
+}
+
+func (cs capturers) captureWSResponseHeaders(h http.Header) {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSResponseHeaders(h)
+		}
+	}
+}
+
+func (cs capturers) captureWSResponseMessage(m any) {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSResponseMessage(m)
+		}
+	}
+}
+
+func (cs capturers) captureWSClientClose() {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSClientClose()
+		}
+	}
+}
+
+func (cs capturers) captureWSEnd(name, endpoint string) {
+	for _, c := range cs {
+		if wc, ok := c.(WSCapturer); ok {
+			wc.CaptureWSEnd(name, endpoint)
+		}
+	}
+}
+
 func (cs capturers) captureCDPStart(name string) {
 	for _, c := range cs {
 		c.CaptureCDPStart(name)
@@ -173,8 +272,12 @@ func (cs capturers) captureSSHStderr(stderr string) {
 	}
 }
 
-func (cs capturers) captureDBStatement(name string, stmt string) {
+func (cs capturers) captureDBStatement(name string, stmt string, args []any) {
 	for _, c := range cs {
+		if ac, ok := c.(DBStatementArgsCapturer); ok {
+			ac.CaptureDBStatementArgs(name, stmt, args)
+			continue
+		}
 		c.CaptureDBStatement(name, stmt)
 	}
 }
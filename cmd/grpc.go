@@ -0,0 +1,206 @@
+/*
+Copyright © 2022 Ken'ichiro Oyama <k1lowxb@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k1LoW/runn"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// grpcCmd represents the grpc command.
+var grpcCmd = &cobra.Command{
+	Use:   "grpc",
+	Short: "inspect gRPC services",
+	Long:  `inspect gRPC services using the server reflection or proto sources.`,
+}
+
+// grpcLsCmd represents the grpc ls command.
+var grpcLsCmd = &cobra.Command{
+	Use:     "ls [RUNNER_OR_ADDR] [SERVICE]",
+	Short:   "list gRPC methods",
+	Long:    `list gRPC methods.`,
+	Aliases: []string{"list"},
+	Args:    cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		_, methods, err := grpcMethods(ctx, args[0], args[1:]...)
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"method:", "type:", "request:", "response:"})
+		table.SetAutoWrapText(false)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoFormatHeaders(false)
+		table.SetCenterSeparator("")
+		table.SetColumnSeparator("")
+		table.SetRowSeparator("-")
+		table.SetHeaderLine(true)
+		table.SetBorder(false)
+		for _, m := range methods {
+			table.Append([]string{m.Name(), string(m.Type), string(m.Request.FullName()), string(m.Response.FullName())})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+// grpcDescribeCmd represents the grpc describe command.
+var grpcDescribeCmd = &cobra.Command{
+	Use:   "describe [RUNNER_OR_ADDR] [SERVICE_OR_METHOD]",
+	Short: "describe request and response messages of gRPC methods",
+	Long:  `describe request and response messages of gRPC methods as runbook YAML, or append steps calling them to runbook.`,
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		runner, methods, err := grpcMethods(ctx, args[0], args[1:]...)
+		if err != nil {
+			return err
+		}
+		if flgs.Out == "" {
+			enc := yaml.NewEncoder(os.Stdout)
+			for _, m := range methods {
+				if err := enc.Encode(yaml.MapSlice{
+					{Key: "method", Value: m.Name()},
+					{Key: "type", Value: string(m.Type)},
+					{Key: "request", Value: runn.GRPCMessageSkeleton(m.Request)},
+					{Key: "response", Value: runn.GRPCMessageSkeleton(m.Response)},
+				}); err != nil {
+					return err
+				}
+			}
+			return enc.Close()
+		}
+
+		rb := runn.NewRunbook(flgs.Desc)
+		p := filepath.Clean(flgs.Out)
+		if _, err := os.Stat(p); err == nil {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			rb, err = runn.ParseRunbook(f)
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		if runner != nil {
+			// Copy the runner defined in the runbook specified by --book
+			if _, ok := rb.Runners[args[0]]; !ok {
+				rb.Runners[args[0]] = runner
+			}
+		}
+		for _, m := range methods {
+			if err := rb.AppendGRPCStep(args[0], m); err != nil {
+				return err
+			}
+		}
+		b, err := yaml.Marshal(rb)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(p, b, 0600)
+	},
+}
+
+// grpcMethods returns the methods of the runner defined in the runbook specified by --book or the gRPC server of the address.
+// When the runner is defined in the runbook, the definition of the runner is also returned.
+func grpcMethods(ctx context.Context, target string, filter ...string) (any, []*runn.GRPCMethod, error) {
+	const key = "greq"
+	var runner any
+	opts := []runn.Option{
+		runn.GRPCNoTLS(flgs.GRPCNoTLS),
+		runn.GRPCProtos(flgs.GRPCProtos),
+		runn.GRPCImportPaths(flgs.GRPCImportPaths),
+		runn.GRPCProtosets(flgs.GRPCProtosets),
+		runn.GRPCBufImages(flgs.GRPCBufImages),
+	}
+	if flgs.Book != "" {
+		f, err := os.Open(filepath.Clean(flgs.Book))
+		if err != nil {
+			return nil, nil, err
+		}
+		rb, err := runn.ParseRunbook(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		runner = rb.Runners[target]
+	}
+	rkey := target
+	if runner != nil {
+		opts = append(opts, runn.Book(flgs.Book))
+	} else {
+		rkey = key
+		dsn := target
+		if !strings.HasPrefix(dsn, "grpc://") && !strings.HasPrefix(dsn, "grpcs://") {
+			dsn = fmt.Sprintf("grpc://%s", dsn)
+		}
+		opts = append(opts, runn.Runner(rkey, dsn))
+	}
+	o, err := runn.New(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer o.Close()
+	methods, err := o.GRPCMethods(ctx, rkey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(filter) == 0 {
+		return runner, methods, nil
+	}
+	filtered := []*runn.GRPCMethod{}
+	for _, m := range methods {
+		if m.Name() == filter[0] || m.Service == filter[0] {
+			filtered = append(filtered, m)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, nil, fmt.Errorf("cannot find service or method: %s", filter[0])
+	}
+	return runner, filtered, nil
+}
+
+func init() {
+	rootCmd.AddCommand(grpcCmd)
+	grpcCmd.AddCommand(grpcLsCmd, grpcDescribeCmd)
+	for _, c := range []*cobra.Command{grpcLsCmd, grpcDescribeCmd} {
+		c.Flags().StringVarP(&flgs.Book, "book", "", "", flgs.Usage("Book"))
+		c.Flags().BoolVarP(&flgs.GRPCNoTLS, "grpc-no-tls", "", false, flgs.Usage("GRPCNoTLS"))
+		c.Flags().StringSliceVarP(&flgs.GRPCProtos, "grpc-proto", "", []string{}, flgs.Usage("GRPCProtos"))
+		c.Flags().StringSliceVarP(&flgs.GRPCImportPaths, "grpc-import-path", "", []string{}, flgs.Usage("GRPCImportPaths"))
+		c.Flags().StringSliceVarP(&flgs.GRPCProtosets, "grpc-protoset", "", []string{}, flgs.Usage("GRPCProtosets"))
+		c.Flags().StringSliceVarP(&flgs.GRPCBufImages, "grpc-buf-image", "", []string{}, flgs.Usage("GRPCBufImages"))
+	}
+	grpcDescribeCmd.Flags().StringVarP(&flgs.Desc, "desc", "", "", flgs.Usage("Desc"))
+	grpcDescribeCmd.Flags().StringVarP(&flgs.Out, "out", "", "", flgs.Usage("Out"))
+}
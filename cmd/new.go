@@ -29,6 +29,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/k1LoW/runn"
 	"github.com/k1LoW/runn/capture"
@@ -49,12 +50,20 @@ var newCmd = &cobra.Command{
 			err error
 			al  [][]string
 		)
-		if len(args) == 0 {
+		if flgs.OpenAPI3 != "" {
+			return newFromOpenAPI3()
+		}
+		switch {
+		case flgs.HAR != "":
+			if len(args) > 0 {
+				return errors.New("arguments cannot be used with --har")
+			}
+		case len(args) == 0:
 			if isatty.IsTerminal(os.Stdin.Fd()) {
 				return errors.New("interactive mode is planned, but not yet implemented")
 			}
 			al = argsListFromStdin(os.Stdin)
-		} else {
+		default:
 			al = [][]string{args}
 		}
 		ctx := context.Background()
@@ -80,6 +89,19 @@ var newCmd = &cobra.Command{
 				return err
 			}
 		}
+		if flgs.HAR != "" {
+			f, err := os.Open(filepath.Clean(flgs.HAR))
+			if err != nil {
+				return err
+			}
+			if err := rb.AppendStepsFromHAR(f, flgs.HARHosts, flgs.HARMethods); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
 		if flgs.Out == "" {
 			o = os.Stdout
 		} else {
@@ -122,9 +144,57 @@ func init() {
 	newCmd.Flags().StringVarP(&flgs.Desc, "desc", "", "", flgs.Usage("Desc"))
 	newCmd.Flags().StringVarP(&flgs.Out, "out", "", "", flgs.Usage("Out"))
 	newCmd.Flags().BoolVarP(&flgs.AndRun, "and-run", "", false, flgs.Usage("AndRun"))
+	newCmd.Flags().StringVarP(&flgs.OpenAPI3, "openapi", "", "", flgs.Usage("OpenAPI3"))
+	newCmd.Flags().StringVarP(&flgs.OpenAPI3GroupBy, "openapi-group-by", "", runn.OpenAPI3GroupByOperation, flgs.Usage("OpenAPI3GroupBy"))
+	newCmd.Flags().StringVarP(&flgs.HAR, "har", "", "", flgs.Usage("HAR"))
+	newCmd.Flags().StringSliceVarP(&flgs.HARHosts, "har-host", "", []string{}, flgs.Usage("HARHosts"))
+	newCmd.Flags().StringSliceVarP(&flgs.HARMethods, "har-method", "", []string{}, flgs.Usage("HARMethods"))
 	newCmd.Flags().BoolVarP(&flgs.GRPCNoTLS, "grpc-no-tls", "", false, flgs.Usage("GRPCNoTLS"))
 	newCmd.Flags().StringSliceVarP(&flgs.GRPCProtos, "grpc-proto", "", []string{}, flgs.Usage("GRPCProtos"))
 	newCmd.Flags().StringSliceVarP(&flgs.GRPCImportPaths, "grpc-import-path", "", []string{}, flgs.Usage("GRPCImportPaths"))
+	newCmd.Flags().StringSliceVarP(&flgs.GRPCProtosets, "grpc-protoset", "", []string{}, flgs.Usage("GRPCProtosets"))
+	newCmd.Flags().StringSliceVarP(&flgs.GRPCBufImages, "grpc-buf-image", "", []string{}, flgs.Usage("GRPCBufImages"))
+}
+
+// newFromOpenAPI3 generates runbooks from the OpenAPI 3 document.
+// The runbooks are written to the directory specified by --out, or to STDOUT as multiple YAML documents.
+func newFromOpenAPI3() error {
+	if flgs.AndRun {
+		return errors.New("--and-run is not supported with --openapi")
+	}
+	rbs, err := runn.NewRunbooksFromOpenAPI3(flgs.OpenAPI3, flgs.OpenAPI3GroupBy, flgs.Out)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(rbs))
+	for k := range rbs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if flgs.Out == "" {
+		enc := yaml.NewEncoder(os.Stdout)
+		for _, k := range names {
+			if err := enc.Encode(rbs[k]); err != nil {
+				return err
+			}
+		}
+		return enc.Close()
+	}
+	if err := os.MkdirAll(filepath.Clean(flgs.Out), 0750); err != nil {
+		return err
+	}
+	for _, k := range names {
+		p := filepath.Join(flgs.Out, fmt.Sprintf("%s.yml", k))
+		b, err := yaml.Marshal(rbs[k])
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Clean(p), b, 0600); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(os.Stderr, p)
+	}
+	return nil
 }
 
 func runAndCapture(ctx context.Context, o *os.File, fn func(*os.File) error) error {
@@ -155,6 +225,8 @@ func runAndCapture(ctx context.Context, o *os.File, fn func(*os.File) error) err
 		runn.GRPCNoTLS(flgs.GRPCNoTLS),
 		runn.GRPCProtos(flgs.GRPCProtos),
 		runn.GRPCImportPaths(flgs.GRPCImportPaths),
+		runn.GRPCProtosets(flgs.GRPCProtosets),
+		runn.GRPCBufImages(flgs.GRPCBufImages),
 	}
 	oo, err := runn.New(opts...)
 	if err != nil {
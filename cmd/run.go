@@ -48,6 +48,10 @@ var runCmd = &cobra.Command{
 		if flgs.Format == "" {
 			opts = append(opts, runn.Capture(runn.NewCmdOut(os.Stdout, flgs.Verbose)))
 		}
+		cov := runn.NewOpenAPI3Coverage()
+		if flgs.OpenAPICoverage || flgs.OpenAPICoverageOut != "" {
+			opts = append(opts, runn.OpenAPI3Coverage(cov))
+		}
 
 		// setup cache dir
 		if err := runn.SetCacheDir(flgs.CacheDir); err != nil {
@@ -79,6 +83,33 @@ var runCmd = &cobra.Command{
 			}
 		}
 
+		switch {
+		case flgs.OpenAPICoverageOut != "":
+			c, err := os.Create(filepath.Clean(flgs.OpenAPICoverageOut))
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := c.Close(); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "%s\n", err)
+					os.Exit(1)
+				}
+			}()
+			if err := cov.OutJSON(c); err != nil {
+				return err
+			}
+		case flgs.OpenAPICoverage:
+			// Keep stdout valid JSON with --format json
+			w := os.Stdout
+			if flgs.Format == "json" {
+				w = os.Stderr
+			}
+			_, _ = fmt.Fprintln(w, "")
+			if err := cov.Out(w); err != nil {
+				return err
+			}
+		}
+
 		if flgs.Profile {
 			p, err := os.Create(filepath.Clean(flgs.ProfileOut))
 			if err != nil {
@@ -111,7 +142,10 @@ func init() {
 	runCmd.Flags().BoolVarP(&flgs.GRPCNoTLS, "grpc-no-tls", "", false, flgs.Usage("GRPCNoTLS"))
 	runCmd.Flags().StringSliceVarP(&flgs.GRPCProtos, "grpc-proto", "", []string{}, flgs.Usage("GRPCProtos"))
 	runCmd.Flags().StringSliceVarP(&flgs.GRPCImportPaths, "grpc-import-path", "", []string{}, flgs.Usage("GRPCImportPaths"))
+	runCmd.Flags().StringSliceVarP(&flgs.GRPCProtosets, "grpc-protoset", "", []string{}, flgs.Usage("GRPCProtosets"))
+	runCmd.Flags().StringSliceVarP(&flgs.GRPCBufImages, "grpc-buf-image", "", []string{}, flgs.Usage("GRPCBufImages"))
 	runCmd.Flags().StringVarP(&flgs.CaptureDir, "capture", "", "", flgs.Usage("CaptureDir"))
+	runCmd.Flags().StringVarP(&flgs.CaptureHARDir, "capture-har", "", "", flgs.Usage("CaptureHARDir"))
 	runCmd.Flags().StringSliceVarP(&flgs.Vars, "var", "", []string{}, flgs.Usage("Vars"))
 	runCmd.Flags().StringSliceVarP(&flgs.Runners, "runner", "", []string{}, flgs.Usage("Runners"))
 	runCmd.Flags().StringSliceVarP(&flgs.Overlays, "overlay", "", []string{}, flgs.Usage("Overlays"))
@@ -127,6 +161,8 @@ func init() {
 	runCmd.Flags().StringVarP(&flgs.Format, "format", "", "", flgs.Usage("Format"))
 	runCmd.Flags().BoolVarP(&flgs.Profile, "profile", "", false, flgs.Usage("Profile"))
 	runCmd.Flags().StringVarP(&flgs.ProfileOut, "profile-out", "", "runn.prof", flgs.Usage("ProfileOut"))
+	runCmd.Flags().BoolVarP(&flgs.OpenAPICoverage, "openapi-coverage", "", false, flgs.Usage("OpenAPICoverage"))
+	runCmd.Flags().StringVarP(&flgs.OpenAPICoverageOut, "openapi-coverage-out", "", "", flgs.Usage("OpenAPICoverageOut"))
 	runCmd.Flags().StringVarP(&flgs.CacheDir, "cache-dir", "", "", flgs.Usage("CacheDir"))
 	runCmd.Flags().BoolVarP(&flgs.RetainCacheDir, "retain-cache-dir", "", false, flgs.Usage("RetainCacheDir"))
 	runCmd.Flags().BoolVarP(&flgs.Verbose, "verbose", "", false, flgs.Usage("Verbose"))
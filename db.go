@@ -3,10 +3,13 @@ package runn
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/araddon/dateparse"
@@ -14,6 +17,7 @@ import (
 	"github.com/golang-sql/sqlexp"
 	"github.com/golang-sql/sqlexp/nest"
 	_ "github.com/googleapis/go-sql-spanner"
+	"github.com/k1LoW/duration"
 	"github.com/xo/dburl"
 	"modernc.org/sqlite"
 )
@@ -22,6 +26,7 @@ const (
 	dbStoreLastInsertIDKey = "last_insert_id"
 	dbStoreRowsAffectedKey = "rows_affected"
 	dbStoreRowsKey         = "rows"
+	dbStoreResultSetsKey   = "result_sets"
 )
 
 type Querier interface {
@@ -33,14 +38,40 @@ type TxQuerier interface {
 	BeginTx(ctx context.Context, opts *nest.TxOptions) (*nest.Tx, error)
 }
 
+const (
+	dbTxBegin    = "begin"
+	dbTxCommit   = "commit"
+	dbTxRollback = "rollback"
+)
+
 type dbRunner struct {
-	name     string
-	client   TxQuerier
-	operator *operator
+	name   string
+	client TxQuerier
+	// txs are the transactions spanning multiple steps. The nested transactions are savepoints.
+	txs []*dbTx
+	// dialect is the SQL dialect used to classify statements.
+	dialect string
+	// timeout is the timeout of each query.
+	timeout time.Duration
+	// readOnly rejects the statements that may write.
+	readOnly bool
+	// rollbackOnEnd wraps the whole runbook in one transaction and always rolls it back.
+	rollbackOnEnd bool
+	// fixtureRows are the rows inserted by the fixtures to be deleted at the end of the runbook.
+	fixtureRows []*dbFixtureRow
+	operator    *operator
 }
 
 type dbQuery struct {
 	stmt string
+	// args are bound to the placeholders of the statement. The named args are sql.NamedArg.
+	args []any
+	// txOp is the transaction control (begin, commit or rollback) instead of the statement.
+	txOp string
+	// mode is query or exec. If empty, it is determined by the statement.
+	mode string
+	// fixtures are loaded instead of the statement.
+	fixtures *dbFixtures
 }
 
 type DBResponse struct {
@@ -51,14 +82,7 @@ type DBResponse struct {
 }
 
 func newDBRunner(name, dsn string) (*dbRunner, error) {
-	var db *sql.DB
-	var err error
-	if strings.HasPrefix(dsn, "sp://") || strings.HasPrefix(dsn, "spanner://") {
-		d := strings.Split(strings.Split(dsn, "://")[1], "/")
-		db, err = sql.Open("spanner", fmt.Sprintf(`projects/%s/instances/%s/databases/%s`, d[0], d[1], d[2]))
-	} else {
-		db, err = dburl.Open(normalizeDSN(dsn))
-	}
+	db, err := openDB(dsn, nil, false)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +91,91 @@ func newDBRunner(name, dsn string) (*dbRunner, error) {
 		return nil, err
 	}
 	return &dbRunner{
-		name:   name,
-		client: nx,
+		name:    name,
+		client:  nx,
+		dialect: dbDialectOf(db),
 	}, nil
 }
 
+// setConfig sets the settings of the connection pool and the query.
+func (rnr *dbRunner) setConfig(db *sql.DB, c *dbRunnerConfig) error {
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		return errors.New("invalid max connections: must be positive")
+	}
+	if c.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime != "" {
+		d, err := duration.Parse(c.ConnMaxLifetime)
+		if err != nil {
+			return fmt.Errorf("invalid connMaxLifetime: %w", err)
+		}
+		db.SetConnMaxLifetime(d)
+	}
+	if c.ConnMaxIdleTime != "" {
+		d, err := duration.Parse(c.ConnMaxIdleTime)
+		if err != nil {
+			return fmt.Errorf("invalid connMaxIdleTime: %w", err)
+		}
+		db.SetConnMaxIdleTime(d)
+	}
+	if c.Timeout != "" {
+		d, err := duration.Parse(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		rnr.timeout = d
+	}
+	rnr.readOnly = c.ReadOnly
+	rnr.rollbackOnEnd = c.RollbackOnEnd
+	return nil
+}
+
+// openDB opens the database of the dsn. initSQL are executed on each new connection.
+// If readOnly is true, the connections of SQLite reject the writes using PRAGMA query_only.
+func openDB(dsn string, initSQL []string, readOnly bool) (*sql.DB, error) {
+	var driverName, dataSource string
+	if strings.HasPrefix(dsn, "sp://") || strings.HasPrefix(dsn, "spanner://") {
+		d := strings.Split(strings.Split(dsn, "://")[1], "/")
+		driverName = "spanner"
+		dataSource = fmt.Sprintf(`projects/%s/instances/%s/databases/%s`, d[0], d[1], d[2])
+	} else {
+		u, err := dburl.Parse(normalizeDSN(dsn))
+		if err != nil {
+			return nil, err
+		}
+		driverName = u.Driver
+		dataSource = u.DSN
+	}
+	db, err := sql.Open(driverName, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if readOnly && dbDialectOf(db) == dbDialectSQLite {
+		initSQL = append(initSQL[:len(initSQL):len(initSQL)], "PRAGMA query_only = ON")
+	}
+	if len(initSQL) == 0 {
+		return db, nil
+	}
+	d := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	var c driver.Connector
+	if dc, ok := d.(driver.DriverContext); ok {
+		c, err = dc.OpenConnector(dataSource)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c = &dsnConnector{dsn: dataSource, driver: d}
+	}
+	return sql.OpenDB(&initSQLConnector{Connector: c, initSQL: initSQL}), nil
+}
+
 var dsnRep = strings.NewReplacer("sqlite://", "moderncsqlite://", "sqlite3://", "moderncsqlite://", "sq://", "moderncsqlite://")
 
 func normalizeDSN(dsn string) string {
@@ -82,18 +186,54 @@ func normalizeDSN(dsn string) string {
 }
 
 func (rnr *dbRunner) Run(ctx context.Context, q *dbQuery) error {
-	stmts := separateStmt(q.stmt)
+	if rnr.rollbackOnEnd && len(rnr.txs) == 0 {
+		if err := rnr.begin(); err != nil {
+			return err
+		}
+	}
+	if q.txOp != "" {
+		return rnr.runTxOp(q.txOp)
+	}
+	if q.fixtures != nil {
+		return rnr.loadFixtures(ctx, q.fixtures)
+	}
+	stmts := separateStmt(q.stmt, rnr.dialect)
+	if len(q.args) > 0 && len(stmts) > 1 {
+		return errors.New("args can only be used with a single statement")
+	}
+	if rnr.readOnly {
+		for _, stmt := range stmts {
+			if !isReadOnlyStmt(stmt, rnr.dialect) {
+				return fmt.Errorf("the statement is not allowed in the read-only DB runner: %s", stmt)
+			}
+		}
+	}
 	out := map[string]any{}
-	tx, err := rnr.client.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return err
+	// In the transaction spanning multiple steps, statements are executed without their own transaction.
+	tx := rnr.currentTx()
+	inTx := tx != nil
+	if !inTx {
+		var err error
+		tx, err = rnr.client.BeginTx(ctx, &sql.TxOptions{ReadOnly: rnr.readOnly})
+		if err != nil {
+			return err
+		}
 	}
 	for _, stmt := range stmts {
-		rnr.operator.capturers.captureDBStatement(rnr.name, stmt)
+		rnr.operator.capturers.captureDBStatement(rnr.name, stmt, q.args)
+		mode := q.mode
+		if mode == "" {
+			mode = classifyStmt(stmt, rnr.dialect)
+		}
 		err := func() error {
-			if !strings.HasPrefix(strings.ToUpper(stmt), "SELECT") {
-				// exec
-				r, err := tx.ExecContext(ctx, stmt)
+			ctx := ctx
+			if rnr.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, rnr.timeout)
+				defer cancel()
+			}
+			if mode == dbModeExec {
+				r, err := tx.ExecContext(ctx, stmt, q.args...)
 				if err != nil {
 					return err
 				}
@@ -113,116 +253,264 @@ func (rnr *dbRunner) Run(ctx context.Context, q *dbQuery) error {
 			}
 
 			// query
-			rows := []map[string]any{}
-			r, err := tx.QueryContext(ctx, stmt)
+			r, err := tx.QueryContext(ctx, stmt, q.args...)
 			if err != nil {
 				return err
 			}
 			defer r.Close()
 
-			columns, err := r.Columns()
-			if err != nil {
-				return err
-			}
-			types, err := r.ColumnTypes()
-			if err != nil {
-				return err
-			}
-			for r.Next() {
-				row := map[string]any{}
-				vals := make([]any, len(columns))
-				valsp := make([]any, len(columns))
-				for i := range columns {
-					valsp[i] = &vals[i]
-				}
-				if err := r.Scan(valsp...); err != nil {
+			resultSets := []any{}
+			for {
+				columns, rows, err := scanDBRows(r)
+				if err != nil {
 					return err
 				}
-				for i, c := range columns {
-					t := strings.ToUpper(types[i].DatabaseTypeName())
-					switch v := vals[i].(type) {
-					case []byte:
-						s := string(v)
-						switch {
-						case strings.Contains(t, "TEXT") || strings.Contains(t, "CHAR") || t == "TIME": // MySQL8: ENUM = CHAR
-							row[c] = s
-						case t == "DECIMAL" || t == "FLOAT" || t == "DOUBLE": // MySQL: NUMERIC = DECIMAL
-							num, err := strconv.ParseFloat(s, 64)
-							if err != nil {
-								return fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
-							}
-							row[c] = num
-						case t == "DATE" || t == "TIMESTAMP" || t == "DATETIME": // MySQL(SSH port fowarding)
-							d, err := dateparse.ParseStrict(s)
-							if err != nil {
-								return fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
-							}
-							row[c] = d
-						case t == "JSONB": // PostgreSQL JSONB
-							var jsonColumn map[string]any
-							err = json.Unmarshal(v, &jsonColumn)
-							if err != nil {
-								return fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
-							}
-							row[c] = jsonColumn
-						default: // MySQL: BOOLEAN = TINYINT
-							num, err := strconv.Atoi(s)
-							if err != nil {
-								return fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
-							}
-							row[c] = num
-						}
-					case string:
-						switch {
-						case t == "JSON": // Sqlite JSON
-							var jsonColumn map[string]any
-							err = json.Unmarshal([]byte(v), &jsonColumn)
-							if err != nil {
-								return fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, v, err)
-							}
-							row[c] = jsonColumn
-						default:
-							row[c] = v
-						}
-					default:
-						// MySQL8: DATE, TIMESTAMP, DATETIME
-						row[c] = v
-					}
+				// The result set without columns (e.g. the status of the procedure) is not recorded.
+				if len(columns) > 0 {
+					rnr.operator.capturers.captureDBResponse(rnr.name, &DBResponse{
+						Columns: columns,
+						Rows:    rows,
+					})
+					resultSets = append(resultSets, rows)
+				}
+				if !r.NextResultSet() {
+					break
 				}
-				rows = append(rows, row)
 			}
 			if err := r.Err(); err != nil {
 				return err
 			}
-
-			rnr.operator.capturers.captureDBResponse(rnr.name, &DBResponse{
-				Columns: columns,
-				Rows:    rows,
-			})
+			if len(resultSets) == 0 {
+				rnr.operator.capturers.captureDBResponse(rnr.name, &DBResponse{
+					Rows: []map[string]any{},
+				})
+				resultSets = append(resultSets, []map[string]any{})
+			}
 
 			out = map[string]any{
-				string(dbStoreRowsKey): rows,
+				string(dbStoreRowsKey): resultSets[0],
+			}
+			if len(resultSets) > 1 {
+				out[string(dbStoreResultSetsKey)] = resultSets
 			}
 			return nil
 		}()
 		if err != nil {
+			if inTx {
+				return err
+			}
 			if err := tx.Rollback(); err != nil {
 				return err
 			}
 			return err
 		}
 	}
-	if err := tx.Commit(); err != nil {
-		return err
+	if !inTx {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
 	}
 	rnr.operator.record(out)
 	return nil
 }
 
+// scanDBRows scans the rows of the current result set.
+func scanDBRows(r *sql.Rows) ([]string, []map[string]any, error) {
+	rows := []map[string]any{}
+	columns, err := r.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	types, err := r.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	for r.Next() {
+		row := map[string]any{}
+		vals := make([]any, len(columns))
+		valsp := make([]any, len(columns))
+		for i := range columns {
+			valsp[i] = &vals[i]
+		}
+		if err := r.Scan(valsp...); err != nil {
+			return nil, nil, err
+		}
+		for i, c := range columns {
+			t := strings.ToUpper(types[i].DatabaseTypeName())
+			switch v := vals[i].(type) {
+			case []byte:
+				s := string(v)
+				switch {
+				case strings.Contains(t, "TEXT") || strings.Contains(t, "CHAR") || t == "TIME": // MySQL8: ENUM = CHAR
+					row[c] = s
+				case t == "DECIMAL" || t == "FLOAT" || t == "DOUBLE": // MySQL: NUMERIC = DECIMAL
+					num, err := strconv.ParseFloat(s, 64)
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
+					}
+					row[c] = num
+				case t == "DATE" || t == "TIMESTAMP" || t == "DATETIME": // MySQL(SSH port fowarding)
+					d, err := dateparse.ParseStrict(s)
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
+					}
+					row[c] = d
+				case t == "JSONB": // PostgreSQL JSONB
+					var jsonColumn map[string]any
+					err = json.Unmarshal(v, &jsonColumn)
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
+					}
+					row[c] = jsonColumn
+				default: // MySQL: BOOLEAN = TINYINT
+					num, err := strconv.Atoi(s)
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, s, err)
+					}
+					row[c] = num
+				}
+			case string:
+				switch {
+				case t == "JSON": // Sqlite JSON
+					var jsonColumn map[string]any
+					err = json.Unmarshal([]byte(v), &jsonColumn)
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid column: evaluated %s, but got %s(%v): %w", c, t, v, err)
+					}
+					row[c] = jsonColumn
+				default:
+					row[c] = v
+				}
+			default:
+				// MySQL8: DATE, TIMESTAMP, DATETIME
+				row[c] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := r.Err(); err != nil {
+		return nil, nil, err
+	}
+	return columns, rows, nil
+}
+
+func (rnr *dbRunner) runTxOp(op string) error {
+	switch op {
+	case dbTxBegin:
+		if err := rnr.begin(); err != nil {
+			return err
+		}
+	case dbTxCommit, dbTxRollback:
+		// The transaction of rollbackOnEnd cannot be ended by steps.
+		if len(rnr.txs) == 0 || (rnr.rollbackOnEnd && len(rnr.txs) == 1) {
+			return fmt.Errorf("no transaction to %s", op)
+		}
+		tx := rnr.txs[len(rnr.txs)-1]
+		rnr.txs = rnr.txs[:len(rnr.txs)-1]
+		if op == dbTxCommit {
+			if err := tx.commit(); err != nil {
+				return err
+			}
+			rnr.addFixtureRows(tx.fixtureRows)
+		} else {
+			if err := tx.rollback(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid transaction control: %s", op)
+	}
+	rnr.operator.record(map[string]any{})
+	return nil
+}
+
+// begin begins the transaction spanning multiple steps.
+// If the transaction has already begun, it begins the nested transaction using the savepoint.
+func (rnr *dbRunner) begin() error {
+	// The transaction must not be bound to the context of the step, because database/sql rolls back the transaction when the context is done.
+	ctx := context.Background()
+	current := rnr.currentTx()
+	if current == nil {
+		tx, err := rnr.client.BeginTx(ctx, &sql.TxOptions{ReadOnly: rnr.readOnly})
+		if err != nil {
+			return err
+		}
+		rnr.txs = append(rnr.txs, &dbTx{tx: tx})
+		return nil
+	}
+	tx, err := current.BeginTx(ctx, &sql.TxOptions{})
+	if err == nil {
+		rnr.txs = append(rnr.txs, &dbTx{tx: tx})
+		return nil
+	}
+	// Some drivers (e.g. SQLite) do not tell their savepoint syntax, so fall back to the standard SQL savepoint.
+	sp := fmt.Sprintf("runn_savepoint_%d", len(rnr.txs))
+	if _, err := current.ExecContext(ctx, fmt.Sprintf("SAVEPOINT %s", sp)); err != nil {
+		return err
+	}
+	rnr.txs = append(rnr.txs, &dbTx{tx: current, savepoint: sp})
+	return nil
+}
+
+func (rnr *dbRunner) currentTx() *nest.Tx {
+	if len(rnr.txs) == 0 {
+		return nil
+	}
+	return rnr.txs[len(rnr.txs)-1].tx
+}
+
+// Close rolls back the transactions that have not ended, and deletes the rows inserted by the fixtures with the strategy delete-after-run.
+func (rnr *dbRunner) Close() error {
+	if len(rnr.txs) > 0 {
+		// Rolling back the outermost transaction also rolls back the nested ones.
+		tx := rnr.txs[0]
+		rnr.txs = nil
+		if err := tx.rollback(); err != nil {
+			return err
+		}
+	}
+	return rnr.deleteFixtureRows()
+}
+
+// dbTx is the transaction spanning multiple steps.
+type dbTx struct {
+	tx *nest.Tx
+	// savepoint is the name of the standard SQL savepoint created in tx. Empty if tx itself is the (nested) transaction.
+	savepoint string
+	// fixtureRows are the rows inserted by the fixtures in the transaction, which are discarded when it is rolled back.
+	fixtureRows []*dbFixtureRow
+}
+
+func (t *dbTx) commit() error {
+	if t.savepoint == "" {
+		return t.tx.Commit()
+	}
+	_, err := t.tx.ExecContext(context.Background(), fmt.Sprintf("RELEASE SAVEPOINT %s", t.savepoint))
+	return err
+}
+
+func (t *dbTx) rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+	if _, err := t.tx.ExecContext(context.Background(), fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", t.savepoint)); err != nil {
+		return err
+	}
+	return t.commit()
+}
+
 func nestTx(client Querier) (TxQuerier, error) {
+	db, err := sqlDB(client)
+	if err != nil {
+		return nil, err
+	}
+	return nest.Wrap(db), nil
+}
+
+func sqlDB(client Querier) (*sql.DB, error) {
 	switch c := client.(type) {
 	case *sql.DB:
-		return nest.Wrap(c), nil
+		return c, nil
 	case *sql.Tx:
 		if c == nil {
 			return nil, fmt.Errorf("invalid db client: %v", c)
@@ -230,42 +518,64 @@ func nestTx(client Querier) (TxQuerier, error) {
 		var v reflect.Value = reflect.ValueOf(c).Elem()
 		var psv reflect.Value = v.FieldByName("db").Elem()
 		db := (*sql.DB)(unsafe.Pointer(psv.UnsafeAddr()))
-		return nest.Wrap(db), nil
+		return db, nil
 	default:
 		return nil, fmt.Errorf("invalid db client: %v", c)
 	}
 }
 
-func separateStmt(stmt string) []string {
-	if !strings.Contains(stmt, ";") {
-		return []string{stmt}
-	}
-	stmts := []string{}
-	s := []rune{}
-	ins := false
-	ind := false
-	for _, c := range stmt {
-		s = append(s, c)
-		switch c {
-		case '\'':
-			ins = !ins
-		case '"':
-			ind = !ind
-		case ';':
-			if !ins && !ind {
-				stmts = append(stmts, strings.Trim(string(s), " \n"))
-				s = []rune{}
-			}
+// dsnConnector is the driver.Connector of the driver that does not implement driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// initSQLConnector executes the statements (e.g. setting the time zone of the session) on each new connection.
+type initSQLConnector struct {
+	driver.Connector
+	initSQL []string
+}
+
+func (c *initSQLConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range c.initSQL {
+		if err := execOnConn(ctx, conn, stmt); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to execute initSQL: %s: %w", stmt, err)
 		}
 	}
-	if len(s) > 0 {
-		cutset := " \n\\n\"" // When I receive a multi-line query with `key: |`, I get an unexplained string at the end. Therefore, remove it as a workaround.
-		l := strings.TrimRight(string(s), cutset)
-		if len(l) > 0 {
-			stmts = append(stmts, l)
+	return conn, nil
+}
+
+func execOnConn(ctx context.Context, conn driver.Conn, stmt string) error {
+	if e, ok := conn.(driver.ExecerContext); ok {
+		_, err := e.ExecContext(ctx, stmt, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
 		}
 	}
-	return stmts
+	st, err := conn.Prepare(stmt)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if e, ok := st.(driver.StmtExecContext); ok {
+		_, err = e.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = st.Exec(nil) //nolint:staticcheck
+	return err
 }
 
 func init() {
@@ -0,0 +1,483 @@
+package runn
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+	"github.com/golang-sql/sqlexp/nest"
+)
+
+const (
+	// dbFixtureInsert inserts the rows.
+	dbFixtureInsert = "insert"
+	// dbFixtureTruncate deletes all rows of the tables, then inserts the rows.
+	dbFixtureTruncate = "truncate"
+	// dbFixtureUpsert inserts the rows, or updates the rows having the same primary key.
+	dbFixtureUpsert = "upsert"
+	// dbFixtureDeleteAfterRun inserts the rows, and deletes them at the end of the runbook.
+	dbFixtureDeleteAfterRun = "delete-after-run"
+)
+
+type dbFixtures struct {
+	files    []string
+	strategy string
+}
+
+// dbFixtureTable is the rows of the table loaded from the fixture file.
+type dbFixtureTable struct {
+	name string
+	rows []map[string]any
+	// csv is true if the values are the strings read from CSV, which are converted by the types of the columns.
+	csv bool
+}
+
+// dbFixtureRow is the inserted row to be deleted at the end of the runbook.
+type dbFixtureRow struct {
+	table string
+	// keys are the columns of the primary key, and values are the values of them.
+	keys   []string
+	values []any
+}
+
+func (rnr *dbRunner) loadFixtures(ctx context.Context, f *dbFixtures) error {
+	if rnr.readOnly {
+		return fmt.Errorf("fixtures are not allowed in the read-only DB runner")
+	}
+	tables := []*dbFixtureTable{}
+	for _, p := range f.files {
+		t, err := readDBFixture(fp(p, rnr.operator.root))
+		if err != nil {
+			return err
+		}
+		tables = append(tables, t...)
+	}
+	tx := rnr.currentTx()
+	inTx := tx != nil
+	if !inTx {
+		var err error
+		tx, err = rnr.client.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	var (
+		affected int64
+		inserted []*dbFixtureRow
+	)
+	err := func() error {
+		if f.strategy == dbFixtureTruncate {
+			// Delete in the reverse order for the foreign keys
+			seen := map[string]struct{}{}
+			for i := len(tables) - 1; i >= 0; i-- {
+				if _, ok := seen[tables[i].name]; ok {
+					continue
+				}
+				seen[tables[i].name] = struct{}{}
+				if err := rnr.execFixtureStmt(ctx, tx, fmt.Sprintf("DELETE FROM %s", quoteIdent(tables[i].name, rnr.dialect)), nil); err != nil {
+					return err
+				}
+			}
+		}
+		for _, t := range tables {
+			types, err := columnTypes(ctx, tx, t.name, rnr.dialect)
+			if err != nil {
+				return err
+			}
+			var keys []string
+			if (f.strategy == dbFixtureUpsert && rnr.dialect != dbDialectMySQL) || f.strategy == dbFixtureDeleteAfterRun {
+				keys, err = primaryKeys(ctx, tx, t.name, rnr.dialect)
+				if err != nil {
+					return err
+				}
+			}
+			for _, row := range t.rows {
+				if t.csv {
+					row, err = convertCSVRow(row, types)
+					if err != nil {
+						return fmt.Errorf("invalid fixture of %s: %w", t.name, err)
+					}
+				}
+				stmt, args, err := insertStmt(t.name, row, f.strategy, keys, rnr.dialect)
+				if err != nil {
+					return err
+				}
+				rnr.operator.capturers.captureDBStatement(rnr.name, stmt, args)
+				r, err := tx.ExecContext(ctx, stmt, args...)
+				if err != nil {
+					return fmt.Errorf("failed to load fixture into %s: %w", t.name, err)
+				}
+				a, _ := r.RowsAffected()
+				affected += a
+				if f.strategy == dbFixtureDeleteAfterRun {
+					fr, err := insertedRow(t.name, row, keys, r)
+					if err != nil {
+						return err
+					}
+					inserted = append(inserted, fr)
+				}
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		if !inTx {
+			_ = tx.Rollback()
+		}
+		return err
+	}
+	if !inTx {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	rnr.addFixtureRows(inserted)
+	rnr.operator.record(map[string]any{
+		string(dbStoreRowsAffectedKey): affected,
+	})
+	return nil
+}
+
+func (rnr *dbRunner) execFixtureStmt(ctx context.Context, tx *nest.Tx, stmt string, args []any) error {
+	rnr.operator.capturers.captureDBStatement(rnr.name, stmt, args)
+	_, err := tx.ExecContext(ctx, stmt, args...)
+	return err
+}
+
+// addFixtureRows adds the inserted rows to be deleted.
+// The rows inserted in the transaction are held by it until it is committed, because rolling it back removes them.
+func (rnr *dbRunner) addFixtureRows(rows []*dbFixtureRow) {
+	if len(rnr.txs) == 0 {
+		rnr.fixtureRows = append(rnr.fixtureRows, rows...)
+		return
+	}
+	tx := rnr.txs[len(rnr.txs)-1]
+	tx.fixtureRows = append(tx.fixtureRows, rows...)
+}
+
+// deleteFixtureRows deletes the rows inserted by the fixtures with the strategy delete-after-run.
+func (rnr *dbRunner) deleteFixtureRows() error {
+	if len(rnr.fixtureRows) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	rows := rnr.fixtureRows
+	rnr.fixtureRows = nil
+	tx, err := rnr.client.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	// Delete in the reverse order for the foreign keys
+	for i := len(rows) - 1; i >= 0; i-- {
+		stmt, args := deleteStmt(rows[i].table, rows[i].keys, rows[i].values, rnr.dialect)
+		// The row already deleted by the steps (no rows affected) is regarded as cleaned up.
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to delete fixture from %s: %w", rows[i].table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// insertedRow returns the row to be deleted at the end of the runbook using the values of the primary key.
+// The value of the auto-increment key omitted in the fixture is the ID of the inserted row.
+func insertedRow(table string, row map[string]any, keys []string, r sql.Result) (*dbFixtureRow, error) {
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		v, ok := row[k]
+		if !ok && len(keys) == 1 {
+			id, err := r.LastInsertId()
+			if err == nil {
+				values[i] = id
+				continue
+			}
+		}
+		if !ok || v == nil {
+			return nil, fmt.Errorf("delete-after-run requires the value of the primary key %s of %s", k, table)
+		}
+		fv, err := fixtureValue(v)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = fv
+	}
+	return &dbFixtureRow{table: table, keys: keys, values: values}, nil
+}
+
+// readDBFixture reads the fixture file.
+// YAML and JSON are the map of the table name and the rows, or the rows of the table named after the file.
+// CSV is the rows of the table named after the file, and the first line is the header.
+func readDBFixture(p string) ([]*dbFixtureTable, error) {
+	b, err := readFile(p)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".csv":
+		r := csv.NewReader(strings.NewReader(string(b)))
+		records, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture: %s: %w", p, err)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("invalid fixture: %s: no header", p)
+		}
+		t := &dbFixtureTable{name: name, csv: true}
+		for _, rec := range records[1:] {
+			row := map[string]any{}
+			for i, c := range records[0] {
+				if i < len(rec) {
+					row[c] = rec[i]
+				}
+			}
+			t.rows = append(t.rows, row)
+		}
+		return []*dbFixtureTable{t}, nil
+	case ".yml", ".yaml", ".json":
+		var rows []map[string]any
+		if err := yaml.Unmarshal(b, &rows); err == nil {
+			return []*dbFixtureTable{{name: name, rows: rows}}, nil
+		}
+		var ms yaml.MapSlice
+		if err := yaml.Unmarshal(b, &ms); err != nil {
+			return nil, fmt.Errorf("invalid fixture: %s: %w", p, err)
+		}
+		tables := []*dbFixtureTable{}
+		for _, item := range ms {
+			t := &dbFixtureTable{name: fmt.Sprintf("%v", item.Key)}
+			vs, ok := item.Value.([]any)
+			if !ok && item.Value != nil {
+				return nil, fmt.Errorf("invalid fixture: %s: rows of %s should be a list", p, t.name)
+			}
+			for _, v := range vs {
+				row, ok := v.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("invalid fixture: %s: row of %s should be a map", p, t.name)
+				}
+				t.rows = append(t.rows, row)
+			}
+			tables = append(tables, t)
+		}
+		return tables, nil
+	default:
+		return nil, fmt.Errorf("unsupported fixture: %s", p)
+	}
+}
+
+// columnTypes returns the database type names of the columns of the table reported by the driver.
+func columnTypes(ctx context.Context, tx *nest.Tx, table, dialect string) (map[string]string, error) {
+	r, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", quoteIdent(table, dialect)))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	cts, err := r.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]string{}
+	for _, ct := range cts {
+		types[ct.Name()] = strings.ToUpper(ct.DatabaseTypeName())
+	}
+	return types, r.Err()
+}
+
+// primaryKeys returns the columns of the primary key of the table.
+func primaryKeys(ctx context.Context, tx *nest.Tx, table, dialect string) ([]string, error) {
+	var (
+		q   string
+		arg any
+	)
+	switch dialect {
+	case dbDialectSQLite:
+		q = "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk"
+		arg = table
+	case dbDialectPostgres:
+		q = "SELECT a.attname FROM pg_index i JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey) WHERE i.indrelid = $1::regclass AND i.indisprimary ORDER BY array_position(i.indkey, a.attnum)"
+		arg = table
+	case dbDialectMySQL:
+		q = "SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION"
+		arg = table
+	default:
+		return nil, fmt.Errorf("the primary key of %s cannot be resolved: %s", table, dialect)
+	}
+	r, err := tx.QueryContext(ctx, q, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	keys := []string{}
+	for r.Next() {
+		var k string
+		if err := r.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no primary key: %s", table)
+	}
+	return keys, nil
+}
+
+// convertCSVRow converts the values read from CSV by the types of the columns.
+// The empty value of the column other than strings is NULL.
+func convertCSVRow(row map[string]any, types map[string]string) (map[string]any, error) {
+	converted := map[string]any{}
+	for c, v := range row {
+		s, ok := v.(string)
+		if !ok {
+			converted[c] = v
+			continue
+		}
+		t := types[c]
+		isText := t == "" || strings.Contains(t, "CHAR") || strings.Contains(t, "TEXT") || strings.Contains(t, "CLOB")
+		if s == "" && !isText {
+			converted[c] = nil
+			continue
+		}
+		switch {
+		case strings.Contains(t, "BOOL"):
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of %s(%s): %s", c, t, s)
+			}
+			converted[c] = b
+		case strings.Contains(t, "INT") || t == "SERIAL" || t == "BIGSERIAL":
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of %s(%s): %s", c, t, s)
+			}
+			converted[c] = n
+		case strings.Contains(t, "REAL") || strings.Contains(t, "FLOAT") || strings.Contains(t, "DOUBLE"):
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of %s(%s): %s", c, t, s)
+			}
+			converted[c] = f
+		default:
+			// DECIMAL, DATE, TIMESTAMP and so on are passed to the driver as strings.
+			converted[c] = s
+		}
+	}
+	return converted, nil
+}
+
+// insertStmt returns the INSERT statement of the row according to the strategy.
+func insertStmt(table string, row map[string]any, strategy string, keys []string, dialect string) (string, []any, error) {
+	columns := sortedColumns(row)
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("invalid fixture: empty row of %s", table)
+	}
+	qcs := make([]string, len(columns))
+	phs := make([]string, len(columns))
+	args := make([]any, len(columns))
+	for i, c := range columns {
+		qcs[i] = quoteIdent(c, dialect)
+		phs[i] = placeholder(i+1, dialect)
+		v, err := fixtureValue(row[c])
+		if err != nil {
+			return "", nil, err
+		}
+		args[i] = v
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table, dialect), strings.Join(qcs, ", "), strings.Join(phs, ", "))
+	if strategy != dbFixtureUpsert {
+		return stmt, args, nil
+	}
+	if dialect == dbDialectMySQL {
+		sets := make([]string, len(qcs))
+		for i, qc := range qcs {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", qc, qc)
+		}
+		return fmt.Sprintf("%s ON DUPLICATE KEY UPDATE %s", stmt, strings.Join(sets, ", ")), args, nil
+	}
+	qks := make([]string, len(keys))
+	isKey := map[string]struct{}{}
+	for i, k := range keys {
+		qks[i] = quoteIdent(k, dialect)
+		isKey[k] = struct{}{}
+	}
+	sets := []string{}
+	for i, c := range columns {
+		if _, ok := isKey[c]; ok {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", qcs[i], qcs[i]))
+	}
+	if len(sets) == 0 {
+		return fmt.Sprintf("%s ON CONFLICT (%s) DO NOTHING", stmt, strings.Join(qks, ", ")), args, nil
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", stmt, strings.Join(qks, ", "), strings.Join(sets, ", ")), args, nil
+}
+
+// deleteStmt returns the DELETE statement of the row matching the values of the primary key.
+func deleteStmt(table string, keys []string, values []any, dialect string) (string, []any) {
+	conds := make([]string, len(keys))
+	for i, k := range keys {
+		conds[i] = fmt.Sprintf("%s = %s", quoteIdent(k, dialect), placeholder(i+1, dialect))
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", quoteIdent(table, dialect), strings.Join(conds, " AND ")), values
+}
+
+// fixtureValue returns the value bound to the placeholder. Maps and lists are bound as JSON.
+func fixtureValue(v any) (any, error) {
+	switch v.(type) {
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		return v, nil
+	}
+}
+
+func sortedColumns(row map[string]any) []string {
+	columns := make([]string, 0, len(row))
+	for c := range row {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// quoteIdent quotes the identifier (e.g. schema.table) of the dialect.
+func quoteIdent(name, dialect string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		switch dialect {
+		case dbDialectMySQL, dbDialectSpanner:
+			parts[i] = "`" + strings.ReplaceAll(p, "`", "``") + "`"
+		case dbDialectSQLServer:
+			parts[i] = "[" + strings.ReplaceAll(p, "]", "]]") + "]"
+		default:
+			parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// placeholder returns the n-th (1-origin) placeholder of the dialect.
+func placeholder(n int, dialect string) string {
+	switch dialect {
+	case dbDialectPostgres:
+		return fmt.Sprintf("$%d", n)
+	case dbDialectSQLServer, dbDialectSpanner:
+		return fmt.Sprintf("@p%d", n)
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,210 @@
+package runn
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+)
+
+func TestInsertStmt(t *testing.T) {
+	row := map[string]any{"id": 1, "username": "alice", "profile": map[string]any{"age": 20}}
+	tests := []struct {
+		strategy string
+		keys     []string
+		dialect  string
+		want     string
+		wantArgs []any
+	}{
+		{
+			dbFixtureTruncate, nil, dbDialectSQLite,
+			`INSERT INTO "users" ("id", "profile", "username") VALUES (?, ?, ?)`,
+			[]any{1, `{"age":20}`, "alice"},
+		},
+		{
+			dbFixtureTruncate, nil, dbDialectPostgres,
+			`INSERT INTO "users" ("id", "profile", "username") VALUES ($1, $2, $3)`,
+			[]any{1, `{"age":20}`, "alice"},
+		},
+		{
+			dbFixtureUpsert, []string{"id"}, dbDialectPostgres,
+			`INSERT INTO "users" ("id", "profile", "username") VALUES ($1, $2, $3) ON CONFLICT ("id") DO UPDATE SET "profile" = excluded."profile", "username" = excluded."username"`,
+			[]any{1, `{"age":20}`, "alice"},
+		},
+		{
+			dbFixtureUpsert, nil, dbDialectMySQL,
+			"INSERT INTO `users` (`id`, `profile`, `username`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `id` = VALUES(`id`), `profile` = VALUES(`profile`), `username` = VALUES(`username`)",
+			[]any{1, `{"age":20}`, "alice"},
+		},
+	}
+	for _, tt := range tests {
+		got, gotArgs, err := insertStmt("users", row, tt.strategy, tt.keys, tt.dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("got %v\nwant %v", got, tt.want)
+		}
+		if diff := cmp.Diff(gotArgs, tt.wantArgs, nil); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestDeleteStmt(t *testing.T) {
+	got, gotArgs := deleteStmt("app.users", []string{"tenant_id", "id"}, []any{"t1", 1}, dbDialectPostgres)
+	want := `DELETE FROM "app"."users" WHERE "tenant_id" = $1 AND "id" = $2`
+	if got != want {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+	if diff := cmp.Diff(gotArgs, []any{"t1", 1}, nil); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDBFixturesDeleteAfterRun(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testutil.SQLite(t)
+	o, err := New(Book("testdata/book/db_fixtures.yml"), DBRunner("db", db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var c int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username = 'frank'").Scan(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c != 0 {
+		t.Error("the rows of the fixtures with delete-after-run should be deleted at the end of the runbook")
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c != 3 {
+		t.Errorf("got %v\nwant %v", c, 3)
+	}
+}
+
+func TestDBFixturesDeleteAfterRunRowAlreadyDeleted(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testutil.SQLite(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.yml"), []byte("- username: frank\n  password: passw0rd\n  email: frank@example.com\n  created: 2022-02-22\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	initdb, err := os.ReadFile(filepath.Join(testutil.Testdata(), "book", "initdb.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "initdb.yml"), initdb, 0600); err != nil {
+		t.Fatal(err)
+	}
+	book := `desc: Delete the row of the fixture in the runbook
+steps:
+  -
+    include: initdb.yml
+  -
+    db:
+      fixtures: users.yml
+      strategy: delete-after-run
+  -
+    db:
+      query: DELETE FROM users WHERE username = 'frank'
+`
+	p := filepath.Join(dir, "book.yml")
+	if err := os.WriteFile(p, []byte(book), 0600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := New(Book(p), DBRunner("db", db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDBFixturesDeleteAfterRunRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testutil.SQLite(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.yml"), []byte("- id: 20\n  username: frank\n  password: passw0rd\n  email: frank@example.com\n  created: 2022-02-22\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	initdb, err := os.ReadFile(filepath.Join(testutil.Testdata(), "book", "initdb.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "initdb.yml"), initdb, 0600); err != nil {
+		t.Fatal(err)
+	}
+	book := `desc: Load the fixture in the transaction rolled back
+steps:
+  -
+    include: initdb.yml
+  -
+    db:
+      begin: true
+  -
+    db:
+      fixtures: users.yml
+      strategy: delete-after-run
+  -
+    db:
+      rollback: true
+  -
+    db:
+      query: INSERT INTO users (id, username, password, email, created) VALUES (20, 'grace', 'passw0rd', 'grace@example.com', datetime('2022-02-22'))
+`
+	p := filepath.Join(dir, "book.yml")
+	if err := os.WriteFile(p, []byte(book), 0600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := New(Book(p), DBRunner("db", db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var c int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE id = 20").Scan(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c != 1 {
+		t.Error("the row inserted after the rollback should not be deleted as the row of the fixture")
+	}
+}
+
+func TestDBFixturesDeleteAfterRunRollbackOnEnd(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testutil.SQLite(t)
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.yml"), []byte("- username: alice\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := New(DBRunner("db", db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.root = dir
+	r := o.dbRunners["db"]
+	r.rollbackOnEnd = true
+	if err := r.Run(ctx, &dbQuery{fixtures: &dbFixtures{files: []string{"users.yml"}, strategy: dbFixtureDeleteAfterRun}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.fixtureRows) != 0 {
+		t.Errorf("the rows inserted in the transaction of rollbackOnEnd should not be deleted: %v", r.fixtureRows)
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+}
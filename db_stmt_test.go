@@ -0,0 +1,95 @@
+package runn
+
+import (
+	"testing"
+)
+
+func TestClassifyStmt(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		dialect string
+		want    string
+	}{
+		{"SELECT 1", dbDialectUnknown, dbModeQuery},
+		{"select 1", dbDialectUnknown, dbModeQuery},
+		{"  \n(SELECT 1) UNION (SELECT 2)", dbDialectUnknown, dbModeQuery},
+		{"INSERT INTO users (username) VALUES ('alice')", dbDialectUnknown, dbModeExec},
+		{"CREATE TABLE users (id INTEGER)", dbDialectUnknown, dbModeExec},
+		{"-- comment\nSELECT 1", dbDialectUnknown, dbModeQuery},
+		{"/* comment */ SELECT 1", dbDialectUnknown, dbModeQuery},
+		{"/* SELECT */ DELETE FROM users", dbDialectUnknown, dbModeExec},
+		{"# comment\nSELECT 1", dbDialectMySQL, dbModeQuery},
+		{"/* outer /* inner */ SELECT */ DELETE FROM users", dbDialectPostgres, dbModeExec},
+		{"WITH u AS (SELECT * FROM users) SELECT * FROM u", dbDialectUnknown, dbModeQuery},
+		{"WITH RECURSIVE r(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM r WHERE n < 3) SELECT n FROM r", dbDialectSQLite, dbModeQuery},
+		{"WITH a AS (SELECT 1), b AS MATERIALIZED (SELECT 2) SELECT * FROM a, b", dbDialectPostgres, dbModeQuery},
+		{"WITH d AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM d)", dbDialectPostgres, dbModeExec},
+		{"WITH d AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM d) RETURNING id", dbDialectPostgres, dbModeQuery},
+		{"INSERT INTO users (username) VALUES ('alice') RETURNING id", dbDialectPostgres, dbModeQuery},
+		{"INSERT INTO users (username) VALUES ('returning')", dbDialectPostgres, dbModeExec},
+		{"UPDATE users SET username = 'bob' WHERE id = 1 RETURNING *", dbDialectSQLite, dbModeQuery},
+		{"INSERT INTO users (username) OUTPUT INSERTED.id VALUES ('alice')", dbDialectSQLServer, dbModeQuery},
+		{"INSERT INTO users (id, username) VALUES (1, 'alice') THEN RETURN id", dbDialectSpanner, dbModeQuery},
+		{"SHOW TABLES", dbDialectMySQL, dbModeQuery},
+		{"EXPLAIN SELECT 1", dbDialectUnknown, dbModeQuery},
+		{"DESCRIBE users", dbDialectMySQL, dbModeQuery},
+		{"PRAGMA table_info(users)", dbDialectSQLite, dbModeQuery},
+		{"VALUES (1), (2)", dbDialectUnknown, dbModeQuery},
+		{"UPDATE users SET note = E'it\\'s RETURNING' WHERE id = $1", dbDialectPostgres, dbModeExec},
+		{"UPDATE users SET note = 'it\\'s RETURNING' WHERE id = ?", dbDialectMySQL, dbModeExec},
+		{"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1 $body$ LANGUAGE sql", dbDialectPostgres, dbModeExec},
+		{"UPDATE `select` SET `returning` = 1", dbDialectMySQL, dbModeExec},
+		{"UPDATE [select] SET [output] = 1", dbDialectSQLServer, dbModeExec},
+		{"", dbDialectUnknown, dbModeExec},
+	}
+	for _, tt := range tests {
+		got := classifyStmt(tt.stmt, tt.dialect)
+		if got != tt.want {
+			t.Errorf("%q (%s): got %v\nwant %v", tt.stmt, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestIsReadOnlyStmt(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		dialect string
+		want    bool
+	}{
+		{"SELECT * FROM users", dbDialectUnknown, true},
+		{"-- comment\nSELECT * FROM users", dbDialectUnknown, true},
+		{"(SELECT 1) UNION (SELECT 2)", dbDialectUnknown, true},
+		{"SHOW TABLES", dbDialectMySQL, true},
+		{"SELECT REPLACE(username, 'a', 'b') FROM users", dbDialectUnknown, true},
+		{"SELECT * FROM users WHERE id IN (SELECT user_id FROM posts)", dbDialectUnknown, true},
+		{"WITH u AS (SELECT * FROM users) SELECT * FROM u", dbDialectPostgres, true},
+		{"EXPLAIN SELECT * FROM users", dbDialectUnknown, true},
+		{"INSERT INTO users (username) VALUES ('alice')", dbDialectUnknown, false},
+		{"UPDATE users SET username = 'select'", dbDialectUnknown, false},
+		{"DELETE FROM users RETURNING *", dbDialectPostgres, false},
+		{"DROP TABLE users", dbDialectUnknown, false},
+		{"/* SELECT */ TRUNCATE users", dbDialectUnknown, false},
+		{"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", dbDialectPostgres, false},
+		{"WITH d AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM d)", dbDialectPostgres, false},
+		{"EXPLAIN ANALYZE DELETE FROM users", dbDialectPostgres, false},
+		{"EXPLAIN ANALYZE INSERT INTO t SELECT * FROM s", dbDialectPostgres, false},
+		{"EXPLAIN ANALYZE UPDATE users SET username = 'bob' ORDER BY id DESC LIMIT 1", dbDialectMySQL, false},
+		{"EXPLAIN (ANALYZE, BUFFERS) UPDATE users SET username = 'bob'", dbDialectPostgres, false},
+		{"EXPLAIN (ANALYZE, BUFFERS) SELECT * FROM users", dbDialectPostgres, true},
+		{"EXPLAIN FORMAT=JSON SELECT * FROM users ORDER BY id DESC", dbDialectMySQL, true},
+		{"EXPLAIN QUERY PLAN SELECT * FROM users", dbDialectSQLite, true},
+		{"SELECT * INTO users_backup FROM users", dbDialectPostgres, false},
+		{"PRAGMA foreign_keys = OFF", dbDialectSQLite, false},
+		{"CALL delete_users()", dbDialectMySQL, false},
+		{"SELECT 1 -- it's\n; DELETE FROM u", dbDialectUnknown, false},
+		{"SELECT 1; DELETE FROM users", dbDialectUnknown, false},
+		{"SELECT 1;", dbDialectUnknown, true},
+		{"", dbDialectUnknown, false},
+	}
+	for _, tt := range tests {
+		got := isReadOnlyStmt(tt.stmt, tt.dialect)
+		if got != tt.want {
+			t.Errorf("%q (%s): got %v\nwant %v", tt.stmt, tt.dialect, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package runn
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -170,6 +173,276 @@ SELECT * FROM users;
 	}
 }
 
+func TestDBRunWithArgs(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		args    []any
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			"SELECT ? AS v",
+			[]any{"O'Reilly"},
+			map[string]any{
+				"rows": []map[string]any{
+					{"v": "O'Reilly"},
+				},
+				"run": true,
+			},
+			false,
+		},
+		{
+			"SELECT :a + :b AS v",
+			[]any{sql.Named("a", 1), sql.Named("b", 2)},
+			map[string]any{
+				"rows": []map[string]any{
+					{"v": int64(3)},
+				},
+				"run": true,
+			},
+			false,
+		},
+		{
+			"SELECT ?;SELECT ?;",
+			[]any{1, 2},
+			nil,
+			true,
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			_, dsn := testutil.SQLite(t)
+			o, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := newDBRunner("db", dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.operator = o
+			q := &dbQuery{stmt: tt.stmt, args: tt.args}
+			if err := r.Run(ctx, q); err != nil {
+				if !tt.wantErr {
+					t.Error(err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("want error")
+			}
+			got := o.store.steps[0]
+			if diff := cmp.Diff(got, tt.want, nil); diff != "" {
+				t.Errorf("%s", diff)
+			}
+		})
+	}
+}
+
+func TestDBRunnerRollbackOnEnd(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testutil.SQLite(t)
+	o, err := New(Book("testdata/book/db_transaction.yml"), DBRunner("db", db, DBRollbackOnEnd(true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var c int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'users'").Scan(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c != 0 {
+		t.Error("the table created in the runbook should be rolled back")
+	}
+}
+
+func TestDBRunnerClosedInIncludedRunbook(t *testing.T) {
+	ctx := context.Background()
+	db, dsn := testutil.SQLite(t)
+	dir := t.TempDir()
+	initdb, err := os.ReadFile(filepath.Join(testutil.Testdata(), "book", "initdb.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "initdb.yml"), initdb, 0600); err != nil {
+		t.Fatal(err)
+	}
+	child := fmt.Sprintf(`desc: Leave the transaction of the runner defined in the included runbook
+runners:
+  tx: %s
+steps:
+  -
+    tx:
+      begin: true
+  -
+    tx:
+      query: INSERT INTO users (username, password, email, created) VALUES ('charlie', 'passw0rd', 'charlie@example.com', datetime('2022-02-22'))
+`, dsn)
+	parent := `desc: Include the runbook leaving the transaction
+steps:
+  -
+    include: initdb.yml
+  -
+    include: child.yml
+`
+	if err := os.WriteFile(filepath.Join(dir, "child.yml"), []byte(child), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "parent.yml"), []byte(parent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := New(Book(filepath.Join(dir, "parent.yml")), DBRunner("db", db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var c int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username = 'charlie'").Scan(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c != 0 {
+		t.Error("the transaction of the runner defined in the included runbook should be rolled back")
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (username, password, email, created) VALUES ('dave', 'passw0rd', 'dave@example.com', datetime('2022-02-22'))"); err != nil {
+		t.Errorf("the database should not be locked by the included runbook: %v", err)
+	}
+}
+
+func TestDBRunnerTransactionInvalid(t *testing.T) {
+	ctx := context.Background()
+	_, dsn := testutil.SQLite(t)
+	o, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newDBRunner("db", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.operator = o
+	t.Cleanup(func() {
+		_ = r.Close()
+	})
+	if err := r.Run(ctx, &dbQuery{txOp: dbTxCommit}); err == nil {
+		t.Error("want error")
+	}
+	r.rollbackOnEnd = true
+	if err := r.Run(ctx, &dbQuery{stmt: "SELECT 1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(ctx, &dbQuery{txOp: dbTxRollback}); err == nil {
+		t.Error("the transaction of rollbackOnEnd should not be rolled back by steps")
+	}
+}
+
+func TestDBRunnerWithConfig(t *testing.T) {
+	ctx := context.Background()
+	_, dsn := testutil.SQLite(t)
+	tests := []struct {
+		name    string
+		c       *dbRunnerConfig
+		stmt    string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			"initSQL is executed on the connection",
+			&dbRunnerConfig{DSN: dsn, InitSQL: []string{"CREATE TEMP TABLE session (name TEXT)", "INSERT INTO session VALUES ('runn')"}},
+			"SELECT name FROM session",
+			map[string]any{"rows": []map[string]any{{"name": "runn"}}, "run": true},
+			false,
+		},
+		{
+			"readOnly allows SELECT",
+			&dbRunnerConfig{DSN: dsn, ReadOnly: true},
+			"SELECT 1 AS one",
+			map[string]any{"rows": []map[string]any{{"one": int64(1)}}, "run": true},
+			false,
+		},
+		{
+			"readOnly rejects CREATE",
+			&dbRunnerConfig{DSN: dsn, ReadOnly: true},
+			"CREATE TABLE users (id INTEGER)",
+			nil,
+			true,
+		},
+		{
+			"readOnly rejects the statement following the semicolon after the comment",
+			&dbRunnerConfig{DSN: dsn, ReadOnly: true},
+			"SELECT 1 -- it's\n; DELETE FROM u",
+			nil,
+			true,
+		},
+		{
+			"timeout",
+			&dbRunnerConfig{DSN: dsn, Timeout: "100ms"},
+			"WITH RECURSIVE r(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM r) SELECT COUNT(*) FROM r",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := openDB(tt.c.DSN, tt.c.InitSQL, tt.c.ReadOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				_ = db.Close()
+			})
+			nx, err := nestTx(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &dbRunner{name: "db", client: nx, dialect: dbDialectOf(db)}
+			if err := r.setConfig(db, tt.c); err != nil {
+				t.Fatal(err)
+			}
+			o, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.operator = o
+			if err := r.Run(ctx, &dbQuery{stmt: tt.stmt}); err != nil {
+				if !tt.wantErr {
+					t.Error(err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("want error")
+			}
+			got := o.store.steps[0]
+			if diff := cmp.Diff(got, tt.want, nil); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestOpenDBReadOnlySQLite(t *testing.T) {
+	ctx := context.Background()
+	_, dsn := testutil.SQLite(t)
+	db, err := openDB(dsn, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	if _, err := db.ExecContext(ctx, "SELECT 1"); err != nil {
+		t.Error(err)
+	}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER)"); err == nil {
+		t.Error("want error")
+	}
+}
+
 func TestSeparateStmt(t *testing.T) {
 	tests := []struct {
 		stmt string
@@ -269,11 +542,60 @@ SELECT * FROM users;
 				"SELECT * FROM users;",
 			},
 		},
+		{
+			"SELECT 1 -- it's\n; DELETE FROM u",
+			[]string{"SELECT 1 -- it's\n;", " DELETE FROM u"},
+		},
+		{
+			"SELECT 'a;b'; SELECT \"c;d\" /* ; */",
+			[]string{"SELECT 'a;b';", " SELECT \"c;d\" /* ; */"},
+		},
 	}
 	for _, tt := range tests {
-		got := separateStmt(tt.stmt)
+		got := separateStmt(tt.stmt, dbDialectUnknown)
 		if diff := cmp.Diff(got, tt.want, nil); diff != "" {
 			t.Errorf("%s", diff)
 		}
 	}
 }
+
+func TestDBRunnerCloseFailureInRunN(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testutil.SQLite(t)
+	dir := t.TempDir()
+	initdb, err := os.ReadFile(filepath.Join(testutil.Testdata(), "book", "initdb.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "initdb.yml"), initdb, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users.yml"), []byte("- id: 20\n  username: frank\n  password: passw0rd\n  email: frank@example.com\n  created: 2022-02-22\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	book := `desc: Drop the table of the fixture before the cleanup
+steps:
+  -
+    include: initdb.yml
+  -
+    db:
+      fixtures: users.yml
+      strategy: delete-after-run
+  -
+    db:
+      query: DROP TABLE users
+`
+	if err := os.WriteFile(filepath.Join(dir, "book.yml"), []byte(book), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := Load(filepath.Join(dir, "book.yml"), DBRunner("db", db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ops.RunN(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !ops.Result().HasFailure() {
+		t.Error("the runbook failed to clean up the fixtures should be failure")
+	}
+}
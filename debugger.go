@@ -1,12 +1,15 @@
 package runn
 
 import (
+	"database/sql"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/olekukonko/tablewriter"
@@ -14,7 +17,13 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-var _ Capturer = (*debugger)(nil)
+var (
+	_ Capturer                = (*debugger)(nil)
+	_ HTTPEventCapturer       = (*debugger)(nil)
+	_ HTTPTimingCapturer      = (*debugger)(nil)
+	_ WSCapturer              = (*debugger)(nil)
+	_ DBStatementArgsCapturer = (*debugger)(nil)
+)
 
 type debugger struct {
 	out           io.Writer
@@ -42,6 +51,14 @@ func (d *debugger) CaptureHTTPResponse(name string, res *http.Response) {
 	_, _ = fmt.Fprintf(d.out, "-----START HTTP RESPONSE-----\n%s\n-----END HTTP RESPONSE-----\n", string(b))
 }
 
+func (d *debugger) CaptureHTTPResponseEvent(name string, e map[string]any) {
+	_, _ = fmt.Fprintf(d.out, "-----START HTTP RESPONSE EVENT-----\n%s\n-----END HTTP RESPONSE EVENT-----\n", dumpMapInterface(e))
+}
+
+func (d *debugger) CaptureHTTPTiming(name string, timing *HTTPTiming, tlsInfo *HTTPTLSInfo) {
+	_, _ = fmt.Fprintf(d.out, "-----START HTTP TIMING-----\n%s-----END HTTP TIMING-----\n", dumpHTTPTiming(timing, tlsInfo))
+}
+
 func (d *debugger) CaptureGRPCStart(name string, typ GRPCType, service, method string) {
 	_, _ = fmt.Fprintf(d.out, ">>>>>START gRPC (%s/%s)>>>>>\n", service, method)
 }
@@ -60,6 +77,10 @@ func (d *debugger) CaptureGRPCResponseStatus(s *status.Status) {
 	if c != codes.OK {
 		m = fmt.Sprintf("%s (%d): %s", c.String(), int(c), s.Message())
 	}
+	// Each detail is separated like a YAML document.
+	for _, detail := range GRPCStatusDetails(s) {
+		m = fmt.Sprintf("%s\n---\n%s", m, dumpGRPCMessage(detail))
+	}
 	_, _ = fmt.Fprintf(d.out, "-----START gRPC RESPONSE STATUS-----\n%s\n-----END gRPC RESPONSE STATUS-----\n", m)
 }
 
@@ -81,6 +102,32 @@ func (d *debugger) CaptureGRPCEnd(name string, typ GRPCType, service, method str
 	_, _ = fmt.Fprintf(d.out, "<<<<<END gRPC (%s/%s)<<<<<\n", service, method)
 }
 
+func (d *debugger) CaptureWSStart(name, endpoint string, subprotocols []string) {
+	_, _ = fmt.Fprintf(d.out, ">>>>>START WebSocket (%s)>>>>>\n", endpoint)
+}
+
+func (d *debugger) CaptureWSRequestHeaders(h map[string][]string) {
+	_, _ = fmt.Fprintf(d.out, "-----START WebSocket REQUEST HEADERS-----\n%s\n-----END WebSocket REQUEST HEADERS-----\n", dumpGRPCMetadata(h))
+}
+
+func (d *debugger) CaptureWSRequestMessage(m any) {
+	_, _ = fmt.Fprintf(d.out, "-----START WebSocket REQUEST MESSAGE-----\n%s\n-----END WebSocket REQUEST MESSAGE-----\n", dumpWSMessage(m))
+}
+
+func (d *debugger) CaptureWSResponseHeaders(h map[string][]string) {
+	_, _ = fmt.Fprintf(d.out, "-----START WebSocket RESPONSE HEADERS-----\n%s\n-----END WebSocket RESPONSE HEADERS-----\n", dumpGRPCMetadata(h))
+}
+
+func (d *debugger) CaptureWSResponseMessage(m any) {
+	_, _ = fmt.Fprintf(d.out, "-----START WebSocket RESPONSE MESSAGE-----\n%s\n-----END WebSocket RESPONSE MESSAGE-----\n", dumpWSMessage(m))
+}
+
+func (d *debugger) CaptureWSClientClose() {}
+
+func (d *debugger) CaptureWSEnd(name, endpoint string) {
+	_, _ = fmt.Fprintf(d.out, "<<<<<END WebSocket (%s)<<<<<\n", endpoint)
+}
+
 func (d *debugger) CaptureCDPStart(name string) {
 	_, _ = fmt.Fprint(d.out, ">>>>>START CDP>>>>>\n")
 }
@@ -107,7 +154,14 @@ func (d *debugger) CaptureSSHStderr(stderr string) {
 }
 
 func (d *debugger) CaptureDBStatement(name string, stmt string) {
+	d.CaptureDBStatementArgs(name, stmt, nil)
+}
+
+func (d *debugger) CaptureDBStatementArgs(name string, stmt string, args []any) {
 	_, _ = fmt.Fprintf(d.out, "-----START QUERY-----\n%s\n-----END QUERY-----\n", stmt)
+	if len(args) > 0 {
+		_, _ = fmt.Fprintf(d.out, "-----START QUERY ARGS-----\n%s\n-----END QUERY ARGS-----\n", dumpDBArgs(args))
+	}
 }
 
 func (d *debugger) CaptureDBResponse(name string, res *DBResponse) {
@@ -185,11 +239,43 @@ func dumpMapInterface(m map[string]any) string {
 	return strings.Join(d, "\n")
 }
 
+// dumpDBArgs dumps the args with the 1-based position or the name.
+func dumpDBArgs(args []any) string {
+	var d []string
+	for i, a := range args {
+		k := strconv.Itoa(i + 1)
+		if na, ok := a.(sql.NamedArg); ok {
+			k = na.Name
+			a = na.Value
+		}
+		switch v := a.(type) {
+		case string:
+			d = append(d, fmt.Sprintf(`%s: %#v`, k, v))
+		default:
+			b, _ := json.Marshal(v)
+			d = append(d, fmt.Sprintf(`%s: %s`, k, string(b)))
+		}
+	}
+	return strings.Join(d, "\n")
+}
+
 var (
 	dumpCDPValues   = dumpMapInterface
 	dumpGRPCMessage = dumpMapInterface
 )
 
+func dumpWSMessage(m any) string {
+	switch v := m.(type) {
+	case map[string]any:
+		return dumpMapInterface(v)
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
 func dumpGRPCMetadata(m map[string][]string) string {
 	var keys []string
 	for k := range m {
@@ -205,3 +291,20 @@ func dumpGRPCMetadata(m map[string][]string) string {
 	}
 	return strings.Join(d, "\n")
 }
+
+func dumpHTTPTiming(timing *HTTPTiming, tlsInfo *HTTPTLSInfo) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "dns: %s\n", timing.DNS)
+	_, _ = fmt.Fprintf(&b, "connect: %s\n", timing.Connect)
+	_, _ = fmt.Fprintf(&b, "tls: %s\n", timing.TLSHandshake)
+	_, _ = fmt.Fprintf(&b, "ttfb: %s\n", timing.TTFB)
+	_, _ = fmt.Fprintf(&b, "transfer: %s\n", timing.Transfer)
+	_, _ = fmt.Fprintf(&b, "total: %s\n", timing.Total)
+	if tlsInfo != nil {
+		_, _ = fmt.Fprintf(&b, "tls version: %s\n", tlsInfo.Version)
+		_, _ = fmt.Fprintf(&b, "tls cipher: %s\n", tlsInfo.CipherSuite)
+		_, _ = fmt.Fprintf(&b, "peer certificate subject: %s\n", tlsInfo.Subject)
+		_, _ = fmt.Fprintf(&b, "peer certificate expiry: %s\n", tlsInfo.NotAfter.Format(time.RFC3339))
+	}
+	return b.String()
+}
@@ -3,6 +3,7 @@ package runn
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,10 +13,16 @@ import (
 
 	"github.com/k1LoW/runn/testutil"
 	"github.com/tenntenn/golden"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 var testDebuggerHostRe = regexp.MustCompile(`(?s)Host:[^\r\n]+\r\n`)
 var testDebuggerDateRe = regexp.MustCompile(`(?s)Date:[^\r\n]+\r\n`)
+var testDebuggerTimingRe = regexp.MustCompile(`(?m)^(dns|connect|tls|ttfb|transfer|total): .+$`)
 
 func TestDebugger(t *testing.T) {
 	tests := []struct {
@@ -58,6 +65,7 @@ func TestDebugger(t *testing.T) {
 			if strings.Contains(tt.book, "http.yml") {
 				got = testDebuggerHostRe.ReplaceAllString(got, "Host: replace.example.com\r\n")
 				got = testDebuggerDateRe.ReplaceAllString(got, "Date: Wed, 07 Sep 2022 06:28:20 GMT\r\n")
+				got = testDebuggerTimingRe.ReplaceAllString(got, "$1: 0s")
 			}
 
 			f := fmt.Sprintf("%s.debugger", filepath.Base(tt.book))
@@ -109,3 +117,41 @@ func TestDebuggerWithStderr(t *testing.T) {
 		})
 	}
 }
+
+func TestDebuggerGRPCResponseStatusWithDetails(t *testing.T) {
+	detail, err := anypb.New(&errdetails.ErrorInfo{Reason: "INVALID_NAME", Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := status.FromProto(&spb.Status{
+		Code:    int32(codes.InvalidArgument),
+		Message: "invalid name",
+		Details: []*anypb.Any{detail},
+	})
+	out := new(bytes.Buffer)
+	NewDebugger(out).CaptureGRPCResponseStatus(s)
+	got := out.String()
+	for _, want := range []string{"InvalidArgument (3): invalid name\n---\n", `@type: "type.googleapis.com/google.rpc.ErrorInfo"`, `reason: "INVALID_NAME"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestDebuggerDBStatementWithArgs(t *testing.T) {
+	tests := []struct {
+		args []any
+		want string
+	}{
+		{nil, "-----START QUERY-----\nSELECT 1\n-----END QUERY-----\n"},
+		{[]any{uint64(1), "alice"}, "-----START QUERY-----\nSELECT 1\n-----END QUERY-----\n-----START QUERY ARGS-----\n1: 1\n2: \"alice\"\n-----END QUERY ARGS-----\n"},
+		{[]any{sql.Named("id", uint64(1))}, "-----START QUERY-----\nSELECT 1\n-----END QUERY-----\n-----START QUERY ARGS-----\nid: 1\n-----END QUERY ARGS-----\n"},
+	}
+	for _, tt := range tests {
+		out := new(bytes.Buffer)
+		NewDebugger(out).CaptureDBStatementArgs("db", "SELECT 1", tt.args)
+		if got := out.String(); got != tt.want {
+			t.Errorf("got %q\nwant %q", got, tt.want)
+		}
+	}
+}
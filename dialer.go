@@ -0,0 +1,222 @@
+package runn
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	httpUnixScheme = "http+unix"
+	grpcUnixScheme = "grpc+unix"
+)
+
+// dialer dials connections with overridden name resolution (like `curl --resolve`), via a proxy, or to a Unix domain socket.
+type dialer struct {
+	// host:port (or host) -> ip:port (or ip)
+	resolve map[string]string
+	proxy   *url.URL
+	socket  string
+	d       *net.Dialer
+}
+
+func newDialer(proxyURL string, resolve map[string]string, socket string) (*dialer, error) {
+	d := &dialer{
+		resolve: resolve,
+		socket:  socket,
+		d: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+	if proxyURL != "" {
+		u, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		d.proxy = u
+	}
+	return d, nil
+}
+
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy: unsupported scheme: %s", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy: host is required: %s", proxyURL)
+	}
+	return u, nil
+}
+
+// DialContext connects to the address.
+// If the proxy is set, the address is sent to the proxy as it is and only the address of the proxy is resolved using resolve.
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.socket != "" || d.proxy == nil {
+		return d.dialDirect(ctx, network, addr)
+	}
+	switch d.proxy.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if d.proxy.User != nil {
+			p, _ := d.proxy.User.Password()
+			auth = &proxy.Auth{User: d.proxy.User.Username(), Password: p}
+		}
+		pd, err := proxy.SOCKS5("tcp", d.resolveAddr(proxyHostPort(d.proxy)), auth, d.d)
+		if err != nil {
+			return nil, err
+		}
+		return pd.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	default:
+		return d.dialHTTPConnect(ctx, addr)
+	}
+}
+
+// dialDirect connects to the address (or the Unix domain socket) without the proxy.
+func (d *dialer) dialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.socket != "" {
+		return d.d.DialContext(ctx, "unix", d.socket)
+	}
+	return d.d.DialContext(ctx, network, d.resolveAddr(addr))
+}
+
+// dialHTTPConnect opens a tunnel to the address using HTTP CONNECT method.
+func (d *dialer) dialHTTPConnect(ctx context.Context, addr string) (net.Conn, error) {
+	if d.proxy.Scheme == "https" {
+		return nil, fmt.Errorf("unsupported proxy scheme for tunneling: %s", d.proxy.Scheme)
+	}
+	conn, err := d.d.DialContext(ctx, "tcp", d.resolveAddr(proxyHostPort(d.proxy)))
+	if err != nil {
+		return nil, err
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if d.proxy.User != nil {
+		p, _ := d.proxy.User.Password()
+		req.Header.Set("Proxy-Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username()+":"+p))))
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+		defer func() {
+			_ = conn.SetDeadline(time.Time{})
+		}()
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to connect via proxy %s: %s", d.proxy.Host, res.Status)
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// resolveAddr returns the overridden address using resolve.
+func (d *dialer) resolveAddr(addr string) string {
+	if len(d.resolve) == 0 {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	to, ok := d.resolve[addr]
+	if !ok {
+		to, ok = d.resolve[host]
+	}
+	if !ok {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(to); err == nil {
+		return to
+	}
+	return net.JoinHostPort(strings.Trim(to, "[]"), port)
+}
+
+// setTransport sets the dialer and the proxy to the transport.
+// The transport connects to the proxy by itself, so the dialer of the transport does not use the proxy.
+func (d *dialer) setTransport(ts *http.Transport) {
+	switch {
+	case d.socket != "":
+		ts.Proxy = nil
+	case d.proxy != nil:
+		ts.Proxy = http.ProxyURL(d.proxy)
+	}
+	ts.DialContext = d.dialDirect
+}
+
+func proxyHostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch u.Scheme {
+	case "https":
+		return net.JoinHostPort(u.Hostname(), "443")
+	case "socks5", "socks5h":
+		return net.JoinHostPort(u.Hostname(), "1080")
+	default:
+		return net.JoinHostPort(u.Hostname(), "80")
+	}
+}
+
+// splitUnixEndpoint splits the endpoint such as `http+unix:///path/to.sock` into the path of the socket and the base path.
+// The socket path can also be URL-encoded in the host part such as `http+unix://%2Fpath%2Fto.sock/api/v1`.
+func splitUnixEndpoint(endpoint string) (string, string, error) {
+	scheme, rest, ok := strings.Cut(endpoint, "://")
+	if !ok || (scheme != httpUnixScheme && scheme != grpcUnixScheme) {
+		return "", "", fmt.Errorf("invalid unix endpoint: %s", endpoint)
+	}
+	if strings.HasPrefix(rest, "/") {
+		return rest, "", nil
+	}
+	host, p, _ := strings.Cut(rest, "/")
+	socket, err := url.PathUnescape(host)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid unix endpoint: %s: %w", endpoint, err)
+	}
+	if socket == "" {
+		return "", "", fmt.Errorf("invalid unix endpoint: %s", endpoint)
+	}
+	if p != "" {
+		p = "/" + p
+	}
+	return socket, p, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,268 @@
+package runn
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/k1LoW/runn/testutil"
+)
+
+func TestResolveAddr(t *testing.T) {
+	d, err := newDialer("", map[string]string{
+		"example.com:443": "127.0.0.1:8443",
+		"example.com":     "127.0.0.2",
+		"v6.example.com":  "[::1]",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"example.com:443", "127.0.0.1:8443"},
+		{"example.com:80", "127.0.0.2:80"},
+		{"v6.example.com:80", "[::1]:80"},
+		{"example.org:80", "example.org:80"},
+	}
+	for _, tt := range tests {
+		if got := d.resolveAddr(tt.in); got != tt.want {
+			t.Errorf("got %v\nwant %v", got, tt.want)
+		}
+	}
+}
+
+func TestSplitUnixEndpoint(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantSocket string
+		wantPath   string
+		wantErr    bool
+	}{
+		{"http+unix:///var/run/app.sock", "/var/run/app.sock", "", false},
+		{"http+unix://%2Fvar%2Frun%2Fapp.sock/api/v1", "/var/run/app.sock", "/api/v1", false},
+		{"grpc+unix:///var/run/app.sock", "/var/run/app.sock", "", false},
+		{"http+unix://", "", "", true},
+		{"unix:///var/run/app.sock", "", "", true},
+	}
+	for _, tt := range tests {
+		socket, p, err := splitUnixEndpoint(tt.in)
+		if err != nil {
+			if !tt.wantErr {
+				t.Errorf("got error: %v", err)
+			}
+			continue
+		}
+		if tt.wantErr {
+			t.Errorf("want error: %s", tt.in)
+		}
+		if socket != tt.wantSocket {
+			t.Errorf("got %v\nwant %v", socket, tt.wantSocket)
+		}
+		if p != tt.wantPath {
+			t.Errorf("got %v\nwant %v", p, tt.wantPath)
+		}
+	}
+}
+
+func TestHTTPRunnerWithDialer(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+r.URL.Path)
+	}))
+	t.Cleanup(ts.Close)
+
+	sock := filepath.Join(t.TempDir(), "http.sock")
+	ul, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	us := &httptest.Server{Listener: ul, Config: &http.Server{Handler: ts.Config.Handler}}
+	us.Start()
+	t.Cleanup(us.Close)
+
+	var proxied int64
+	ps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&proxied, 1)
+		_, _ = io.WriteString(w, "proxy:"+r.URL.Host+r.URL.Path)
+	}))
+	t.Cleanup(ps.Close)
+
+	tests := []struct {
+		name     string
+		endpoint string
+		opts     []httpRunnerOption
+		want     string
+	}{
+		{"resolve", "http://app.test", []httpRunnerOption{HTTPResolve("app.test:80", ts.Listener.Addr().String())}, "app.test/users"},
+		{"unix", "http+unix://" + sock, nil, "localhost/users"},
+		{"unix with base path", "http+unix://" + url.PathEscape(sock) + "/v1", []httpRunnerOption{HTTPTimeout("10sec")}, "localhost/v1/users"},
+		{"proxy", "http://app.test", []httpRunnerOption{HTTPProxy(ps.URL)}, "proxy:app.test/users"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(Runner("req", tt.endpoint, tt.opts...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.httpRunners["req"].Run(ctx, &httpRequest{path: "/users", method: http.MethodGet}); err != nil {
+				t.Fatal(err)
+			}
+			res := o.store.latest()["res"].(map[string]any)
+			if got := res["rawBody"].(string); got != tt.want {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+	if atomic.LoadInt64(&proxied) != 1 {
+		t.Errorf("got %v\nwant %v", proxied, 1)
+	}
+}
+
+func TestParseRunnerWithDialer(t *testing.T) {
+	bk := newBook()
+	if err := bk.parseRunner("req", map[string]any{
+		"endpoint": "https://example.com",
+		"proxy":    "socks5://127.0.0.1:1080",
+		"resolve": map[string]any{
+			"example.com:443": "127.0.0.1:8443",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	d := bk.httpRunners["req"].dialer
+	if d == nil || d.proxy.String() != "socks5://127.0.0.1:1080" || d.resolve["example.com:443"] != "127.0.0.1:8443" {
+		t.Errorf("invalid dialer: %#v", d)
+	}
+	if err := bk.parseRunner("greq", map[string]any{
+		"addr":  "grpc+unix:///var/run/app.sock",
+		"proxy": "http://127.0.0.1:8080",
+	}); err == nil {
+		t.Error("want error")
+	}
+	if err := bk.parseRunner("req", map[string]any{
+		"endpoint": "https://example.com",
+		"proxy":    "ftp://127.0.0.1",
+	}); err == nil {
+		t.Error("want error")
+	}
+	if err := bk.parseRunner("greq", "grpc+unix:///var/run/app.sock"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bk.grpcRunners["greq"].target, "unix:///var/run/app.sock"; got != want {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestGrpcRunnerWithDialer(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.GRPCServer(t, false, false)
+
+	sock := filepath.Join(t.TempDir(), "grpc.sock")
+	ul, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = ul.Close()
+	})
+	go forward(ul, ts.Addr())
+
+	var connected int64
+	ps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt64(&connected, 1)
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		src, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = dst.Close()
+			return
+		}
+		if _, err := io.WriteString(src, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+			_ = src.Close()
+			_ = dst.Close()
+			return
+		}
+		pipe(src, dst)
+	}))
+	t.Cleanup(ps.Close)
+
+	tests := []struct {
+		name   string
+		target string
+		opts   []grpcRunnerOption
+	}{
+		{"resolve", "grpc.test:1234", []grpcRunnerOption{TLS(false), Resolve("grpc.test:1234", ts.Addr())}},
+		{"unix", "grpc+unix://" + sock, nil},
+		{"proxy", ts.Addr(), []grpcRunnerOption{TLS(false), Proxy(ps.URL)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(GrpcRunnerWithOptions("greq", tt.target, tt.opts...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := o.grpcRunners["greq"]
+			t.Cleanup(func() {
+				_ = r.Close()
+			})
+			req := &grpcRequest{
+				service: "grpctest.GrpcTestService",
+				method:  "Hello",
+				messages: []*grpcMessage{
+					{op: GRPCOpMessage, params: map[string]any{"name": "alice"}},
+				},
+			}
+			if err := r.Run(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			res := o.store.latest()["res"].(map[string]any)
+			if got := res["status"].(int); got != 0 {
+				t.Errorf("got %v\nwant %v", got, 0)
+			}
+		})
+	}
+	if atomic.LoadInt64(&connected) != 1 {
+		t.Errorf("got %v\nwant %v", connected, 1)
+	}
+}
+
+func forward(l net.Listener, to string) {
+	for {
+		src, err := l.Accept()
+		if err != nil {
+			return
+		}
+		dst, err := net.Dial("tcp", to)
+		if err != nil {
+			_ = src.Close()
+			continue
+		}
+		pipe(src, dst)
+	}
+}
+
+func pipe(a, b net.Conn) {
+	go func() {
+		_, _ = io.Copy(a, b)
+		_ = a.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		_ = b.Close()
+	}()
+}
@@ -21,43 +21,54 @@ var intRe = regexp.MustCompile(`^\-?[0-9]+$`)
 var floatRe = regexp.MustCompile(`^\-?[0-9.]+$`)
 
 type Flags struct {
-	Debug           bool     `usage:"debug"`
-	Long            bool     `usage:"long format"`
-	FailFast        bool     `usage:"fail fast"`
-	SkipTest        bool     `usage:"skip \"test:\" section"`
-	SkipIncluded    bool     `usage:"skip running the included runbook by itself"`
-	RunMatch        string   `usage:"run all runbooks with a matching file path, treating the value passed to the option as an unanchored regular expression"`
-	RunID           string   `usage:"run the matching runbook if there is only one runbook with a forward matching ID"`
-	GRPCNoTLS       bool     `usage:"disable TLS use in all gRPC runners"`
-	GRPCProtos      []string `usage:"set the name of proto source for all gRPC runners"`
-	GRPCImportPaths []string `usage:"set the path to the directory where proto sources can be imported for all gRPC runners"`
-	CaptureDir      string   `usage:"destination of runbook run capture results"`
-	Vars            []string `usage:"set var to runbook (\"key:value\")"`
-	Runners         []string `usage:"set runner to runbook (\"key:dsn\")"`
-	Overlays        []string `usage:"overlay values on the runbook"`
-	Underlays       []string `usage:"lay values under the runbook"`
-	Sample          int      `usage:"sample the specified number of runbooks"`
-	Shuffle         string   `usage:"randomize the order of running runbooks (\"on\",\"off\",N)"`
-	Concurrent      string   `usage:"run runbooks concurrently (\"on\",\"off\",N)"`
-	ShardIndex      int      `usage:"index of distributed runbooks"`
-	ShardN          int      `usage:"number of shards for distributing runbooks"`
-	Random          int      `usage:"run the specified number of runbooks at random"`
-	Desc            string   `usage:"description of runbook"`
-	Out             string   `usage:"target path of runbook"`
-	Format          string   `usage:"format of result output"`
-	AndRun          bool     `usage:"run created runbook and capture the response for test"`
-	LoadTConcurrent int      `usage:"number of concurrent load test runs"`
-	LoadTDuration   string   `usage:"load test running duration"`
-	LoadTWarmUp     string   `usage:"warn-up time for load test"`
-	LoadTThreshold  string   `usage:"if this threshold condition is not met, loadt command returns exit status 1 (EXIT_FAILURE)"`
-	Profile         bool     `usage:"profile runs of runbooks"`
-	ProfileOut      string   `usage:"profile output path"`
-	ProfileDepth    int      `usage:"depth of profile"`
-	ProfileUnit     string   `usage:"-"`
-	ProfileSort     string   `usage:"-"`
-	CacheDir        string   `usage:"specify cache directory for remote runbooks"`
-	RetainCacheDir  bool     `usage:"retain cache directory for remote runbooks"`
-	Verbose         bool     `usage:"verbose"`
+	Debug              bool     `usage:"debug"`
+	Long               bool     `usage:"long format"`
+	FailFast           bool     `usage:"fail fast"`
+	SkipTest           bool     `usage:"skip \"test:\" section"`
+	SkipIncluded       bool     `usage:"skip running the included runbook by itself"`
+	RunMatch           string   `usage:"run all runbooks with a matching file path, treating the value passed to the option as an unanchored regular expression"`
+	RunID              string   `usage:"run the matching runbook if there is only one runbook with a forward matching ID"`
+	GRPCNoTLS          bool     `usage:"disable TLS use in all gRPC runners"`
+	GRPCProtos         []string `usage:"set the name of proto source for all gRPC runners"`
+	GRPCImportPaths    []string `usage:"set the path to the directory where proto sources can be imported for all gRPC runners"`
+	GRPCProtosets      []string `usage:"set the path of protoset file (FileDescriptorSet) for all gRPC runners"`
+	GRPCBufImages      []string `usage:"set the path of buf image file for all gRPC runners"`
+	CaptureDir         string   `usage:"destination of runbook run capture results"`
+	CaptureHARDir      string   `usage:"destination of HAR files of HTTP requests and responses in runbook runs"`
+	Vars               []string `usage:"set var to runbook (\"key:value\")"`
+	Runners            []string `usage:"set runner to runbook (\"key:dsn\")"`
+	Overlays           []string `usage:"overlay values on the runbook"`
+	Underlays          []string `usage:"lay values under the runbook"`
+	Sample             int      `usage:"sample the specified number of runbooks"`
+	Shuffle            string   `usage:"randomize the order of running runbooks (\"on\",\"off\",N)"`
+	Concurrent         string   `usage:"run runbooks concurrently (\"on\",\"off\",N)"`
+	ShardIndex         int      `usage:"index of distributed runbooks"`
+	ShardN             int      `usage:"number of shards for distributing runbooks"`
+	Random             int      `usage:"run the specified number of runbooks at random"`
+	Desc               string   `usage:"description of runbook"`
+	Out                string   `usage:"target path of runbook"`
+	Book               string   `usage:"path of runbook that defines the runner"`
+	Format             string   `usage:"format of result output"`
+	AndRun             bool     `usage:"run created runbook and capture the response for test"`
+	OpenAPI3           string   `usage:"generate runbooks from the OpenAPI 3 document (file path or URL)"`
+	OpenAPI3GroupBy    string   `usage:"unit of runbooks generated from the OpenAPI 3 document (\"operation\",\"tag\")"`
+	HAR                string   `usage:"generate steps from the HAR file"`
+	HARHosts           []string `usage:"hosts of HAR entries to generate steps"`
+	HARMethods         []string `usage:"methods of HAR entries to generate steps"`
+	LoadTConcurrent    int      `usage:"number of concurrent load test runs"`
+	LoadTDuration      string   `usage:"load test running duration"`
+	LoadTWarmUp        string   `usage:"warn-up time for load test"`
+	LoadTThreshold     string   `usage:"if this threshold condition is not met, loadt command returns exit status 1 (EXIT_FAILURE)"`
+	Profile            bool     `usage:"profile runs of runbooks"`
+	ProfileOut         string   `usage:"profile output path"`
+	ProfileDepth       int      `usage:"depth of profile"`
+	ProfileUnit        string   `usage:"-"`
+	ProfileSort        string   `usage:"-"`
+	OpenAPICoverage    bool     `usage:"report coverage of operations of OpenAPI 3 documents exercised by HTTP runners"`
+	OpenAPICoverageOut string   `usage:"output path of OpenAPI 3 coverage report as JSON"`
+	CacheDir           string   `usage:"specify cache directory for remote runbooks"`
+	RetainCacheDir     bool     `usage:"retain cache directory for remote runbooks"`
+	Verbose            bool     `usage:"verbose"`
 }
 
 func (f *Flags) ToOpts() ([]runn.Option, error) {
@@ -74,6 +85,8 @@ func (f *Flags) ToOpts() ([]runn.Option, error) {
 		runn.GRPCNoTLS(f.GRPCNoTLS),
 		runn.GRPCProtos(f.GRPCProtos),
 		runn.GRPCImportPaths(f.GRPCImportPaths),
+		runn.GRPCProtosets(f.GRPCProtosets),
+		runn.GRPCBufImages(f.GRPCBufImages),
 		runn.Profile(f.Profile),
 	}
 	if f.RunID != "" {
@@ -169,6 +182,16 @@ func (f *Flags) ToOpts() ([]runn.Option, error) {
 		}
 		opts = append(opts, runn.Capture(capture.Runbook(f.CaptureDir)))
 	}
+	if f.CaptureHARDir != "" {
+		fi, err := os.Stat(f.CaptureHARDir)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("%s is not directory", f.CaptureHARDir)
+		}
+		opts = append(opts, runn.Capture(capture.HAR(f.CaptureHARDir)))
+	}
 	return opts, nil
 }
 
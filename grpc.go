@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,6 +17,7 @@ import (
 	"github.com/goccy/go-json"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/k1LoW/duration"
 	"github.com/k1LoW/runn/version"
 	"github.com/ktr0731/evans/grpc/grpcreflection"
 	"github.com/mitchellh/copystructure"
@@ -22,6 +25,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -29,6 +33,7 @@ import (
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
@@ -49,6 +54,8 @@ const (
 	GRPCOpClose   GRPCOp = "close"
 )
 
+const grpcCompressionGzip = "gzip"
+
 const (
 	grpcStoreStatusKey   = "status"
 	grpcStoreHeaderKey   = "headers"
@@ -68,7 +75,21 @@ type grpcRunner struct {
 	skipVerify  bool
 	importPaths []string
 	protos      []string
-	cc          *grpc.ClientConn
+	protosets   []string
+	bufImages   []string
+	dialer      *dialer
+	// default timeout of each call. It is overridden by the timeout of the step.
+	timeout        time.Duration
+	keepalive      *keepalive.ClientParameters
+	maxSendMsgSize int
+	maxRecvMsgSize int
+	compression    string
+	authority      string
+	protocol       string
+	cc             *grpc.ClientConn
+	// HTTP client and base URL for gRPC-Web and Connect protocol
+	httpClient  *http.Client
+	httpBaseURL string
 	mds         map[string]protoreflect.MethodDescriptor
 	operator    *operator
 }
@@ -87,84 +108,164 @@ type grpcRequest struct {
 }
 
 func newGrpcRunner(name, target string) (*grpcRunner, error) {
+	if strings.HasPrefix(target, grpcUnixScheme+"://") {
+		socket, _, err := splitUnixEndpoint(target)
+		if err != nil {
+			return nil, err
+		}
+		// Use the unix resolver of grpc-go
+		target = fmt.Sprintf("unix://%s", socket)
+	}
 	return &grpcRunner{
-		name:   name,
-		target: target,
-		mds:    map[string]protoreflect.MethodDescriptor{},
+		name:     name,
+		target:   target,
+		protocol: GRPCProtocolGRPC,
+		mds:      map[string]protoreflect.MethodDescriptor{},
 	}, nil
 }
 
-func (rnr *grpcRunner) Close() error {
-	if rnr.cc == nil {
+// setDialer sets the proxy and the overrides of name resolution.
+func (rnr *grpcRunner) setDialer(proxyURL string, resolve map[string]string) error {
+	if proxyURL == "" && len(resolve) == 0 {
 		return nil
 	}
-	return rnr.cc.Close()
+	if rnr.isUnix() {
+		return errors.New("proxy and resolve cannot be used with unix socket")
+	}
+	d, err := newDialer(proxyURL, resolve, "")
+	if err != nil {
+		return err
+	}
+	rnr.dialer = d
+	return nil
 }
 
-func (rnr *grpcRunner) Run(ctx context.Context, r *grpcRequest) error {
-	if rnr.cc == nil {
-		opts := []grpc.DialOption{
-			grpc.WithReturnConnectionError(),
-			grpc.WithUserAgent(fmt.Sprintf("runn/%s", version.Version)),
-		}
-		useTLS := true
-		if strings.HasSuffix(rnr.target, ":80") {
-			useTLS = false
+// setConnConfig sets the default timeout of the call and the settings of the connection.
+func (rnr *grpcRunner) setConnConfig(c *grpcRunnerConfig) error {
+	if c.Timeout != "" {
+		d, err := duration.Parse(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
 		}
-		if rnr.tls != nil {
-			useTLS = *rnr.tls
+		rnr.timeout = d
+	}
+	if c.Keepalive != nil {
+		kp := &keepalive.ClientParameters{
+			PermitWithoutStream: c.Keepalive.PermitWithoutStream,
 		}
-		if useTLS {
-			tlsc := tls.Config{MinVersion: tls.VersionTLS12}
-			if rnr.cert != nil {
-				certificate, err := tls.X509KeyPair(rnr.cert, rnr.key)
-				if err != nil {
-					return err
-				}
-				tlsc.Certificates = []tls.Certificate{certificate}
+		if c.Keepalive.Time != "" {
+			d, err := duration.Parse(c.Keepalive.Time)
+			if err != nil {
+				return fmt.Errorf("invalid keepalive.time: %w", err)
 			}
-			if rnr.skipVerify {
-				//#nosec G402
-				tlsc.InsecureSkipVerify = true
-			} else if rnr.cacert != nil {
-				certpool, err := x509.SystemCertPool()
-				if err != nil {
-					// FIXME for Windows
-					// ref: https://github.com/golang/go/issues/18609
-					certpool = x509.NewCertPool()
-				}
-				if ok := certpool.AppendCertsFromPEM(rnr.cacert); !ok {
-					return errors.New("failed to append cacert")
-				}
-				tlsc.RootCAs = certpool
+			kp.Time = d
+		}
+		if c.Keepalive.Timeout != "" {
+			d, err := duration.Parse(c.Keepalive.Timeout)
+			if err != nil {
+				return fmt.Errorf("invalid keepalive.timeout: %w", err)
 			}
-			opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tlsc)))
-		} else {
-			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			kp.Timeout = d
 		}
-		cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		defer cancel()
-		cc, err := grpc.DialContext(cctx, rnr.target, opts...)
+		rnr.keepalive = kp
+	}
+	if c.MaxSendMsgSize < 0 || c.MaxRecvMsgSize < 0 {
+		return errors.New("invalid max message size: must be positive")
+	}
+	rnr.maxSendMsgSize = c.MaxSendMsgSize
+	rnr.maxRecvMsgSize = c.MaxRecvMsgSize
+	switch c.Compression {
+	case "", grpcCompressionGzip:
+		rnr.compression = c.Compression
+	default:
+		return fmt.Errorf("unsupported compression: %s", c.Compression)
+	}
+	rnr.authority = c.Authority
+	switch c.Protocol {
+	case "":
+	case GRPCProtocolGRPC, GRPCProtocolGRPCWeb, GRPCProtocolConnect:
+		rnr.protocol = c.Protocol
+	default:
+		return fmt.Errorf("unsupported protocol: %s", c.Protocol)
+	}
+	if rnr.compression != "" && rnr.isHTTPProtocol() {
+		return fmt.Errorf("compression is not supported by %s protocol", rnr.protocol)
+	}
+	if rnr.keepalive != nil && rnr.isHTTPProtocol() {
+		return fmt.Errorf("keepalive is not supported by %s protocol", rnr.protocol)
+	}
+	return nil
+}
+
+func (rnr *grpcRunner) useTLS() bool {
+	if rnr.tls != nil {
+		return *rnr.tls
+	}
+	return !strings.HasSuffix(rnr.target, ":80") && !rnr.isUnix()
+}
+
+func (rnr *grpcRunner) tlsConfig() (*tls.Config, error) {
+	tlsc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if rnr.cert != nil {
+		certificate, err := tls.X509KeyPair(rnr.cert, rnr.key)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		rnr.cc = cc
+		tlsc.Certificates = []tls.Certificate{certificate}
 	}
-	if len(rnr.importPaths) > 0 || len(rnr.protos) > 0 {
-		if err := rnr.resolveAllMethodsUsingProtos(); err != nil {
-			return err
+	if rnr.skipVerify {
+		//#nosec G402
+		tlsc.InsecureSkipVerify = true
+	} else if rnr.cacert != nil {
+		certpool, err := x509.SystemCertPool()
+		if err != nil {
+			// FIXME for Windows
+			// ref: https://github.com/golang/go/issues/18609
+			certpool = x509.NewCertPool()
 		}
-	}
-	if len(rnr.mds) == 0 {
-		if err := rnr.resolveAllMethodsUsingReflection(ctx); err != nil {
-			return err
+		if ok := certpool.AppendCertsFromPEM(rnr.cacert); !ok {
+			return nil, errors.New("failed to append cacert")
 		}
+		tlsc.RootCAs = certpool
+	}
+	return tlsc, nil
+}
+
+func (rnr *grpcRunner) isUnix() bool {
+	return strings.HasPrefix(rnr.target, "unix:")
+}
+
+func (rnr *grpcRunner) Close() error {
+	if rnr.httpClient != nil {
+		rnr.httpClient.CloseIdleConnections()
+	}
+	if rnr.cc == nil {
+		return nil
+	}
+	return rnr.cc.Close()
+}
+
+func (rnr *grpcRunner) Run(ctx context.Context, r *grpcRequest) error {
+	if err := rnr.connect(ctx); err != nil {
+		return err
+	}
+	if err := rnr.resolveAllMethods(ctx); err != nil {
+		return err
 	}
 	key := strings.Join([]string{r.service, r.method}, "/")
 	md, ok := rnr.mds[key]
 	if !ok {
 		return fmt.Errorf("cannot find method: %s", key)
 	}
+	// The default timeout is not applied to bidirectional streaming RPC.
+	if r.timeout == 0 && rnr.timeout > 0 && !(md.IsStreamingServer() && md.IsStreamingClient()) {
+		rr := *r
+		rr.timeout = rnr.timeout
+		r = &rr
+	}
+	if rnr.isHTTPProtocol() {
+		return rnr.invokeOverHTTP(ctx, md, r)
+	}
 	switch {
 	case !md.IsStreamingServer() && !md.IsStreamingClient():
 		rnr.operator.capturers.captureGRPCStart(rnr.name, GRPCUnary, r.service, r.method)
@@ -222,6 +323,7 @@ func (rnr *grpcRunner) invokeUnary(ctx context.Context, md protoreflect.MethodDe
 		string(grpcStoreHeaderKey):  resHeaders,
 		string(grpcStoreTrailerKey): resTrailers,
 		string(grpcStoreMessageKey): nil,
+		string(grpcStoreDetailsKey): []map[string]any{},
 	}
 
 	rnr.operator.capturers.captureGRPCResponseStatus(stat)
@@ -246,6 +348,7 @@ func (rnr *grpcRunner) invokeUnary(ctx context.Context, md protoreflect.MethodDe
 		d[grpcStoreMessagesKey] = messages
 	} else {
 		d[grpcStoreMessageKey] = stat.Message()
+		d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
 	}
 
 	rnr.operator.record(map[string]any{
@@ -281,9 +384,13 @@ func (rnr *grpcRunner) invokeServerStreaming(ctx context.Context, md protoreflec
 
 	stream, err := rnr.cc.NewStream(ctx, streamDesc, toEndpoint(md.FullName()))
 	if err != nil {
+		if rnr.recordDeadlineExceeded(err) {
+			return nil
+		}
 		return err
 	}
-	if err := stream.SendMsg(req); err != nil {
+	// io.EOF means the stream has been aborted. The status is received by RecvMsg.
+	if err := stream.SendMsg(req); err != nil && !errors.Is(err, io.EOF) {
 		return err
 	}
 
@@ -291,6 +398,7 @@ func (rnr *grpcRunner) invokeServerStreaming(ctx context.Context, md protoreflec
 		string(grpcStoreHeaderKey):  metadata.MD{},
 		string(grpcStoreTrailerKey): metadata.MD{},
 		string(grpcStoreMessageKey): nil,
+		string(grpcStoreDetailsKey): []map[string]any{},
 	}
 	messages := []map[string]any{}
 
@@ -329,6 +437,7 @@ func (rnr *grpcRunner) invokeServerStreaming(ctx context.Context, md protoreflec
 			messages = append(messages, msg)
 		} else {
 			d[grpcStoreMessageKey] = stat.Message()
+			d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
 		}
 	}
 	d[grpcStoreMessagesKey] = messages
@@ -367,12 +476,16 @@ func (rnr *grpcRunner) invokeClientStreaming(ctx context.Context, md protoreflec
 	}
 	stream, err := rnr.cc.NewStream(ctx, streamDesc, toEndpoint(md.FullName()))
 	if err != nil {
+		if rnr.recordDeadlineExceeded(err) {
+			return nil
+		}
 		return err
 	}
 	d := map[string]any{
 		string(grpcStoreHeaderKey):  metadata.MD{},
 		string(grpcStoreTrailerKey): metadata.MD{},
 		string(grpcStoreMessageKey): nil,
+		string(grpcStoreDetailsKey): []map[string]any{},
 	}
 	messages := []map[string]any{}
 	for _, m := range r.messages {
@@ -425,6 +538,7 @@ func (rnr *grpcRunner) invokeClientStreaming(ctx context.Context, md protoreflec
 		messages = append(messages, msg)
 	} else {
 		d[grpcStoreMessageKey] = stat.Message()
+		d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
 	}
 
 	d[grpcStoreMessagesKey] = messages
@@ -468,6 +582,7 @@ func (rnr *grpcRunner) invokeBidiStreaming(ctx context.Context, md protoreflect.
 		string(grpcStoreHeaderKey):  metadata.MD{},
 		string(grpcStoreTrailerKey): metadata.MD{},
 		string(grpcStoreMessageKey): nil,
+		string(grpcStoreDetailsKey): []map[string]any{},
 	}
 	messages := []map[string]any{}
 	clientClose := false
@@ -526,6 +641,7 @@ L:
 				messages = append(messages, msg)
 			} else {
 				d[grpcStoreMessageKey] = stat.Message()
+				d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
 			}
 		case GRPCOpClose:
 			clientClose = true
@@ -543,6 +659,7 @@ L:
 	if stat.Code() != codes.OK {
 		d[grpcStoreStatusKey] = int64(stat.Code())
 		d[grpcStoreMessageKey] = stat.Message()
+		d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
 
 		rnr.operator.capturers.captureGRPCResponseStatus(stat)
 	}
@@ -598,6 +715,7 @@ L:
 					messages = append(messages, msg)
 				} else {
 					d[grpcStoreMessageKey] = stat.Message()
+					d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
 				}
 			}
 		}
@@ -628,6 +746,26 @@ L:
 	return nil
 }
 
+// recordDeadlineExceeded records the status when the deadline is exceeded before the stream starts.
+func (rnr *grpcRunner) recordDeadlineExceeded(err error) bool {
+	stat, ok := status.FromError(err)
+	if !ok || stat.Code() != codes.DeadlineExceeded {
+		return false
+	}
+	rnr.operator.capturers.captureGRPCResponseStatus(stat)
+	rnr.operator.record(map[string]any{
+		string(grpcStoreResponseKey): map[string]any{
+			string(grpcStoreStatusKey):   int64(stat.Code()),
+			string(grpcStoreHeaderKey):   metadata.MD{},
+			string(grpcStoreTrailerKey):  metadata.MD{},
+			string(grpcStoreMessageKey):  stat.Message(),
+			string(grpcStoreMessagesKey): []map[string]any{},
+			string(grpcStoreDetailsKey):  GRPCStatusDetails(stat),
+		},
+	})
+	return true
+}
+
 func setHeaders(ctx context.Context, h metadata.MD) context.Context {
 	kv := []string{}
 	for k, v := range h {
@@ -656,12 +794,95 @@ func (rnr *grpcRunner) setMessage(req proto.Message, message map[string]any) err
 	return protojson.Unmarshal(b, req)
 }
 
+// connect connects to the server ( or prepares the HTTP client for gRPC-Web and Connect protocol ).
+func (rnr *grpcRunner) connect(ctx context.Context) error {
+	if rnr.isHTTPProtocol() && rnr.httpClient == nil {
+		if err := rnr.setHTTPClient(); err != nil {
+			return err
+		}
+	}
+	if rnr.cc == nil && !rnr.isHTTPProtocol() {
+		opts := []grpc.DialOption{
+			grpc.WithReturnConnectionError(),
+			grpc.WithUserAgent(fmt.Sprintf("runn/%s", version.Version)),
+		}
+		if rnr.keepalive != nil {
+			opts = append(opts, grpc.WithKeepaliveParams(*rnr.keepalive))
+		}
+		var copts []grpc.CallOption
+		if rnr.maxSendMsgSize > 0 {
+			copts = append(copts, grpc.MaxCallSendMsgSize(rnr.maxSendMsgSize))
+		}
+		if rnr.maxRecvMsgSize > 0 {
+			copts = append(copts, grpc.MaxCallRecvMsgSize(rnr.maxRecvMsgSize))
+		}
+		if rnr.compression != "" {
+			// The compressor is set per connection instead of registering it in the global registry of grpc-go
+			// so that the connections without compression do not advertise grpc-accept-encoding.
+			opts = append(opts, grpc.WithCompressor(grpc.NewGZIPCompressor()), grpc.WithDecompressor(grpc.NewGZIPDecompressor())) //nolint:staticcheck
+		}
+		if len(copts) > 0 {
+			opts = append(opts, grpc.WithDefaultCallOptions(copts...))
+		}
+		if rnr.authority != "" {
+			opts = append(opts, grpc.WithAuthority(rnr.authority))
+		}
+		if rnr.dialer != nil {
+			opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				return rnr.dialer.DialContext(ctx, "tcp", addr)
+			}))
+		}
+		if rnr.useTLS() {
+			tlsc, err := rnr.tlsConfig()
+			if err != nil {
+				return err
+			}
+			opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsc)))
+		} else {
+			opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		}
+		cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		cc, err := grpc.DialContext(cctx, rnr.target, opts...)
+		if err != nil {
+			return err
+		}
+		rnr.cc = cc
+	}
+	return nil
+}
+
+// resolveAllMethods resolves methods using protosets, buf images, protos or the server reflection.
+func (rnr *grpcRunner) resolveAllMethods(ctx context.Context) error {
+	if len(rnr.protosets) > 0 || len(rnr.bufImages) > 0 {
+		if err := rnr.resolveAllMethodsUsingProtosets(); err != nil {
+			return err
+		}
+	}
+	if len(rnr.importPaths) > 0 || len(rnr.protos) > 0 {
+		if err := rnr.resolveAllMethodsUsingProtos(); err != nil {
+			return err
+		}
+	}
+	if len(rnr.mds) == 0 {
+		if rnr.isHTTPProtocol() {
+			return fmt.Errorf("protos, protosets or bufImage is required for %s protocol because the server reflection is not available", rnr.protocol)
+		}
+		if err := rnr.resolveAllMethodsUsingReflection(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (rnr *grpcRunner) resolveAllMethodsUsingReflection(ctx context.Context) error {
 	grefc := grpcreflection.NewClient(rnr.cc, map[string][]string{})
 	svcs, err := grefc.ListServices()
 	if err != nil {
 		return err
 	}
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]struct{}{}
 	for _, svc := range svcs {
 		fd, err := grefc.FindSymbol(svc)
 		if err != nil {
@@ -671,6 +892,7 @@ func (rnr *grpcRunner) resolveAllMethodsUsingReflection(ctx context.Context) err
 		if !ok {
 			return fmt.Errorf("failed to get service descripter of %s (%v)", svc, fd)
 		}
+		appendFileDescriptorProtos(set, sd.ParentFile(), seen)
 		mds := sd.Methods()
 		for i := 0; i < mds.Len(); i++ {
 			md := mds.Get(i)
@@ -678,7 +900,21 @@ func (rnr *grpcRunner) resolveAllMethodsUsingReflection(ctx context.Context) err
 			rnr.mds[key] = md
 		}
 	}
-	return nil
+	// Register the descriptors resolved using the server reflection to decode the messages such as the status details.
+	return registerFileDescriptorSet(set)
+}
+
+// appendFileDescriptorProtos appends the file descriptor and its dependencies to the set.
+func appendFileDescriptorProtos(set *descriptorpb.FileDescriptorSet, fd protoreflect.FileDescriptor, seen map[string]struct{}) {
+	if _, ok := seen[fd.Path()]; ok {
+		return
+	}
+	seen[fd.Path()] = struct{}{}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		appendFileDescriptorProtos(set, imports.Get(i).FileDescriptor, seen)
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
 }
 
 func (rnr *grpcRunner) resolveAllMethodsUsingProtos() error {
@@ -715,14 +951,7 @@ func (rnr *grpcRunner) resolveAllMethodsUsingProtos() error {
 		if err != nil {
 			return err
 		}
-		for i := 0; i < d.Services().Len(); i++ {
-			svc := d.Services().Get(i)
-			for j := 0; j < svc.Methods().Len(); j++ {
-				m := svc.Methods().Get(j)
-				key := fmt.Sprintf("%s/%s", svc.FullName(), m.Name())
-				rnr.mds[key] = m
-			}
-		}
+		rnr.addMethods(d)
 	}
 	return nil
 }
@@ -731,6 +960,19 @@ func dcopy(in any) any {
 	return copystructure.Must(copystructure.Copy(in))
 }
 
+func grpcTypeOf(md protoreflect.MethodDescriptor) GRPCType {
+	switch {
+	case !md.IsStreamingServer() && !md.IsStreamingClient():
+		return GRPCUnary
+	case md.IsStreamingServer() && !md.IsStreamingClient():
+		return GRPCServerStreaming
+	case !md.IsStreamingServer() && md.IsStreamingClient():
+		return GRPCClientStreaming
+	default:
+		return GRPCBidiStreaming
+	}
+}
+
 func toEndpoint(mn protoreflect.FullName) string {
 	splitted := strings.Split(string(mn), ".")
 	service := strings.Join(splitted[:len(splitted)-1], ".")
@@ -738,9 +980,13 @@ func toEndpoint(mn protoreflect.FullName) string {
 	return fmt.Sprintf("/%s/%s", service, method)
 }
 
-func registerFiles(fds []*desc.FileDescriptor) (err error) {
+func registerFiles(fds []*desc.FileDescriptor) error {
+	return registerFileDescriptorSet(desc.ToFileDescriptorSet(fds...))
+}
+
+func registerFileDescriptorSet(set *descriptorpb.FileDescriptorSet) (err error) {
 	var rf *protoregistry.Files
-	rf, err = protodesc.NewFiles(desc.ToFileDescriptorSet(fds...))
+	rf, err = protodesc.NewFiles(set)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,76 @@
+package runn
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // register the standard error detail types
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const grpcStoreDetailsKey = "details"
+
+const grpcDetailTypeKey = "@type"
+
+// GRPCStatusDetails returns the details of the status as JSON-like maps.
+// The details are decoded using the registered descriptors ( including the ones resolved from protos, protosets and the server reflection ).
+// The detail of unknown type is returned with the type URL and the base64 encoded value.
+func GRPCStatusDetails(s *status.Status) []map[string]any {
+	details := []map[string]any{}
+	if s == nil {
+		return details
+	}
+	for _, a := range s.Proto().GetDetails() {
+		m, err := decodeGRPCStatusDetail(a)
+		if err != nil {
+			m = map[string]any{
+				grpcDetailTypeKey: a.GetTypeUrl(),
+				"value":           base64.StdEncoding.EncodeToString(a.GetValue()),
+			}
+		}
+		details = append(details, m)
+	}
+	return details
+}
+
+func decodeGRPCStatusDetail(a *anypb.Any) (map[string]any, error) {
+	name := a.GetTypeUrl()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	var msg proto.Message
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name)); err == nil {
+		msg = mt.New().Interface()
+	} else {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, err
+		}
+		md, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("not message: %s", name)
+		}
+		msg = dynamicpb.NewMessage(md)
+	}
+	if err := proto.Unmarshal(a.GetValue(), msg); err != nil {
+		return nil, err
+	}
+	b, err := protojson.MarshalOptions{UseProtoNames: true, UseEnumNumbers: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	m[grpcDetailTypeKey] = a.GetTypeUrl()
+	return m, nil
+}
@@ -0,0 +1,165 @@
+package runn
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v2"
+)
+
+// GRPCMethod is the method resolved by the gRPC runner.
+type GRPCMethod struct {
+	Service  string
+	Method   string
+	Type     GRPCType
+	Request  protoreflect.MessageDescriptor
+	Response protoreflect.MessageDescriptor
+}
+
+// Name returns the name of the method used as the key of gRPC steps (e.g. grpctest.GrpcTestService/Hello).
+func (m *GRPCMethod) Name() string {
+	return strings.Join([]string{m.Service, m.Method}, "/")
+}
+
+// GRPCMethods returns the methods resolved by the gRPC runner using protos, protosets, buf images or the server reflection.
+func (o *operator) GRPCMethods(ctx context.Context, key string) ([]*GRPCMethod, error) {
+	rnr, ok := o.grpcRunners[key]
+	if !ok {
+		return nil, fmt.Errorf("cannot find gRPC runner: %s", key)
+	}
+	if err := rnr.connect(ctx); err != nil {
+		return nil, err
+	}
+	if err := rnr.resolveAllMethods(ctx); err != nil {
+		return nil, err
+	}
+	methods := []*GRPCMethod{}
+	for _, md := range rnr.mds {
+		methods = append(methods, &GRPCMethod{
+			Service:  string(md.Parent().FullName()),
+			Method:   string(md.Name()),
+			Type:     grpcTypeOf(md),
+			Request:  md.Input(),
+			Response: md.Output(),
+		})
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name() < methods[j].Name()
+	})
+	return methods, nil
+}
+
+// AppendGRPCStep appends the step calling the method with the skeleton of the request message.
+// runner is the key of the runner in the runbook, or the address of the gRPC server.
+func (rb *runbook) AppendGRPCStep(runner string, m *GRPCMethod) error {
+	key := runner
+	if _, ok := rb.Runners[runner]; !ok {
+		dsn := runner
+		if !strings.HasPrefix(dsn, "grpc://") && !strings.HasPrefix(dsn, "grpcs://") {
+			dsn = fmt.Sprintf("grpc://%s", dsn)
+		}
+		key = rb.setRunner(dsn)
+	}
+	if rb.useMap {
+		rb.stepKeys = append(rb.stepKeys, fmt.Sprintf("%s%d", m.Method, len(rb.stepKeys)))
+	}
+	hm := yaml.MapSlice{}
+	skel := GRPCMessageSkeleton(m.Request)
+	switch m.Type {
+	case GRPCClientStreaming, GRPCBidiStreaming:
+		hm = append(hm, yaml.MapItem{Key: "messages", Value: []any{skel}})
+	default:
+		hm = append(hm, yaml.MapItem{Key: "message", Value: skel})
+	}
+	step := yaml.MapSlice{
+		{Key: key, Value: yaml.MapSlice{
+			{Key: m.Name(), Value: hm},
+		}},
+	}
+	rb.Steps = append(rb.Steps, step)
+	return nil
+}
+
+// GRPCMessageSkeleton returns the skeleton of the message filled with the default values.
+// The keys are the names of the fields, and the values are in the JSON mapping of Protocol Buffers.
+func GRPCMessageSkeleton(md protoreflect.MessageDescriptor) yaml.MapSlice {
+	return messageSkeleton(md, map[protoreflect.FullName]struct{}{})
+}
+
+func messageSkeleton(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]struct{}) yaml.MapSlice {
+	ms := yaml.MapSlice{}
+	if _, ok := seen[md.FullName()]; ok {
+		// Recursive message
+		return ms
+	}
+	seen[md.FullName()] = struct{}{}
+	defer delete(seen, md.FullName())
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		// Only the first field of the oneof can be set.
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() && od.Fields().Get(0) != fd {
+			continue
+		}
+		ms = append(ms, yaml.MapItem{Key: string(fd.Name()), Value: fieldSkeleton(fd, seen)})
+	}
+	return ms
+}
+
+func fieldSkeleton(fd protoreflect.FieldDescriptor, seen map[protoreflect.FullName]struct{}) any {
+	switch {
+	case fd.IsMap():
+		return yaml.MapSlice{}
+	case fd.IsList():
+		return []any{singularSkeleton(fd, seen)}
+	default:
+		return singularSkeleton(fd, seen)
+	}
+}
+
+func singularSkeleton(fd protoreflect.FieldDescriptor, seen map[protoreflect.FullName]struct{}) any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return false
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return ""
+	case protoreflect.EnumKind:
+		return int(fd.Default().Enum())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return 0.0
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return wellKnownSkeleton(fd.Message(), seen)
+	default:
+		return 0
+	}
+}
+
+// wellKnownSkeleton returns the skeleton of the message, considering the JSON mapping of the well-known types.
+func wellKnownSkeleton(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]struct{}) any {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return "1970-01-01T00:00:00Z"
+	case "google.protobuf.Duration":
+		return "0s"
+	case "google.protobuf.FieldMask", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return ""
+	case "google.protobuf.BoolValue":
+		return false
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue":
+		return 0.0
+	case "google.protobuf.Int32Value", "google.protobuf.Int64Value", "google.protobuf.UInt32Value", "google.protobuf.UInt64Value":
+		return 0
+	case "google.protobuf.Value":
+		return nil
+	case "google.protobuf.ListValue":
+		return []any{}
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return yaml.MapSlice{}
+	case "google.protobuf.Any":
+		return yaml.MapSlice{{Key: grpcDetailTypeKey, Value: ""}}
+	}
+	return messageSkeleton(md, seen)
+}
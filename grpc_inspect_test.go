@@ -0,0 +1,107 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+	"github.com/tenntenn/golden"
+	"gopkg.in/yaml.v2"
+)
+
+func TestGRPCMethods(t *testing.T) {
+	ctx := context.Background()
+	protoset := filepath.Join(testutil.Testdata(), "grpctest.protoset")
+	tests := []struct {
+		name              string
+		disableReflection bool
+		opts              []Option
+	}{
+		{"reflection", false, nil},
+		{"protos", true, []Option{GRPCProtos([]string{filepath.Join(testutil.Testdata(), "grpctest.proto")})}},
+		{"protosets", true, []Option{GRPCProtosets([]string{protoset})}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts := testutil.GRPCServer(t, false, tt.disableReflection)
+			opts := append([]Option{GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false))}, tt.opts...)
+			o, err := New(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(o.Close)
+			methods, err := o.GRPCMethods(ctx, "greq")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]GRPCType{}
+			for _, m := range methods {
+				if m.Service != "grpctest.GrpcTestService" {
+					continue
+				}
+				got[m.Name()] = m.Type
+			}
+			want := map[string]GRPCType{
+				"grpctest.GrpcTestService/Hello":      GRPCUnary,
+				"grpctest.GrpcTestService/ListHello":  GRPCServerStreaming,
+				"grpctest.GrpcTestService/MultiHello": GRPCClientStreaming,
+				"grpctest.GrpcTestService/HelloChat":  GRPCBidiStreaming,
+			}
+			if diff := cmp.Diff(got, want, nil); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		o, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := o.GRPCMethods(ctx, "greq"); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+func TestAppendGRPCStep(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.GRPCServer(t, false, false)
+	o, err := New(GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(o.Close)
+	methods, err := o.GRPCMethods(ctx, "greq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb := NewRunbook("grpc_inspect")
+	for _, m := range methods {
+		if m.Service != "grpctest.GrpcTestService" {
+			continue
+		}
+		if err := rb.AppendGRPCStep("grpc.example.com:443", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := new(bytes.Buffer)
+	enc := yaml.NewEncoder(got)
+	if err := enc.Encode(rb); err != nil {
+		t.Fatal(err)
+	}
+	f := "grpc_inspect.append_step"
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		golden.Update(t, "testdata", f, got)
+		return
+	}
+	if diff := golden.Diff(t, "testdata", f, got); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,538 @@
+package runn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/k1LoW/runn/version"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	GRPCProtocolGRPC    = "grpc"
+	GRPCProtocolGRPCWeb = "grpcweb"
+	GRPCProtocolConnect = "connect"
+)
+
+const (
+	grpcWebContentType       = "application/grpc-web+proto"
+	connectUnaryContentType  = "application/proto"
+	connectStreamContentType = "application/connect+proto"
+	connectProtocolVersion   = "1"
+)
+
+// Flags of the envelope (the length-prefixed message)
+const (
+	envelopeFlagCompressed = 0x01
+	connectFlagEndStream   = 0x02
+	grpcWebFlagTrailer     = 0x80
+)
+
+// grpcDefaultMaxRecvMsgSize is the default max size of the message the client can receive (same as grpc-go).
+const grpcDefaultMaxRecvMsgSize = 1024 * 1024 * 4
+
+// connectCodes is the map of the error codes of Connect protocol.
+var connectCodes = map[string]codes.Code{
+	"canceled":            codes.Canceled,
+	"unknown":             codes.Unknown,
+	"invalid_argument":    codes.InvalidArgument,
+	"deadline_exceeded":   codes.DeadlineExceeded,
+	"not_found":           codes.NotFound,
+	"already_exists":      codes.AlreadyExists,
+	"permission_denied":   codes.PermissionDenied,
+	"resource_exhausted":  codes.ResourceExhausted,
+	"failed_precondition": codes.FailedPrecondition,
+	"aborted":             codes.Aborted,
+	"out_of_range":        codes.OutOfRange,
+	"unimplemented":       codes.Unimplemented,
+	"internal":            codes.Internal,
+	"unavailable":         codes.Unavailable,
+	"data_loss":           codes.DataLoss,
+	"unauthenticated":     codes.Unauthenticated,
+}
+
+// grpcHTTPResult is the result of the call over HTTP.
+type grpcHTTPResult struct {
+	headers  metadata.MD
+	trailers metadata.MD
+	messages [][]byte
+	status   *status.Status
+}
+
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"details"`
+}
+
+type connectEndStream struct {
+	Error    *connectError       `json:"error"`
+	Metadata map[string][]string `json:"metadata"`
+}
+
+func (rnr *grpcRunner) isHTTPProtocol() bool {
+	return rnr.protocol == GRPCProtocolGRPCWeb || rnr.protocol == GRPCProtocolConnect
+}
+
+func (rnr *grpcRunner) maxRecvMsgSizeOverHTTP() int {
+	if rnr.maxRecvMsgSize > 0 {
+		return rnr.maxRecvMsgSize
+	}
+	return grpcDefaultMaxRecvMsgSize
+}
+
+// setHTTPClient sets the HTTP client for gRPC-Web and Connect protocol.
+func (rnr *grpcRunner) setHTTPClient() error {
+	ts := http.DefaultTransport.(*http.Transport).Clone()
+	scheme := "http"
+	if rnr.useTLS() {
+		tlsc, err := rnr.tlsConfig()
+		if err != nil {
+			return err
+		}
+		ts.TLSClientConfig = tlsc
+		scheme = "https"
+	}
+	host := rnr.target
+	if rnr.isUnix() {
+		d, err := newDialer("", nil, strings.TrimPrefix(rnr.target, "unix://"))
+		if err != nil {
+			return err
+		}
+		d.setTransport(ts)
+		host = "localhost"
+	} else if rnr.dialer != nil {
+		rnr.dialer.setTransport(ts)
+	}
+	rnr.httpClient = &http.Client{Transport: ts}
+	rnr.httpBaseURL = fmt.Sprintf("%s://%s", scheme, host)
+	return nil
+}
+
+// invokeOverHTTP invokes the method using gRPC-Web or Connect protocol.
+// Bidirectional streaming RPC and client streaming RPC of gRPC-Web are not supported because they require full-duplex HTTP/2.
+func (rnr *grpcRunner) invokeOverHTTP(ctx context.Context, md protoreflect.MethodDescriptor, r *grpcRequest) error {
+	typ := grpcTypeOf(md)
+	switch {
+	case typ == GRPCBidiStreaming:
+		return fmt.Errorf("bidirectional streaming RPC is not supported by %s protocol", rnr.protocol)
+	case typ == GRPCClientStreaming && rnr.protocol == GRPCProtocolGRPCWeb:
+		return fmt.Errorf("client streaming RPC is not supported by %s protocol", rnr.protocol)
+	case typ == GRPCUnary && len(r.messages) != 1:
+		return errors.New("unary RPC message should be 1")
+	case typ == GRPCServerStreaming && len(r.messages) != 1:
+		return errors.New("server streaming RPC message should be 1")
+	}
+	rnr.operator.capturers.captureGRPCStart(rnr.name, typ, r.service, r.method)
+	defer rnr.operator.capturers.captureGRPCEnd(rnr.name, typ, r.service, r.method)
+
+	if r.timeout > 0 {
+		cctx, cancel := context.WithTimeout(ctx, r.timeout)
+		ctx = cctx
+		defer cancel()
+	}
+
+	rnr.operator.capturers.captureGRPCRequestHeaders(r.headers)
+
+	unaryConnect := rnr.protocol == GRPCProtocolConnect && typ == GRPCUnary
+	body := new(bytes.Buffer)
+	for _, m := range r.messages {
+		if m.op != GRPCOpMessage {
+			return fmt.Errorf("invalid op: %v", m.op)
+		}
+		req := dynamicpb.NewMessage(md.Input())
+		if err := rnr.setMessage(req, m.params); err != nil {
+			return err
+		}
+		b, err := proto.Marshal(req)
+		if err != nil {
+			return err
+		}
+		if rnr.maxSendMsgSize > 0 && len(b) > rnr.maxSendMsgSize {
+			return fmt.Errorf("trying to send message larger than max (%d vs. %d)", len(b), rnr.maxSendMsgSize)
+		}
+		if unaryConnect {
+			body.Write(b)
+			continue
+		}
+		writeEnvelope(body, 0, b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rnr.httpBaseURL+toEndpoint(md.FullName()), body)
+	if err != nil {
+		return err
+	}
+	for k, vs := range r.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if rnr.authority != "" {
+		req.Host = rnr.authority
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("runn/%s", version.Version))
+	deadline, hasDeadline := ctx.Deadline()
+	switch {
+	case rnr.protocol == GRPCProtocolGRPCWeb:
+		req.Header.Set("Content-Type", grpcWebContentType)
+		req.Header.Set("X-Grpc-Web", "1")
+		if hasDeadline {
+			req.Header.Set("Grpc-Timeout", fmt.Sprintf("%dm", timeoutMilliseconds(deadline)))
+		}
+	case unaryConnect:
+		req.Header.Set("Content-Type", connectUnaryContentType)
+	default:
+		req.Header.Set("Content-Type", connectStreamContentType)
+	}
+	if rnr.protocol == GRPCProtocolConnect {
+		req.Header.Set("Connect-Protocol-Version", connectProtocolVersion)
+		if hasDeadline {
+			req.Header.Set("Connect-Timeout-Ms", strconv.FormatInt(timeoutMilliseconds(deadline), 10))
+		}
+	}
+
+	var result *grpcHTTPResult
+	res, err := rnr.httpClient.Do(req)
+	if err == nil {
+		defer res.Body.Close()
+		maxSize := rnr.maxRecvMsgSizeOverHTTP()
+		switch {
+		case rnr.protocol == GRPCProtocolGRPCWeb:
+			result, err = readGRPCWebResponse(res, maxSize)
+		case unaryConnect:
+			result, err = readConnectUnaryResponse(res, maxSize)
+		default:
+			result, err = readConnectStreamResponse(res, maxSize)
+		}
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && rnr.recordDeadlineExceeded(status.Error(codes.DeadlineExceeded, ctx.Err().Error())) {
+			return nil
+		}
+		return err
+	}
+
+	stat := result.status
+	d := map[string]any{
+		string(grpcStoreHeaderKey):  result.headers,
+		string(grpcStoreTrailerKey): result.trailers,
+		string(grpcStoreMessageKey): nil,
+		string(grpcStoreDetailsKey): []map[string]any{},
+	}
+	if typ == GRPCUnary {
+		d[grpcStoreStatusKey] = int(stat.Code())
+	} else {
+		d[grpcStoreStatusKey] = int64(stat.Code())
+	}
+
+	rnr.operator.capturers.captureGRPCResponseStatus(stat)
+	rnr.operator.capturers.captureGRPCResponseHeaders(result.headers)
+
+	messages := []map[string]any{}
+	for _, b := range result.messages {
+		res := dynamicpb.NewMessage(md.Output())
+		if err := proto.Unmarshal(b, res); err != nil {
+			return err
+		}
+		msg, err := grpcMessageToMap(res)
+		if err != nil {
+			return err
+		}
+		d[grpcStoreMessageKey] = msg
+
+		rnr.operator.capturers.captureGRPCResponseMessage(msg)
+
+		messages = append(messages, msg)
+	}
+	if stat.Code() != codes.OK {
+		d[grpcStoreMessageKey] = stat.Message()
+		d[grpcStoreDetailsKey] = GRPCStatusDetails(stat)
+	}
+	d[grpcStoreMessagesKey] = messages
+
+	rnr.operator.capturers.captureGRPCResponseTrailers(result.trailers)
+
+	rnr.operator.record(map[string]any{
+		string(grpcStoreResponseKey): d,
+	})
+	return nil
+}
+
+func grpcMessageToMap(m proto.Message) (map[string]any, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true, UseEnumNumbers: true, EmitUnpopulated: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var msg map[string]any
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func timeoutMilliseconds(deadline time.Time) int64 {
+	ms := time.Until(deadline).Milliseconds()
+	if ms < 1 {
+		return 1
+	}
+	return ms
+}
+
+func writeEnvelope(w *bytes.Buffer, flags byte, b []byte) {
+	h := make([]byte, 5)
+	h[0] = flags
+	binary.BigEndian.PutUint32(h[1:], uint32(len(b)))
+	w.Write(h)
+	w.Write(b)
+}
+
+// readEnvelope reads the length-prefixed message. It returns io.EOF when there are no more messages.
+// The message larger than maxSize is rejected before being read.
+func readEnvelope(r io.Reader, maxSize int) (byte, []byte, error) {
+	h := make([]byte, 5)
+	if _, err := io.ReadFull(r, h); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, fmt.Errorf("invalid envelope: %w", err)
+		}
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(h[1:])
+	if uint64(size) > uint64(maxSize) {
+		return 0, nil, fmt.Errorf("received message larger than max (%d vs. %d)", size, maxSize)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil, fmt.Errorf("invalid envelope: %w", err)
+	}
+	if h[0]&envelopeFlagCompressed != 0 {
+		return 0, nil, errors.New("compressed message is not supported")
+	}
+	return h[0], b, nil
+}
+
+func readGRPCWebResponse(res *http.Response, maxSize int) (*grpcHTTPResult, error) {
+	result := &grpcHTTPResult{
+		headers:  toMetadata(res.Header),
+		trailers: metadata.MD{},
+	}
+	hasTrailers := false
+	for {
+		flags, b, err := readEnvelope(res.Body, maxSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if flags&grpcWebFlagTrailer != 0 {
+			t, err := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(b), strings.NewReader("\r\n")))).ReadMIMEHeader()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("invalid trailers: %w", err)
+			}
+			result.trailers = toMetadata(http.Header(t))
+			hasTrailers = true
+			continue
+		}
+		result.messages = append(result.messages, b)
+	}
+	// Trailers-Only response has the status in the headers.
+	md := result.trailers
+	if !hasTrailers {
+		md = result.headers
+	}
+	stat, err := grpcWebStatus(md, res.StatusCode)
+	if err != nil {
+		return nil, err
+	}
+	result.status = stat
+	return result, nil
+}
+
+func grpcWebStatus(md metadata.MD, httpStatus int) (*status.Status, error) {
+	if v := md.Get("grpc-status-details-bin"); len(v) > 0 {
+		b, err := decodeBase64(v[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid grpc-status-details-bin: %w", err)
+		}
+		s := &spb.Status{}
+		if err := proto.Unmarshal(b, s); err != nil {
+			return nil, fmt.Errorf("invalid grpc-status-details-bin: %w", err)
+		}
+		return status.FromProto(s), nil
+	}
+	v := md.Get("grpc-status")
+	if len(v) == 0 {
+		if httpStatus != http.StatusOK {
+			return status.New(codeFromHTTPStatus(httpStatus), http.StatusText(httpStatus)), nil
+		}
+		return status.New(codes.Internal, "missing grpc-status"), nil
+	}
+	c, err := strconv.Atoi(v[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc-status: %s", v[0])
+	}
+	var msg string
+	if m := md.Get("grpc-message"); len(m) > 0 {
+		msg, err = url.PathUnescape(m[0])
+		if err != nil {
+			msg = m[0]
+		}
+	}
+	return status.New(codes.Code(c), msg), nil
+}
+
+func readConnectUnaryResponse(res *http.Response, maxSize int) (*grpcHTTPResult, error) {
+	result := &grpcHTTPResult{
+		headers:  metadata.MD{},
+		trailers: metadata.MD{},
+	}
+	// The trailers of unary RPC are sent as the headers prefixed with Trailer-.
+	for k, vs := range res.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "trailer-") {
+			result.trailers[strings.TrimPrefix(k, "trailer-")] = vs
+			continue
+		}
+		result.headers[k] = vs
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxSize {
+		return nil, fmt.Errorf("received message larger than max (%d)", maxSize)
+	}
+	if res.StatusCode == http.StatusOK {
+		result.messages = [][]byte{b}
+		result.status = status.New(codes.OK, "")
+		return result, nil
+	}
+	e := &connectError{}
+	if err := json.Unmarshal(b, e); err != nil || e.Code == "" {
+		result.status = status.New(codeFromHTTPStatus(res.StatusCode), http.StatusText(res.StatusCode))
+		return result, nil
+	}
+	result.status, err = e.toStatus()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func readConnectStreamResponse(res *http.Response, maxSize int) (*grpcHTTPResult, error) {
+	result := &grpcHTTPResult{
+		headers:  toMetadata(res.Header),
+		trailers: metadata.MD{},
+	}
+	if res.StatusCode != http.StatusOK {
+		result.status = status.New(codeFromHTTPStatus(res.StatusCode), http.StatusText(res.StatusCode))
+		return result, nil
+	}
+	for {
+		flags, b, err := readEnvelope(res.Body, maxSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if flags&connectFlagEndStream == 0 {
+			result.messages = append(result.messages, b)
+			continue
+		}
+		end := &connectEndStream{}
+		if err := json.Unmarshal(b, end); err != nil {
+			return nil, fmt.Errorf("invalid end-stream message: %w", err)
+		}
+		result.trailers = toMetadata(end.Metadata)
+		if end.Error != nil {
+			result.status, err = end.Error.toStatus()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			result.status = status.New(codes.OK, "")
+		}
+		return result, nil
+	}
+	result.status = status.New(codes.Internal, "missing end-stream message")
+	return result, nil
+}
+
+func (e *connectError) toStatus() (*status.Status, error) {
+	c, ok := connectCodes[e.Code]
+	if !ok {
+		c = codes.Unknown
+	}
+	s := &spb.Status{
+		Code:    int32(c),
+		Message: e.Message,
+	}
+	for _, d := range e.Details {
+		b, err := decodeBase64(d.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error detail: %w", err)
+		}
+		s.Details = append(s.Details, &anypb.Any{
+			TypeUrl: "type.googleapis.com/" + d.Type,
+			Value:   b,
+		})
+	}
+	return status.FromProto(s), nil
+}
+
+// decodeBase64 decodes the base64 string with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// codeFromHTTPStatus returns the code from the HTTP status.
+// ref: https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func codeFromHTTPStatus(s int) codes.Code {
+	switch s {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+func toMetadata(h map[string][]string) metadata.MD {
+	md := metadata.MD{}
+	for k, vs := range h {
+		md[strings.ToLower(k)] = vs
+	}
+	return md
+}
@@ -0,0 +1,310 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestGrpcRunnerWithProtocol(t *testing.T) {
+	ctx := context.Background()
+	protoset := filepath.Join(testutil.Testdata(), "grpctest.protoset")
+	ts := httptest.NewServer(newGRPCProtocolHandler(t, protoset))
+	t.Cleanup(ts.Close)
+	target := strings.TrimPrefix(ts.URL, "http://")
+
+	badRequest := []map[string]any{
+		{
+			"@type": "type.googleapis.com/google.rpc.BadRequest",
+			"field_violations": []any{
+				map[string]any{"field": "name", "description": "name is required"},
+			},
+		},
+	}
+	tests := []struct {
+		protocol    string
+		method      string
+		names       []string
+		wantStatus  codes.Code
+		wantMessage any
+		wantMsgs    []string
+		wantDetails []map[string]any
+		wantErr     bool
+	}{
+		{GRPCProtocolConnect, "Hello", []string{"alice"}, codes.OK, "hello, alice", []string{"hello, alice"}, []map[string]any{}, false},
+		{GRPCProtocolConnect, "Hello", []string{""}, codes.InvalidArgument, "name is required", []string{}, badRequest, false},
+		{GRPCProtocolConnect, "ListHello", []string{"alice"}, codes.OK, "hello, alice 2", []string{"hello, alice 1", "hello, alice 2"}, []map[string]any{}, false},
+		{GRPCProtocolConnect, "ListHello", []string{""}, codes.InvalidArgument, "name is required", []string{}, badRequest, false},
+		{GRPCProtocolConnect, "MultiHello", []string{"alice", "bob"}, codes.OK, "hello, alice and bob", []string{"hello, alice and bob"}, []map[string]any{}, false},
+		{GRPCProtocolConnect, "HelloChat", []string{"alice"}, codes.OK, nil, nil, nil, true},
+		{GRPCProtocolGRPCWeb, "Hello", []string{"alice"}, codes.OK, "hello, alice", []string{"hello, alice"}, []map[string]any{}, false},
+		{GRPCProtocolGRPCWeb, "Hello", []string{""}, codes.InvalidArgument, "name is required", []string{}, badRequest, false},
+		{GRPCProtocolGRPCWeb, "ListHello", []string{"alice"}, codes.OK, "hello, alice 2", []string{"hello, alice 1", "hello, alice 2"}, []map[string]any{}, false},
+		{GRPCProtocolGRPCWeb, "MultiHello", []string{"alice", "bob"}, codes.OK, nil, nil, nil, true},
+		{GRPCProtocolGRPCWeb, "HelloChat", []string{"alice"}, codes.OK, nil, nil, nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(fmt.Sprintf("%s %s %v", tt.protocol, tt.method, tt.names), func(t *testing.T) {
+			o, err := New(GrpcRunnerWithOptions("greq", target, TLS(false), GRPCProtocol(tt.protocol)), GRPCProtosets([]string{protoset}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := o.grpcRunners["greq"]
+			t.Cleanup(func() {
+				_ = r.Close()
+			})
+			req := &grpcRequest{
+				service: "grpctest.GrpcTestService",
+				method:  tt.method,
+				headers: metadata.MD{"authentication": []string{"XXXXX"}},
+			}
+			for _, n := range tt.names {
+				req.messages = append(req.messages, &grpcMessage{op: GRPCOpMessage, params: map[string]any{"name": n}})
+			}
+			if err := r.Run(ctx, req); err != nil {
+				if !tt.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("want error")
+			}
+			res, ok := o.store.steps[0]["res"].(map[string]any)
+			if !ok {
+				t.Fatalf("invalid steps res: %v", o.store.steps[0]["res"])
+			}
+			var got codes.Code
+			switch v := res["status"].(type) {
+			case int:
+				got = codes.Code(v)
+			case int64:
+				got = codes.Code(v)
+			}
+			if got != tt.wantStatus {
+				t.Errorf("got %v\nwant %v", got, tt.wantStatus)
+			}
+			{
+				got := res["message"]
+				if m, ok := got.(map[string]any); ok {
+					got = m["message"]
+				}
+				if got != tt.wantMessage {
+					t.Errorf("got %v\nwant %v", got, tt.wantMessage)
+				}
+			}
+			{
+				got := []string{}
+				for _, m := range res["messages"].([]map[string]any) {
+					got = append(got, m["message"].(string))
+				}
+				if diff := cmp.Diff(got, tt.wantMsgs, nil); diff != "" {
+					t.Error(diff)
+				}
+			}
+			if diff := cmp.Diff(res["details"], tt.wantDetails, nil); diff != "" {
+				t.Error(diff)
+			}
+			if got := res["headers"].(metadata.MD).Get("x-server"); len(got) != 1 || got[0] != tt.protocol {
+				t.Errorf("got %v\nwant %v", got, tt.protocol)
+			}
+			if tt.wantStatus == codes.OK {
+				if got := res["trailers"].(metadata.MD).Get("x-trailer"); len(got) != 1 || got[0] != "end" {
+					t.Errorf("got %v\nwant %v", got, "end")
+				}
+			}
+		})
+	}
+}
+
+func TestGrpcRunnerWithProtocolWithoutDescriptors(t *testing.T) {
+	o, err := New(GrpcRunnerWithOptions("greq", "localhost:8080", TLS(false), GRPCProtocol(GRPCProtocolConnect)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := o.grpcRunners["greq"]
+	req := &grpcRequest{
+		service: "grpctest.GrpcTestService",
+		method:  "Hello",
+		headers: metadata.MD{},
+		messages: []*grpcMessage{
+			{op: GRPCOpMessage, params: map[string]any{"name": "alice"}},
+		},
+	}
+	if err := r.Run(context.Background(), req); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestGrpcRunnerWithProtocolMaxSendMsgSize(t *testing.T) {
+	ctx := context.Background()
+	protoset := filepath.Join(testutil.Testdata(), "grpctest.protoset")
+	ts := httptest.NewServer(newGRPCProtocolHandler(t, protoset))
+	t.Cleanup(ts.Close)
+	target := strings.TrimPrefix(ts.URL, "http://")
+	for _, p := range []string{GRPCProtocolConnect, GRPCProtocolGRPCWeb} {
+		o, err := New(GrpcRunnerWithOptions("greq", target, TLS(false), GRPCProtocol(p), GRPCMaxSendMsgSize(4)), GRPCProtosets([]string{protoset}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := o.grpcRunners["greq"]
+		t.Cleanup(func() {
+			_ = r.Close()
+		})
+		req := &grpcRequest{
+			service: "grpctest.GrpcTestService",
+			method:  "Hello",
+			headers: metadata.MD{"authentication": []string{"XXXXX"}},
+			messages: []*grpcMessage{
+				{op: GRPCOpMessage, params: map[string]any{"name": "alice"}},
+			},
+		}
+		if err := r.Run(ctx, req); err == nil {
+			t.Errorf("%s: want error", p)
+		}
+	}
+}
+
+// newGRPCProtocolHandler returns the handler of grpctest.GrpcTestService served by connect-go, which speaks Connect and gRPC-Web protocol.
+func newGRPCProtocolHandler(t *testing.T, protoset string) http.Handler {
+	t.Helper()
+	set, err := readFileDescriptorSet(protoset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := rf.FindDescriptorByName("grpctest.GrpcTestService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := d.(protoreflect.ServiceDescriptor)
+	badRequest, err := connect.NewErrorDetail(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "name", Description: "name is required"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The messages are dynamicpb.Message built from the descriptors of the methods.
+	initializer := connect.WithRequestInitializer(func(spec connect.Spec, m any) error {
+		md, ok := spec.Schema.(protoreflect.MethodDescriptor)
+		if !ok {
+			return fmt.Errorf("invalid schema: %v", spec.Schema)
+		}
+		*m.(*dynamicpb.Message) = *dynamicpb.NewMessage(md.Input())
+		return nil
+	})
+	name := func(m *dynamicpb.Message) string {
+		return m.Get(m.Descriptor().Fields().ByName("name")).String()
+	}
+	reply := func(md protoreflect.MethodDescriptor, message string) *dynamicpb.Message {
+		res := dynamicpb.NewMessage(md.Output())
+		res.Set(md.Output().Fields().ByName("message"), protoreflect.ValueOfString(message))
+		return res
+	}
+	invalid := func(protocol string) error {
+		err := connect.NewError(connect.CodeInvalidArgument, errors.New("name is required"))
+		err.AddDetail(badRequest)
+		err.Meta().Set("X-Server", protocol)
+		return err
+	}
+	authenticate := func(h http.Header) error {
+		if h.Get("Authentication") != "XXXXX" {
+			return connect.NewError(connect.CodeUnauthenticated, errors.New("invalid authentication"))
+		}
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	hello := svc.Methods().ByName("Hello")
+	mux.Handle(toEndpoint(hello.FullName()), connect.NewUnaryHandler(toEndpoint(hello.FullName()), func(ctx context.Context, req *connect.Request[dynamicpb.Message]) (*connect.Response[dynamicpb.Message], error) {
+		p := req.Peer().Protocol
+		if err := authenticate(req.Header()); err != nil {
+			return nil, err
+		}
+		n := name(req.Msg)
+		if n == "" {
+			return nil, invalid(p)
+		}
+		res := connect.NewResponse(reply(hello, fmt.Sprintf("hello, %s", n)))
+		res.Header().Set("X-Server", p)
+		res.Trailer().Set("X-Trailer", "end")
+		return res, nil
+	}, connect.WithSchema(hello), initializer))
+	listHello := svc.Methods().ByName("ListHello")
+	mux.Handle(toEndpoint(listHello.FullName()), connect.NewServerStreamHandler(toEndpoint(listHello.FullName()), func(ctx context.Context, req *connect.Request[dynamicpb.Message], stream *connect.ServerStream[dynamicpb.Message]) error {
+		p := req.Peer().Protocol
+		if err := authenticate(req.Header()); err != nil {
+			return err
+		}
+		stream.ResponseHeader().Set("X-Server", p)
+		n := name(req.Msg)
+		if n == "" {
+			return invalid(p)
+		}
+		stream.ResponseTrailer().Set("X-Trailer", "end")
+		for i := 1; i <= 2; i++ {
+			if err := stream.Send(reply(listHello, fmt.Sprintf("hello, %s %d", n, i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, connect.WithSchema(listHello), initializer))
+	multiHello := svc.Methods().ByName("MultiHello")
+	mux.Handle(toEndpoint(multiHello.FullName()), connect.NewClientStreamHandler(toEndpoint(multiHello.FullName()), func(ctx context.Context, stream *connect.ClientStream[dynamicpb.Message]) (*connect.Response[dynamicpb.Message], error) {
+		p := stream.Peer().Protocol
+		if err := authenticate(stream.RequestHeader()); err != nil {
+			return nil, err
+		}
+		var names []string
+		for stream.Receive() {
+			n := name(stream.Msg())
+			if n == "" {
+				return nil, invalid(p)
+			}
+			names = append(names, n)
+		}
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		res := connect.NewResponse(reply(multiHello, fmt.Sprintf("hello, %s", strings.Join(names, " and "))))
+		res.Header().Set("X-Server", p)
+		res.Trailer().Set("X-Trailer", "end")
+		return res, nil
+	}, connect.WithSchema(multiHello), initializer))
+	return mux
+}
+
+func TestReadEnvelopeMaxSize(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writeEnvelope(buf, 0, []byte("hello"))
+	b := buf.Bytes()
+	if _, got, err := readEnvelope(bytes.NewReader(b), 5); err != nil || string(got) != "hello" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, _, err := readEnvelope(bytes.NewReader(b), 4); err == nil {
+		t.Error("want error")
+	}
+}
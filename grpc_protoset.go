@@ -0,0 +1,80 @@
+package runn
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// resolveAllMethodsUsingProtosets resolves methods using compiled FileDescriptorSet files (protoset / buf image).
+func (rnr *grpcRunner) resolveAllMethodsUsingProtosets() error {
+	for _, p := range append(append([]string{}, rnr.protosets...), rnr.bufImages...) {
+		set, err := readFileDescriptorSet(p)
+		if err != nil {
+			return err
+		}
+		if err := registerFileDescriptorSet(set); err != nil {
+			return fmt.Errorf("failed to register %s: %w", p, err)
+		}
+		rf, err := protodesc.NewFiles(set)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", p, err)
+		}
+		rf.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			rnr.addMethods(fd)
+			return true
+		})
+	}
+	return nil
+}
+
+func (rnr *grpcRunner) addMethods(fd protoreflect.FileDescriptor) {
+	for i := 0; i < fd.Services().Len(); i++ {
+		svc := fd.Services().Get(i)
+		for j := 0; j < svc.Methods().Len(); j++ {
+			m := svc.Methods().Get(j)
+			key := fmt.Sprintf("%s/%s", svc.FullName(), m.Name())
+			rnr.mds[key] = m
+		}
+	}
+}
+
+// readFileDescriptorSet reads the FileDescriptorSet from the file.
+// The buf image in binary or JSON format ( optionally gzipped ) can also be read because it is compatible with FileDescriptorSet.
+func readFileDescriptorSet(p string) (*descriptorpb.FileDescriptorSet, error) {
+	b, err := readFile(p)
+	if err != nil {
+		return nil, err
+	}
+	name := p
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		b, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if strings.HasSuffix(name, ".json") {
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, set); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", p, err)
+		}
+		return set, nil
+	}
+	if err := (proto.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", p, err)
+	}
+	return set, nil
+}
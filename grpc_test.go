@@ -1,8 +1,11 @@
 package runn
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -11,8 +14,17 @@ import (
 	"github.com/k1LoW/grpcstub"
 	"github.com/k1LoW/runn/testutil"
 	"github.com/k1LoW/runn/version"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 func TestGrpcRunner(t *testing.T) {
@@ -450,3 +462,323 @@ func TestGrpcRunnerWithTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestGrpcRunnerWithDefaultTimeout(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.GRPCServer(t, false, false)
+	hello := func(slow bool, timeout time.Duration) *grpcRequest {
+		h := metadata.MD{}
+		if slow {
+			h.Set("slow", "enable")
+		}
+		return &grpcRequest{
+			service: "grpctest.GrpcTestService",
+			method:  "Hello",
+			headers: h,
+			messages: []*grpcMessage{
+				{op: GRPCOpMessage, params: map[string]any{"name": "alice"}},
+			},
+			timeout: timeout,
+		}
+	}
+	tests := []struct {
+		name string
+		req  *grpcRequest
+		want codes.Code
+	}{
+		{"default timeout", hello(true, 0), codes.DeadlineExceeded},
+		{"step timeout overrides default timeout", hello(false, 5*time.Second), codes.OK},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false), GRPCTimeout(1*time.Millisecond)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := o.grpcRunners["greq"]
+			t.Cleanup(func() {
+				_ = r.Close()
+			})
+			if err := r.Run(ctx, tt.req); err != nil {
+				t.Fatal(err)
+			}
+			res, ok := o.store.steps[0]["res"].(map[string]any)
+			if !ok {
+				t.Fatalf("invalid steps res: %v", o.store.steps[0]["res"])
+			}
+			if got := codes.Code(res["status"].(int)); got != tt.want {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrpcRunnerWithConnConfig(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.GRPCServer(t, false, false)
+	req := &grpcRequest{
+		service: "grpctest.GrpcTestService",
+		method:  "Hello",
+		headers: metadata.MD{},
+		messages: []*grpcMessage{
+			{op: GRPCOpMessage, params: map[string]any{"name": "alice"}},
+		},
+	}
+	tests := []struct {
+		name          string
+		opts          []grpcRunnerOption
+		wantStatus    codes.Code
+		wantAuthority string
+	}{
+		{
+			"authority and keepalive",
+			[]grpcRunnerOption{TLS(false), GRPCAuthority("grpc.example.com"), GRPCKeepalive(30*time.Second, 5*time.Second, true)},
+			codes.OK,
+			"grpc.example.com",
+		},
+		{
+			// The stub server does not install the gzip decompressor, so the compressed message is rejected.
+			"compression",
+			[]grpcRunnerOption{TLS(false), GRPCCompression("gzip")},
+			codes.Unimplemented,
+			"",
+		},
+		{
+			"max recv msg size",
+			[]grpcRunnerOption{TLS(false), GRPCMaxRecvMsgSize(1)},
+			codes.ResourceExhausted,
+			ts.Addr(),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Resolve methods using the proto because the settings are also applied to the reflection.
+			o, err := New(GrpcRunnerWithOptions("greq", ts.Addr(), tt.opts...), GRPCProtos([]string{filepath.Join(testutil.Testdata(), "grpctest.proto")}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := o.grpcRunners["greq"]
+			t.Cleanup(func() {
+				_ = r.Close()
+			})
+			if err := r.Run(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			res, ok := o.store.steps[0]["res"].(map[string]any)
+			if !ok {
+				t.Fatalf("invalid steps res: %v", o.store.steps[0]["res"])
+			}
+			if got := codes.Code(res["status"].(int)); got != tt.wantStatus {
+				t.Errorf("got %v\nwant %v", got, tt.wantStatus)
+			}
+			if tt.wantAuthority == "" {
+				return
+			}
+			latest := len(ts.Requests()) - 1
+			if got := ts.Requests()[latest].Headers.Get(":authority"); len(got) == 0 || got[0] != tt.wantAuthority {
+				t.Errorf("got %v\nwant %v", got, tt.wantAuthority)
+			}
+		})
+	}
+}
+
+func TestGrpcRunnerConnConfigInvalid(t *testing.T) {
+	tests := []*grpcRunnerConfig{
+		{Timeout: "invalid"},
+		{Keepalive: &grpcKeepaliveConfig{Time: "x"}},
+		{MaxRecvMsgSize: -1},
+		{Compression: "br"},
+		{Compression: "gzip", Protocol: GRPCProtocolGRPCWeb},
+		{Compression: "gzip", Protocol: GRPCProtocolConnect},
+		{Keepalive: &grpcKeepaliveConfig{Time: "10s"}, Protocol: GRPCProtocolGRPCWeb},
+		{Keepalive: &grpcKeepaliveConfig{Time: "10s"}, Protocol: GRPCProtocolConnect},
+	}
+	for _, tt := range tests {
+		r, err := newGrpcRunner("greq", "localhost:8080")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.setConnConfig(tt); err == nil {
+			t.Errorf("want error: %#v", tt)
+		}
+	}
+}
+
+func TestGrpcRunnerWithProtosets(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.GRPCServer(t, false, true)
+	protoset := filepath.Join(testutil.Testdata(), "grpctest.protoset")
+
+	// buf image in JSON format
+	set, err := readFileDescriptorSet(protoset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := protojson.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bufImage := filepath.Join(t.TempDir(), "image.json.gz")
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bufImage, buf.Bytes(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"protosets", []Option{GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false), Protosets([]string{protoset}))}},
+		{"bufImage", []Option{GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false), BufImage(bufImage))}},
+		{"GRPCProtosets", []Option{GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false)), GRPCProtosets([]string{protoset})}},
+		{"GRPCBufImages", []Option{GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false)), GRPCBufImages([]string{bufImage})}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := o.grpcRunners["greq"]
+			t.Cleanup(func() {
+				_ = r.Close()
+			})
+			req := &grpcRequest{
+				service: "grpctest.GrpcTestService",
+				method:  "Hello",
+				headers: metadata.MD{},
+				messages: []*grpcMessage{
+					{op: GRPCOpMessage, params: map[string]any{"name": "alice"}},
+				},
+			}
+			if err := r.Run(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			res, ok := o.store.steps[0]["res"].(map[string]any)
+			if !ok {
+				t.Fatalf("invalid steps res: %v", o.store.steps[0]["res"])
+			}
+			if got := codes.Code(res["status"].(int)); got != codes.OK {
+				t.Errorf("got %v\nwant %v", got, codes.OK)
+			}
+		})
+	}
+}
+
+func TestReadFileDescriptorSetInvalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string][]byte{
+		"invalid.protoset":  []byte("invalid"),
+		"invalid.json":      []byte("{"),
+		"invalid.binpb.gz":  []byte("invalid"),
+		"notfound.protoset": nil,
+	}
+	for name, b := range tests {
+		p := filepath.Join(dir, name)
+		if b != nil {
+			if err := os.WriteFile(p, b, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := readFileDescriptorSet(p); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestGrpcRunnerWithStatusDetails(t *testing.T) {
+	ctx := context.Background()
+	set, err := readFileDescriptorSet(filepath.Join(testutil.Testdata(), "grpctest.protoset"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rf, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := rf.FindDescriptorByName("grpctest.HelloResponse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+	custom.Set(custom.Descriptor().Fields().ByName("message"), protoreflect.ValueOfString("custom detail"))
+	customAny, err := anypb.New(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badRequest, err := anypb.New(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "name", Description: "name is required"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := status.FromProto(&spb.Status{
+		Code:    int32(codes.InvalidArgument),
+		Message: "invalid name",
+		Details: []*anypb.Any{
+			badRequest,
+			customAny,
+			{TypeUrl: "type.googleapis.com/example.Unknown", Value: []byte("unknown")},
+		},
+	})
+	ts := grpcstub.NewServer(t, filepath.Join(testutil.Testdata(), "grpctest.proto"))
+	t.Cleanup(ts.Close)
+	ts.Method("grpctest.GrpcTestService/Hello").Status(st)
+
+	o, err := New(GrpcRunnerWithOptions("greq", ts.Addr(), TLS(false)), GRPCProtosets([]string{filepath.Join(testutil.Testdata(), "grpctest.protoset")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := o.grpcRunners["greq"]
+	t.Cleanup(func() {
+		_ = r.Close()
+	})
+	req := &grpcRequest{
+		service: "grpctest.GrpcTestService",
+		method:  "Hello",
+		headers: metadata.MD{},
+		messages: []*grpcMessage{
+			{op: GRPCOpMessage, params: map[string]any{"name": ""}},
+		},
+	}
+	if err := r.Run(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	res, ok := o.store.steps[0]["res"].(map[string]any)
+	if !ok {
+		t.Fatalf("invalid steps res: %v", o.store.steps[0]["res"])
+	}
+	want := []map[string]any{
+		{
+			"@type": "type.googleapis.com/google.rpc.BadRequest",
+			"field_violations": []any{
+				map[string]any{"field": "name", "description": "name is required"},
+			},
+		},
+		{
+			"@type":       "type.googleapis.com/grpctest.HelloResponse",
+			"message":     "custom detail",
+			"num":         float64(0),
+			"create_time": nil,
+		},
+		{
+			"@type": "type.googleapis.com/example.Unknown",
+			"value": "dW5rbm93bg==",
+		},
+	}
+	if diff := cmp.Diff(res["details"], want, nil); diff != "" {
+		t.Error(diff)
+	}
+}
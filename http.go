@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net"
 	"net/http"
@@ -19,9 +20,6 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
-
-	"github.com/ajg/form"
-	"github.com/goccy/go-json"
 )
 
 const (
@@ -29,6 +27,17 @@ const (
 	MediaTypeTextPlain                 = "text/plain"
 	MediaTypeApplicationFormUrlencoded = "application/x-www-form-urlencoded"
 	MediaTypeMultipartFormData         = "multipart/form-data"
+	MediaTypeApplicationXML            = "application/xml"
+	MediaTypeTextXML                   = "text/xml"
+	MediaTypeApplicationYAML           = "application/yaml"
+	MediaTypeApplicationXYAML          = "application/x-yaml"
+	MediaTypeTextYAML                  = "text/yaml"
+	MediaTypeApplicationMsgpack        = "application/msgpack"
+	MediaTypeApplicationXMsgpack       = "application/x-msgpack"
+	MediaTypeApplicationOctetStream    = "application/octet-stream"
+	MediaTypeTextEventStream           = "text/event-stream"
+	MediaTypeApplicationXNDJSON        = "application/x-ndjson"
+	MediaTypeApplicationJSONLine       = "application/jsonl"
 )
 
 const (
@@ -40,6 +49,8 @@ const (
 	httpStoreResponseKey = "res"
 )
 
+const defaultHandlerEndpoint = "http://example.com"
+
 var notFollowRedirectFn = func(req *http.Request, via []*http.Request) error {
 	return http.ErrUseLastResponse
 }
@@ -57,28 +68,46 @@ type httpRunner struct {
 	key               []byte
 	skipVerify        bool
 	useCookie         *bool
+	auth              *httpAuth
+	sign              *httpSign
+	dialer            *dialer
 }
 
 type httpRequest struct {
 	path      string
 	method    string
 	headers   map[string]string
+	query     url.Values
 	mediaType string
 	body      any
 	useCookie *bool
+	stream    *httpStream
+	fuzz      *httpFuzz
+	compress  string
 
 	multipartWriter   *multipart.Writer
 	multipartBoundary string
 	// operator.root
 	root string
+	// encoders registered by HTTPRequestEncoder option
+	encoders httpRequestEncoders
 }
 
 func newHTTPRunner(name, endpoint string) (*httpRunner, error) {
+	var socket string
+	if strings.HasPrefix(endpoint, httpUnixScheme+"://") {
+		s, p, err := splitUnixEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		socket = s
+		endpoint = "http://localhost" + p
+	}
 	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
-	return &httpRunner{
+	r := &httpRunner{
 		name:     name,
 		endpoint: u,
 		client: &http.Client{
@@ -86,12 +115,42 @@ func newHTTPRunner(name, endpoint string) (*httpRunner, error) {
 			Timeout:   time.Second * 30,
 		},
 		validator: newNopValidator(),
-	}, nil
+	}
+	if socket != "" {
+		r.dialer, err = newDialer("", nil, socket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// setDialer sets the proxy and the overrides of name resolution.
+func (rnr *httpRunner) setDialer(proxyURL string, resolve map[string]string) error {
+	if proxyURL == "" && len(resolve) == 0 {
+		return nil
+	}
+	var socket string
+	if rnr.dialer != nil {
+		socket = rnr.dialer.socket
+	}
+	d, err := newDialer(proxyURL, resolve, socket)
+	if err != nil {
+		return err
+	}
+	rnr.dialer = d
+	return nil
 }
 
 func newHTTPRunnerWithHandler(name string, h http.Handler) (*httpRunner, error) {
+	// Same as the default target of httptest.NewRequest
+	u, err := url.Parse(defaultHandlerEndpoint)
+	if err != nil {
+		return nil, err
+	}
 	return &httpRunner{
 		name:      name,
+		endpoint:  u,
 		handler:   h,
 		validator: newNopValidator(),
 	}, nil
@@ -100,20 +159,16 @@ func newHTTPRunnerWithHandler(name string, h http.Handler) (*httpRunner, error)
 func (r *httpRequest) validate() error {
 	switch r.method {
 	case http.MethodPost, http.MethodPatch:
+		// The body may be empty (e.g. `body: {application/json: null}`), but the media type is required.
 		if r.mediaType == "" {
 			return fmt.Errorf("%s method requires mediaType", r.method)
 		}
-		if r.body == nil {
-			return fmt.Errorf("%s method requires body", r.method)
-		}
 	}
-	if r.isMultipartFormDataMediaType() {
+	if r.mediaType == "" || r.isMultipartFormDataMediaType() {
 		return nil
 	}
-	switch r.mediaType {
-	case MediaTypeApplicationJSON, MediaTypeTextPlain, MediaTypeApplicationFormUrlencoded, "":
-	default:
-		return fmt.Errorf("unsupported mediaType: %s", r.mediaType)
+	if _, _, err := mime.ParseMediaType(r.mediaType); err != nil {
+		return fmt.Errorf("invalid mediaType: %s: %w", r.mediaType, err)
 	}
 	return nil
 }
@@ -125,32 +180,39 @@ func (r *httpRequest) encodeBody() (io.Reader, error) {
 	if r.isMultipartFormDataMediaType() {
 		return r.encodeMultipart()
 	}
-	switch r.mediaType {
-	case MediaTypeApplicationJSON:
-		b, err := json.Marshal(r.body)
+	if r.isOctetStreamMediaType() {
+		return r.encodeOctetStream()
+	}
+	if fn, ok := r.encoders.lookup(r.mediaType); ok {
+		b, err := fn(r.body)
 		if err != nil {
 			return nil, err
 		}
 		return bytes.NewBuffer(b), nil
-	case MediaTypeApplicationFormUrlencoded:
-		values, ok := r.body.(map[string]any)
-		if !ok {
-			return nil, fmt.Errorf("invalid body: %v", r.body)
-		}
-		buf := new(bytes.Buffer)
-		if err := form.NewEncoder(buf).Encode(values); err != nil {
-			return nil, err
-		}
-		return buf, nil
-	case MediaTypeTextPlain:
-		s, ok := r.body.(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid body: %v", r.body)
-		}
-		return strings.NewReader(s), nil
-	default:
+	}
+	// Other media types are sent as raw string.
+	s, ok := r.body.(string)
+	if !ok {
 		return nil, fmt.Errorf("unsupported mediaType: %s", r.mediaType)
 	}
+	return strings.NewReader(s), nil
+}
+
+func (r httpRequest) isOctetStreamMediaType() bool {
+	return mediaTypeCandidates(r.mediaType)[0] == MediaTypeApplicationOctetStream
+}
+
+// encodeOctetStream reads the body from the file relative to the root of runbook.
+func (r *httpRequest) encodeOctetStream() (io.Reader, error) {
+	p, ok := r.body.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid body: %s requires the path of file: %v", MediaTypeApplicationOctetStream, r.body)
+	}
+	b, err := readFile(fp(p, r.root))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
 }
 
 func (r httpRequest) isMultipartFormDataMediaType() bool {
@@ -293,17 +355,16 @@ func isLocalhost(domain string) (bool, error) {
 func (rnr *httpRunner) Run(ctx context.Context, r *httpRequest) error {
 	r.multipartBoundary = rnr.multipartBoundary
 	r.root = rnr.operator.root
+	r.encoders = rnr.operator.httpRequestEncoders
 	reqBody, err := r.encodeBody()
 	if err != nil {
 		return err
 	}
 
-	var (
-		req *http.Request
-		res *http.Response
-	)
-	switch {
-	case rnr.client != nil:
+	if rnr.client == nil && rnr.handler == nil {
+		return fmt.Errorf("invalid http runner: %s", rnr.name)
+	}
+	if rnr.client != nil {
 		if rnr.client.Transport == nil {
 			rnr.client.Transport = http.DefaultTransport.(*http.Transport).Clone()
 		}
@@ -316,6 +377,13 @@ func (rnr *httpRunner) Run(ctx context.Context, r *httpRequest) error {
 			}
 			ts.TLSClientConfig.InsecureSkipVerify = rnr.skipVerify
 		}
+		if rnr.dialer != nil {
+			ts, ok := rnr.client.Transport.(*http.Transport)
+			if !ok {
+				return fmt.Errorf("could not set proxy or resolve: interface conversion error: http.RoundTripper is %#v, not *http.Transport", rnr.client.Transport)
+			}
+			rnr.dialer.setTransport(ts)
+		}
 		if rnr.cacert != nil {
 			certpool, err := x509.SystemCertPool()
 			if err != nil {
@@ -343,60 +411,80 @@ func (rnr *httpRunner) Run(ctx context.Context, r *httpRequest) error {
 			}
 			ts.TLSClientConfig.Certificates = []tls.Certificate{cert}
 		}
+	}
 
-		u, err := mergeURL(rnr.endpoint, r.path)
-		if err != nil {
-			return err
-		}
-		req, err = http.NewRequestWithContext(ctx, r.method, u.String(), reqBody)
-		if err != nil {
-			return err
-		}
-		r.setContentTypeHeader(req)
+	if r.fuzz != nil {
+		return rnr.runFuzz(ctx, r)
+	}
 
-		// Override useCookie
-		if r.useCookie == nil && rnr.useCookie != nil && *rnr.useCookie {
-			r.useCookie = rnr.useCookie
-		}
-		r.setCookieHeader(req, rnr.operator.store.cookies)
-		for k, v := range r.headers {
-			req.Header.Set(k, v)
-			if k == "Host" {
-				req.Host = v
-			}
-		}
+	// The request of the streaming response is bounded by stream.duration.
+	sctx := ctx
+	if r.stream != nil && r.stream.duration > 0 {
+		var cancel context.CancelFunc
+		sctx, cancel = context.WithTimeout(ctx, r.stream.duration)
+		defer cancel()
+	}
+
+	req, err := rnr.newRequest(sctx, r, reqBody)
+	if err != nil {
+		return err
+	}
 
-		rnr.operator.capturers.captureHTTPRequest(rnr.name, req)
+	rnr.operator.capturers.captureHTTPRequest(rnr.name, req)
 
-		if err := rnr.validator.ValidateRequest(ctx, req); err != nil {
-			return err
-		}
+	if err := rnr.validator.ValidateRequest(ctx, req); err != nil {
+		return err
+	}
 
-		res, err = rnr.client.Do(req)
+	trace := newHTTPTrace()
+	res, err := rnr.do(req, trace, r.stream)
+	if err != nil {
+		if streamExpired(ctx, sctx, r.stream) {
+			rnr.recordExpiredStream()
+			return nil
+		}
+		return err
+	}
+	if rnr.auth != nil {
+		retry, err := rnr.auth.retryRequest(sctx, req, res, rnr.operator.expandBeforeRecord)
 		if err != nil {
+			_ = res.Body.Close()
 			return err
 		}
-		defer res.Body.Close()
-	case rnr.handler != nil:
-		req = httptest.NewRequest(r.method, r.path, reqBody)
-		if r.mediaType != "" {
-			req.Header.Set("Content-Type", r.mediaType)
-		}
-		for k, v := range r.headers {
-			req.Header.Set(k, v)
+		if retry != nil {
+			rnr.operator.capturers.captureHTTPResponse(rnr.name, res)
+			_ = res.Body.Close()
+			req = retry
+			rnr.operator.capturers.captureHTTPRequest(rnr.name, req)
+			trace = newHTTPTrace()
+			res, err = rnr.do(req, trace, r.stream)
+			if err != nil {
+				if streamExpired(ctx, sctx, r.stream) {
+					rnr.recordExpiredStream()
+					return nil
+				}
+				return err
+			}
 		}
+	}
+	decoding, err := decodeResponseBody(res)
+	if err != nil {
+		_ = res.Body.Close()
+		return err
+	}
+	defer res.Body.Close()
 
-		rnr.operator.capturers.captureHTTPRequest(rnr.name, req)
-
-		if err := rnr.validator.ValidateRequest(ctx, req); err != nil {
+	var (
+		resBody []byte
+		events  []any
+	)
+	if r.stream != nil {
+		events, resBody, err = rnr.readEvents(ctx, res, r.stream)
+		if err != nil {
 			return err
 		}
-		w := httptest.NewRecorder()
-		rnr.handler.ServeHTTP(w, req)
-		res = w.Result()
-		defer res.Body.Close()
-	default:
-		return fmt.Errorf("invalid http runner: %s", rnr.name)
+		// The events that have been read are captured and validated as the body.
+		res.Body = io.NopCloser(bytes.NewReader(resBody))
 	}
 
 	rnr.operator.capturers.captureHTTPResponse(rnr.name, res)
@@ -409,25 +497,51 @@ func (rnr *httpRunner) Run(ctx context.Context, r *httpRequest) error {
 			return err
 		}
 	}
+	if rnr.operator.openAPI3Coverage != nil {
+		rnr.coverOpenAPI3(req, res)
+	}
 
-	resBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		return err
+	if r.stream == nil {
+		resBody, err = io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+	}
+	trace.done()
+	for _, e := range events {
+		rnr.operator.capturers.captureHTTPResponseEvent(rnr.name, e.(map[string]any))
 	}
+	timing := trace.timing()
+	tlsInfo := newHTTPTLSInfo(res.TLS)
+	rnr.operator.capturers.captureHTTPTiming(rnr.name, timing, tlsInfo)
 
 	d := map[string]any{}
 	d[httpStoreStatusKey] = res.StatusCode
-	if strings.Contains(res.Header.Get("Content-Type"), "json") && len(resBody) > 0 {
-		var b any
-		if err := json.Unmarshal(resBody, &b); err != nil {
+	if r.stream != nil {
+		// The body of the streaming response is recorded as events.
+		d[httpStoreBodyKey] = nil
+		if events == nil {
+			events = []any{}
+		}
+		d[httpStoreEventsKey] = events
+	} else {
+		b, err := rnr.operator.httpResponseDecoders.decode(res.Header.Get("Content-Type"), resBody)
+		if err != nil {
 			return err
 		}
 		d[httpStoreBodyKey] = b
-	} else {
-		d[httpStoreBodyKey] = nil
 	}
 	d[httpStoreRawBodyKey] = string(resBody)
 	d[httpStoreHeaderKey] = res.Header
+	if decoding != nil {
+		decoding.toStore(d)
+	}
+	d[httpStoreTimingKey] = timing.toStore()
+	if tlsInfo != nil {
+		d[httpStoreTLSKey] = tlsInfo.toStore()
+	} else {
+		d[httpStoreTLSKey] = nil
+	}
 
 	cookies := res.Cookies()
 
@@ -448,13 +562,115 @@ func (rnr *httpRunner) Run(ctx context.Context, r *httpRequest) error {
 		d[httpStoreCookieKey] = map[string]*http.Cookie{}
 	}
 
-	rnr.operator.record(map[string]any{
+	v := map[string]any{
 		string(httpStoreResponseKey): d,
-	})
+	}
+	if rnr.auth != nil {
+		if a := rnr.auth.toStore(); a != nil {
+			v[httpStoreAuthKey] = a
+		}
+	}
+	rnr.operator.record(v)
 
 	return nil
 }
 
+// newRequest builds the HTTP request of the step.
+// The request is built in the same way for both the client and the handler.
+func (rnr *httpRunner) newRequest(ctx context.Context, r *httpRequest, body io.Reader) (*http.Request, error) {
+	u, err := mergeURL(rnr.endpoint, r.path)
+	if err != nil {
+		return nil, err
+	}
+	mergeQuery(u, r.query)
+	if r.compress != "" && body != nil {
+		body, err = compressBody(body, r.compress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	r.setContentTypeHeader(req)
+	if r.compress != "" && body != nil {
+		req.Header.Set("Content-Encoding", r.compress)
+	}
+
+	// Override useCookie
+	if r.useCookie == nil && rnr.useCookie != nil && *rnr.useCookie {
+		r.useCookie = rnr.useCookie
+	}
+	r.setCookieHeader(req, rnr.operator.store.cookies)
+	if rnr.auth != nil {
+		if err := rnr.auth.setAuthorization(ctx, req, rnr.client, rnr.operator.expandBeforeRecord); err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+		if k == "Host" {
+			req.Host = v
+		}
+	}
+	// The request is signed last because the signature covers the headers and the body.
+	if rnr.sign != nil {
+		if err := rnr.sign.sign(req, rnr.operator.expandBeforeRecord); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// do sends the request using the client, or serves the request using the handler.
+// The request of the streaming response with stream.duration is bounded by the duration instead of the timeout of the client.
+func (rnr *httpRunner) do(req *http.Request, trace *httpTrace, s *httpStream) (*http.Response, error) {
+	if rnr.client != nil {
+		client := rnr.client
+		if s != nil && s.duration > 0 && client.Timeout > 0 {
+			c := *client
+			c.Timeout = 0
+			client = &c
+		}
+		// Request the compressed response in the same way as the transport, but decode it by decodeResponseBody
+		// to record the original Content-Encoding and the compressed size.
+		if ts, ok := rnr.client.Transport.(*http.Transport); ok && !ts.DisableCompression &&
+			req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != http.MethodHead {
+			req.Header.Set("Accept-Encoding", HTTPContentEncodingGzip)
+		}
+		return client.Do(trace.withClientTrace(req))
+	}
+	w := httptest.NewRecorder()
+	rnr.handler.ServeHTTP(w, toServerRequest(req))
+	trace.gotFirstByte()
+	res := w.Result()
+	res.Request = req
+	return res, nil
+}
+
+// toServerRequest converts the client request to the incoming server request like httptest.NewRequest.
+func toServerRequest(req *http.Request) *http.Request {
+	sreq := req.Clone(req.Context())
+	sreq.Body = req.Body
+	if sreq.Body == nil {
+		sreq.Body = http.NoBody
+	}
+	if sreq.Host == "" {
+		sreq.Host = req.URL.Host
+	}
+	sreq.RequestURI = req.URL.RequestURI()
+	sreq.RemoteAddr = "192.0.2.1:1234"
+	if req.URL.Scheme == "https" {
+		sreq.TLS = &tls.ConnectionState{
+			Version:           tls.VersionTLS12,
+			HandshakeComplete: true,
+			ServerName:        req.URL.Hostname(),
+		}
+	}
+	return sreq
+}
+
 func mergeURL(u *url.URL, p string) (*url.URL, error) {
 	if !strings.HasPrefix(p, "/") {
 		return nil, fmt.Errorf("invalid path: %s", p)
@@ -478,3 +694,17 @@ func mergeURL(u *url.URL, p string) (*url.URL, error) {
 
 	return m, nil
 }
+
+// mergeQuery adds the query parameters to the query of the URL.
+func mergeQuery(u *url.URL, q url.Values) {
+	if len(q) == 0 {
+		return
+	}
+	m := u.Query()
+	for k, vs := range q {
+		for _, v := range vs {
+			m.Add(k, v)
+		}
+	}
+	u.RawQuery = m.Encode()
+}
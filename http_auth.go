@@ -0,0 +1,382 @@
+package runn
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	HTTPAuthTypeBasic  = "basic"
+	HTTPAuthTypeBearer = "bearer"
+	HTTPAuthTypeDigest = "digest"
+	HTTPAuthTypeOAuth2 = "oauth2"
+)
+
+const (
+	OAuth2GrantTypeClientCredentials = "client_credentials"
+	OAuth2GrantTypePassword          = "password"
+)
+
+const httpStoreAuthKey = "auth"
+
+type httpAuthConfig struct {
+	Type         string   `yaml:"type"`
+	Username     string   `yaml:"username,omitempty"`
+	Password     string   `yaml:"password,omitempty"`
+	Token        string   `yaml:"token,omitempty"`
+	TokenURL     string   `yaml:"tokenURL,omitempty"`
+	ClientID     string   `yaml:"clientID,omitempty"`
+	ClientSecret string   `yaml:"clientSecret,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	GrantType    string   `yaml:"grantType,omitempty"`
+}
+
+// httpAuth sets credentials to HTTP requests.
+// Values of the config are expanded at run time, so `{{ vars.* }}` and `{{ env.* }}` can be used.
+type httpAuth struct {
+	config *httpAuthConfig
+	// cached OAuth2 token
+	token *oauth2.Token
+	// cached Digest challenge
+	challenge *digestChallenge
+	nc        int
+	mu        sync.Mutex
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+func (c *httpAuthConfig) validate() error {
+	switch c.Type {
+	case HTTPAuthTypeBasic, HTTPAuthTypeDigest:
+		if c.Username == "" {
+			return fmt.Errorf("%s auth requires username", c.Type)
+		}
+	case HTTPAuthTypeBearer:
+		if c.Token == "" {
+			return errors.New("bearer auth requires token")
+		}
+	case HTTPAuthTypeOAuth2:
+		if c.TokenURL == "" {
+			return errors.New("oauth2 auth requires tokenURL")
+		}
+		switch c.GrantType {
+		case "", OAuth2GrantTypeClientCredentials:
+		case OAuth2GrantTypePassword:
+			if c.Username == "" {
+				return errors.New("oauth2 auth using password grant requires username")
+			}
+		default:
+			return fmt.Errorf("unsupported grantType of oauth2 auth: %s", c.GrantType)
+		}
+	default:
+		return fmt.Errorf("unsupported auth type: %s", c.Type)
+	}
+	return nil
+}
+
+func newHTTPAuth(c *httpAuthConfig) (*httpAuth, error) {
+	if c == nil {
+		return nil, nil
+	}
+	c.Type = strings.ToLower(c.Type)
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth: %w", err)
+	}
+	return &httpAuth{config: c}, nil
+}
+
+// expandConfig expands values of the config.
+func (a *httpAuth) expandConfig(expand func(any) (any, error)) (*httpAuthConfig, error) {
+	c := *a.config
+	for _, s := range []*string{&c.Username, &c.Password, &c.Token, &c.TokenURL, &c.ClientID, &c.ClientSecret} {
+		if *s == "" {
+			continue
+		}
+		e, err := expand(*s)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := e.(string)
+		if !ok {
+			v = fmt.Sprintf("%v", e)
+		}
+		*s = v
+	}
+	return &c, nil
+}
+
+// setAuthorization sets Authorization header to the request.
+// client is used for fetching OAuth2 token.
+func (a *httpAuth) setAuthorization(ctx context.Context, req *http.Request, client *http.Client, expand func(any) (any, error)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c, err := a.expandConfig(expand)
+	if err != nil {
+		return err
+	}
+	switch c.Type {
+	case HTTPAuthTypeBasic:
+		req.SetBasicAuth(c.Username, c.Password)
+	case HTTPAuthTypeBearer:
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	case HTTPAuthTypeDigest:
+		if a.challenge == nil {
+			// Wait for the challenge from the server
+			return nil
+		}
+		h, err := a.digestAuthorization(c, req)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", h)
+	case HTTPAuthTypeOAuth2:
+		tok, err := a.oauth2Token(ctx, c, client)
+		if err != nil {
+			return fmt.Errorf("failed to get oauth2 token: %w", err)
+		}
+		tok.SetAuthHeader(req)
+	}
+	return nil
+}
+
+func (a *httpAuth) oauth2Token(ctx context.Context, c *httpAuthConfig, client *http.Client) (*oauth2.Token, error) {
+	if a.token.Valid() {
+		return a.token, nil
+	}
+	if client != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	var (
+		tok *oauth2.Token
+		err error
+	)
+	switch c.GrantType {
+	case OAuth2GrantTypePassword:
+		conf := &oauth2.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: c.TokenURL},
+			Scopes:       c.Scopes,
+		}
+		if a.token != nil && a.token.RefreshToken != "" {
+			// Refresh token
+			tok, err = conf.TokenSource(ctx, a.token).Token()
+			if err == nil {
+				break
+			}
+		}
+		tok, err = conf.PasswordCredentialsToken(ctx, c.Username, c.Password)
+	default:
+		conf := &clientcredentials.Config{
+			ClientID:     c.ClientID,
+			ClientSecret: c.ClientSecret,
+			TokenURL:     c.TokenURL,
+			Scopes:       c.Scopes,
+		}
+		tok, err = conf.Token(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.token = tok
+	return tok, nil
+}
+
+// retryRequest returns the request to retry with credentials if the response is a Digest challenge.
+func (a *httpAuth) retryRequest(ctx context.Context, req *http.Request, res *http.Response, expand func(any) (any, error)) (*http.Request, error) {
+	if a.config.Type != HTTPAuthTypeDigest || res.StatusCode != http.StatusUnauthorized {
+		return nil, nil
+	}
+	ch, ok := parseDigestChallenge(res.Header.Get("WWW-Authenticate"))
+	if !ok {
+		return nil, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.challenge != nil && a.challenge.nonce == ch.nonce {
+		// Credentials for the current challenge have already been rejected
+		return nil, nil
+	}
+	a.challenge = ch
+	a.nc = 0
+	c, err := a.expandConfig(expand)
+	if err != nil {
+		return nil, err
+	}
+	retry := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, errors.New("failed to retry request for digest auth: request body cannot be re-read")
+		}
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	h, err := a.digestAuthorization(c, retry)
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", h)
+	return retry, nil
+}
+
+func (a *httpAuth) digestAuthorization(c *httpAuthConfig, req *http.Request) (string, error) {
+	ch := a.challenge
+	var h func() hash.Hash
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(ch.algorithm), "-sess")) {
+	case "", "MD5":
+		h = md5.New
+	case "SHA-256":
+		h = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm: %s", ch.algorithm)
+	}
+	hf := func(s string) string {
+		hh := h()
+		_, _ = hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+	a.nc++
+	nc := fmt.Sprintf("%08x", a.nc)
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	cnonce := hex.EncodeToString(b)
+	uri := req.URL.RequestURI()
+	ha1 := hf(fmt.Sprintf("%s:%s:%s", c.Username, ch.realm, c.Password))
+	if strings.HasSuffix(strings.ToLower(ch.algorithm), "-sess") {
+		ha1 = hf(fmt.Sprintf("%s:%s:%s", ha1, ch.nonce, cnonce))
+	}
+	ha2 := hf(fmt.Sprintf("%s:%s", req.Method, uri))
+	var (
+		response string
+		qop      string
+	)
+	for _, q := range strings.Split(ch.qop, ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+	if qop != "" {
+		response = hf(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, ch.nonce, nc, cnonce, qop, ha2))
+	} else {
+		response = hf(fmt.Sprintf("%s:%s:%s", ha1, ch.nonce, ha2))
+	}
+	params := []string{
+		fmt.Sprintf(`username="%s"`, c.Username),
+		fmt.Sprintf(`realm="%s"`, ch.realm),
+		fmt.Sprintf(`nonce="%s"`, ch.nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if ch.algorithm != "" {
+		params = append(params, fmt.Sprintf("algorithm=%s", ch.algorithm))
+	}
+	if qop != "" {
+		params = append(params, fmt.Sprintf("qop=%s", qop), fmt.Sprintf("nc=%s", nc), fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if ch.opaque != "" {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, ch.opaque))
+	}
+	return fmt.Sprintf("Digest %s", strings.Join(params, ", ")), nil
+}
+
+// toStore returns the values of the auth to be recorded.
+func (a *httpAuth) toStore() map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.config.Type != HTTPAuthTypeOAuth2 || a.token == nil {
+		return nil
+	}
+	return map[string]any{
+		"type":         a.config.Type,
+		"accessToken":  a.token.AccessToken,
+		"tokenType":    a.token.Type(),
+		"refreshToken": a.token.RefreshToken,
+		"expiry":       a.token.Expiry,
+	}
+}
+
+func parseDigestChallenge(h string) (*digestChallenge, bool) {
+	const prefix = "digest "
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return nil, false
+	}
+	params := parseAuthParams(h[len(prefix):])
+	ch := &digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: params["algorithm"],
+		qop:       params["qop"],
+	}
+	if ch.nonce == "" {
+		return nil, false
+	}
+	return ch, true
+}
+
+// parseAuthParams parses comma separated auth-params such as `realm="example", qop="auth,auth-int"`.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			break
+		}
+		i := strings.Index(s, "=")
+		if i < 0 {
+			break
+		}
+		k := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " ")
+		var v string
+		if strings.HasPrefix(s, `"`) {
+			s = s[1:]
+			var b strings.Builder
+			for len(s) > 0 {
+				c := s[0]
+				s = s[1:]
+				if c == '\\' && len(s) > 0 {
+					b.WriteByte(s[0])
+					s = s[1:]
+					continue
+				}
+				if c == '"' {
+					break
+				}
+				b.WriteByte(c)
+			}
+			v = b.String()
+		} else {
+			j := strings.Index(s, ",")
+			if j < 0 {
+				j = len(s)
+			}
+			v = strings.TrimSpace(s[:j])
+			s = s[j:]
+		}
+		params[k] = v
+	}
+	return params
+}
@@ -0,0 +1,278 @@
+package runn
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPAuth(t *testing.T) {
+	ctx := context.Background()
+	var gotAuthorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+	tests := []struct {
+		opt  httpRunnerOption
+		want string
+	}{
+		{HTTPBasicAuth("alice", "passw0rd"), "Basic YWxpY2U6cGFzc3cwcmQ="},
+		{HTTPBearerAuth("{{ vars.token }}"), "Bearer t0ken"},
+		{HTTPBearerAuth("{{ env.RUNN_TEST_TOKEN }}"), "Bearer envt0ken"},
+	}
+	t.Setenv("RUNN_TEST_TOKEN", "envt0ken")
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			o, err := New(HTTPRunner("req", ts.URL, ts.Client(), tt.opt), Var("token", "t0ken"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.httpRunners["req"].Run(ctx, &httpRequest{path: "/", method: http.MethodGet}); err != nil {
+				t.Fatal(err)
+			}
+			if gotAuthorization != tt.want {
+				t.Errorf("got %v\nwant %v", gotAuthorization, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPAuthHeaderOverride(t *testing.T) {
+	ctx := context.Background()
+	var gotAuthorization string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	})
+	o, err := New(HTTPRunnerWithHandler("req", h, HTTPBearerAuth("runner")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &httpRequest{path: "/", method: http.MethodGet, headers: map[string]string{"Authorization": "Bearer step"}}
+	if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Bearer step"; gotAuthorization != want {
+		t.Errorf("got %v\nwant %v", gotAuthorization, want)
+	}
+}
+
+func TestHTTPDigestAuth(t *testing.T) {
+	ctx := context.Background()
+	const (
+		realm = "runn"
+		nonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	)
+	var challenged int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get("Authorization")
+		if !strings.HasPrefix(h, "Digest ") {
+			atomic.AddInt64(&challenged, 1)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", qop="auth,auth-int", nonce="%s", opaque="5ccc069c403ebaf9f0171e9517f40e41"`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p := parseAuthParams(strings.TrimPrefix(h, "Digest "))
+		ha1 := md5hex(fmt.Sprintf("%s:%s:%s", p["username"], realm, "passw0rd"))
+		ha2 := md5hex(fmt.Sprintf("%s:%s", r.Method, p["uri"]))
+		want := md5hex(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, nonce, p["nc"], p["cnonce"], p["qop"], ha2))
+		if p["response"] != want || p["uri"] != r.URL.RequestURI() || p["opaque"] != "5ccc069c403ebaf9f0171e9517f40e41" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"passw0rd", http.StatusOK},
+		{"invalid", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			atomic.StoreInt64(&challenged, 0)
+			o, err := New(HTTPRunner("req", ts.URL, ts.Client(), HTTPDigestAuth("alice", tt.password)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				req := &httpRequest{
+					path:      "/users?page=1",
+					method:    http.MethodPost,
+					mediaType: MediaTypeApplicationJSON,
+					body:      map[string]any{"name": "bob"},
+				}
+				if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+					t.Fatal(err)
+				}
+				res := o.store.latest()["res"].(map[string]any)
+				if got := res["status"].(int); got != tt.want {
+					t.Errorf("got %v\nwant %v", got, tt.want)
+				}
+			}
+			// The challenge is cached and reused
+			if got := atomic.LoadInt64(&challenged); got != 1 {
+				t.Errorf("got %v\nwant %v", got, 1)
+			}
+		})
+	}
+}
+
+func TestHTTPOAuth2(t *testing.T) {
+	ctx := context.Background()
+	var tokenRequested int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&tokenRequested, 1)
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var token string
+		switch r.Form.Get("grant_type") {
+		case OAuth2GrantTypeClientCredentials:
+			token = "cc-token"
+		case OAuth2GrantTypePassword:
+			if r.Form.Get("username") != "alice" || r.Form.Get("password") != "passw0rd" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			token = "password-token"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"%s","token_type":"Bearer","expires_in":3600,"scope":"%s"}`, token, r.Form.Get("scope"))
+	})
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer cc-token", "Bearer password-token":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		opt       httpRunnerOption
+		wantToken string
+	}{
+		{HTTPOAuth2ClientCredentials(ts.URL+"/token", "client", "s3cret", "read"), "cc-token"},
+		{HTTPOAuth2Password("{{ vars.tokenURL }}", "client", "s3cret", "alice", "passw0rd"), "password-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantToken, func(t *testing.T) {
+			atomic.StoreInt64(&tokenRequested, 0)
+			o, err := New(HTTPRunner("req", ts.URL, ts.Client(), tt.opt), Var("tokenURL", ts.URL+"/token"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				if err := o.httpRunners["req"].Run(ctx, &httpRequest{path: "/users", method: http.MethodGet}); err != nil {
+					t.Fatal(err)
+				}
+				res := o.store.latest()["res"].(map[string]any)
+				if got := res["status"].(int); got != http.StatusOK {
+					t.Errorf("got %v\nwant %v", got, http.StatusOK)
+				}
+				a, ok := o.store.latest()["auth"].(map[string]any)
+				if !ok {
+					t.Fatalf("invalid auth: %#v", o.store.latest()["auth"])
+				}
+				if got := a["accessToken"]; got != tt.wantToken {
+					t.Errorf("got %v\nwant %v", got, tt.wantToken)
+				}
+			}
+			// The token is cached
+			if got := atomic.LoadInt64(&tokenRequested); got != 1 {
+				t.Errorf("got %v\nwant %v", got, 1)
+			}
+		})
+	}
+}
+
+func TestHTTPAuthConfigInvalid(t *testing.T) {
+	tests := []*httpAuthConfig{
+		{Type: "unknown"},
+		{Type: HTTPAuthTypeBasic},
+		{Type: HTTPAuthTypeBearer},
+		{Type: HTTPAuthTypeOAuth2},
+		{Type: HTTPAuthTypeOAuth2, TokenURL: "http://localhost/token", GrantType: "implicit"},
+		{Type: HTTPAuthTypeOAuth2, TokenURL: "http://localhost/token", GrantType: OAuth2GrantTypePassword},
+	}
+	for _, tt := range tests {
+		if _, err := newHTTPAuth(tt); err == nil {
+			t.Errorf("want error: %#v", tt)
+		}
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(`realm="a, \"b\"", qop="auth,auth-int", algorithm=MD5, nonce="n"`)
+	want := map[string]string{
+		"realm":     `a, "b"`,
+		"qop":       "auth,auth-int",
+		"algorithm": "MD5",
+		"nonce":     "n",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v\nwant %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got %v\nwant %v", got[k], v)
+		}
+	}
+}
+
+func md5hex(s string) string {
+	h := md5.Sum([]byte(s)) //nolint:gosec
+	return hex.EncodeToString(h[:])
+}
+
+func TestParseHTTPRunnerWithAuth(t *testing.T) {
+	bk := newBook()
+	if err := bk.parseRunner("req", map[string]any{
+		"endpoint": "https://example.com",
+		"auth": map[string]any{
+			"type":         "oauth2",
+			"tokenURL":     "https://example.com/token",
+			"clientID":     "{{ env.CLIENT_ID }}",
+			"clientSecret": "{{ env.CLIENT_SECRET }}",
+			"scopes":       []any{"read", "write"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a := bk.httpRunners["req"].auth
+	if a == nil {
+		t.Fatal("auth is not set")
+	}
+	if a.config.Type != HTTPAuthTypeOAuth2 || a.config.ClientID != "{{ env.CLIENT_ID }}" || len(a.config.Scopes) != 2 {
+		t.Errorf("invalid auth config: %#v", a.config)
+	}
+	if err := bk.parseRunner("req", map[string]any{
+		"endpoint": "https://example.com",
+		"auth": map[string]any{
+			"type": "basic",
+		},
+	}); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,203 @@
+package runn
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	HTTPContentEncodingGzip    = "gzip"
+	HTTPContentEncodingDeflate = "deflate"
+	HTTPContentEncodingBrotli  = "br"
+	HTTPContentEncodingZstd    = "zstd"
+)
+
+const (
+	httpStoreContentEncodingKey = "contentEncoding"
+	httpStoreCompressedSizeKey  = "compressedSize"
+)
+
+// httpContentDecoding is the decoding of the response body by Content-Encoding.
+type httpContentDecoding struct {
+	encoding   string
+	compressed *countingReader
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if cerr := b.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func validateContentEncoding(enc string) error {
+	switch enc {
+	case HTTPContentEncodingGzip, HTTPContentEncodingDeflate, HTTPContentEncodingBrotli, HTTPContentEncodingZstd:
+		return nil
+	default:
+		return fmt.Errorf("unsupported content encoding: %s", enc)
+	}
+}
+
+// compressBody compresses the body of the request with the content encoding.
+func compressBody(body io.Reader, enc string) (io.Reader, error) {
+	if body == nil {
+		return nil, nil
+	}
+	buf := new(bytes.Buffer)
+	var w io.WriteCloser
+	switch enc {
+	case HTTPContentEncodingGzip:
+		w = gzip.NewWriter(buf)
+	case HTTPContentEncodingDeflate:
+		w = zlib.NewWriter(buf)
+	case HTTPContentEncodingBrotli:
+		w = brotli.NewWriter(buf)
+	case HTTPContentEncodingZstd:
+		zw, err := zstd.NewWriter(buf)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", enc)
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// newDecodingReader returns the reader that decodes r with the content encoding.
+func newDecodingReader(r io.Reader, enc string) (io.ReadCloser, error) {
+	switch enc {
+	case HTTPContentEncodingGzip:
+		return gzip.NewReader(r)
+	case HTTPContentEncodingDeflate:
+		// "deflate" is the zlib format, but some servers send the raw deflate format.
+		br := bufio.NewReader(r)
+		h, err := br.Peek(2)
+		if err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case HTTPContentEncodingBrotli:
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case HTTPContentEncodingZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", enc)
+	}
+}
+
+// contentEncodings returns the content encodings in the order applied.
+// It returns nil if the content encoding is not specified or contains unsupported one.
+func contentEncodings(h http.Header) []string {
+	var encs []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, e := range strings.Split(v, ",") {
+			e = strings.ToLower(strings.TrimSpace(e))
+			if e == "" || e == "identity" {
+				continue
+			}
+			if err := validateContentEncoding(e); err != nil {
+				return nil
+			}
+			encs = append(encs, e)
+		}
+	}
+	return encs
+}
+
+// decodeBody decodes the body by the content encodings. The decodings are applied in reverse order.
+func decodeBody(body io.ReadCloser, encs []string) (io.ReadCloser, *countingReader, error) {
+	cr := &countingReader{r: body}
+	d := &decodedBody{Reader: cr, closers: []io.Closer{body}}
+	for i := len(encs) - 1; i >= 0; i-- {
+		r, err := newDecodingReader(d.Reader, encs[i])
+		if err != nil {
+			_ = d.Close()
+			return nil, nil, fmt.Errorf("failed to decode body by %s: %w", encs[i], err)
+		}
+		d.Reader = r
+		d.closers = append(d.closers, r)
+	}
+	return d, cr, nil
+}
+
+// decodeResponseBody replaces the body of the response with the body decoded by Content-Encoding.
+// The compressed size is counted while reading the body.
+func decodeResponseBody(res *http.Response) (*httpContentDecoding, error) {
+	encs := contentEncodings(res.Header)
+	if len(encs) == 0 || res.Body == nil || res.Body == http.NoBody {
+		return nil, nil
+	}
+	body, cr, err := decodeBody(res.Body, encs)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = body
+	return &httpContentDecoding{
+		encoding:   strings.Join(encs, ", "),
+		compressed: cr,
+	}, nil
+}
+
+// decodeRequestBody returns the body of the request decoded by Content-Encoding.
+func decodeRequestBody(req *http.Request) ([]byte, error) {
+	b, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	encs := contentEncodings(req.Header)
+	if len(encs) == 0 || len(b) == 0 {
+		return b, nil
+	}
+	body, _, err := decodeBody(io.NopCloser(bytes.NewReader(b)), encs)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (d *httpContentDecoding) toStore(v map[string]any) {
+	v[httpStoreContentEncodingKey] = d.encoding
+	v[httpStoreCompressedSizeKey] = d.compressed.n
+}
@@ -0,0 +1,169 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPRunnerDecodeResponse(t *testing.T) {
+	ctx := context.Background()
+	const body = `{"username":"alice"}`
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := strings.TrimPrefix(r.URL.Path, "/")
+		w.Header().Set("Content-Type", "application/json")
+		if enc == "identity" {
+			_, _ = fmt.Fprint(w, body)
+			return
+		}
+		b, err := compressBody(strings.NewReader(body), enc)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Encoding", enc)
+		_, _ = io.Copy(w, b)
+	})
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	tests := []string{
+		HTTPContentEncodingGzip,
+		HTTPContentEncodingDeflate,
+		HTTPContentEncodingBrotli,
+		HTTPContentEncodingZstd,
+		"identity",
+	}
+	for _, enc := range tests {
+		opts := map[string]Option{
+			"server":  HTTPRunner("req", ts.URL, ts.Client()),
+			"handler": HTTPRunnerWithHandler("req", h),
+		}
+		for k, opt := range opts {
+			opt := opt
+			enc := enc
+			t.Run(fmt.Sprintf("%s with %s", enc, k), func(t *testing.T) {
+				o, err := New(opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req := &httpRequest{path: "/" + enc, method: http.MethodGet, headers: map[string]string{}}
+				if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+					t.Fatal(err)
+				}
+				res := o.store.latest()[httpStoreResponseKey].(map[string]any)
+				if got := res[httpStoreRawBodyKey]; got != body {
+					t.Errorf("got %v\nwant %v", got, body)
+				}
+				if got := res[httpStoreBodyKey].(map[string]any)["username"]; got != "alice" {
+					t.Errorf("got %v\nwant %v", got, "alice")
+				}
+				if enc == "identity" {
+					if _, ok := res[httpStoreContentEncodingKey]; ok {
+						t.Errorf("want no %s: %v", httpStoreContentEncodingKey, res)
+					}
+					return
+				}
+				if got := res[httpStoreContentEncodingKey]; got != enc {
+					t.Errorf("got %v\nwant %v", got, enc)
+				}
+				b, err := compressBody(strings.NewReader(body), enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := res[httpStoreCompressedSizeKey], b.(*bytes.Buffer).Len(); got != want {
+					t.Errorf("got %v\nwant %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestHTTPRunnerCompressRequest(t *testing.T) {
+	ctx := context.Background()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encs := contentEncodings(r.Header)
+		if len(encs) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _, err := decodeBody(r.Body, encs)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+	for _, enc := range []string{HTTPContentEncodingGzip, HTTPContentEncodingDeflate, HTTPContentEncodingBrotli, HTTPContentEncodingZstd} {
+		enc := enc
+		t.Run(enc, func(t *testing.T) {
+			o, err := New(HTTPRunnerWithHandler("req", h))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &httpRequest{
+				path:      "/users",
+				method:    http.MethodPost,
+				mediaType: MediaTypeApplicationJSON,
+				headers:   map[string]string{},
+				body:      map[string]any{"username": "alice"},
+				compress:  enc,
+			}
+			if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			res := o.store.latest()[httpStoreResponseKey].(map[string]any)
+			if got := res[httpStoreStatusKey]; got != http.StatusOK {
+				t.Fatalf("got %v\nwant %v", got, http.StatusOK)
+			}
+			if got, want := strings.TrimSpace(res[httpStoreRawBodyKey].(string)), `{"username":"alice"}`; got != want {
+				t.Errorf("got %v\nwant %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCreateHTTPStepMapSliceWithCompressedBody(t *testing.T) {
+	b, err := compressBody(strings.NewReader(`{"username":"alice"}`), HTTPContentEncodingZstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := b.(*bytes.Buffer).Bytes()
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/users", bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", MediaTypeApplicationJSON)
+	req.Header.Set("Content-Encoding", HTTPContentEncodingZstd)
+	step, err := CreateHTTPStepMapSlice("req", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprintf("%v", step)
+	for _, want := range []string{"{compress zstd}", "map[username:alice]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q does not contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "Content-Encoding") {
+		t.Errorf("%q contains Content-Encoding", got)
+	}
+	// The body of the request is restored.
+	rb, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rb, compressed) {
+		t.Errorf("the compressed body is not restored: %q", rb)
+	}
+}
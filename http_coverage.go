@@ -0,0 +1,211 @@
+package runn
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/goccy/go-json"
+)
+
+const openAPI3DefaultResponse = "default"
+
+// openAPI3Coverage is the coverage of operations of OpenAPI v3 documents exercised by HTTP runners.
+// It is shared by all runbooks to which the OpenAPI3Coverage option is applied.
+type openAPI3Coverage struct {
+	// key: "METHOD path"
+	operations map[string]*openAPI3OperationCoverage
+	mu         sync.Mutex
+}
+
+type openAPI3OperationCoverage struct {
+	method string
+	path   string
+	count  int
+	// key: status of the response in the document ( "200", "4XX", "default" )
+	responses map[string]int
+}
+
+type openAPI3CoverageSimplified struct {
+	Operations        int                                    `json:"operations"`
+	CoveredOperations int                                    `json:"covered_operations"`
+	Responses         int                                    `json:"responses"`
+	CoveredResponses  int                                    `json:"covered_responses"`
+	Results           []*openAPI3OperationCoverageSimplified `json:"results"`
+}
+
+type openAPI3OperationCoverageSimplified struct {
+	Method    string                                `json:"method"`
+	Path      string                                `json:"path"`
+	Covered   bool                                  `json:"covered"`
+	Count     int                                   `json:"count"`
+	Responses []*openAPI3ResponseCoverageSimplified `json:"responses"`
+}
+
+type openAPI3ResponseCoverageSimplified struct {
+	Status  string `json:"status"`
+	Covered bool   `json:"covered"`
+	Count   int    `json:"count"`
+}
+
+// NewOpenAPI3Coverage returns the coverage of operations of OpenAPI v3 documents.
+func NewOpenAPI3Coverage() *openAPI3Coverage {
+	return &openAPI3Coverage{
+		operations: map[string]*openAPI3OperationCoverage{},
+	}
+}
+
+// register registers the operations of the document as the targets of the coverage.
+func (c *openAPI3Coverage) register(doc *openapi3.T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p, item := range doc.Paths {
+		for m, op := range item.Operations() {
+			k := openAPI3OperationKey(m, p)
+			oc, ok := c.operations[k]
+			if !ok {
+				oc = &openAPI3OperationCoverage{
+					method:    strings.ToUpper(m),
+					path:      p,
+					responses: map[string]int{},
+				}
+				c.operations[k] = oc
+			}
+			for s := range op.Responses {
+				s = strings.ToUpper(s)
+				if s == strings.ToUpper(openAPI3DefaultResponse) {
+					s = openAPI3DefaultResponse
+				}
+				if _, ok := oc.responses[s]; !ok {
+					oc.responses[s] = 0
+				}
+			}
+		}
+	}
+}
+
+// cover records that the operation returned the response with the status code.
+func (c *openAPI3Coverage) cover(method, path string, status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	oc, ok := c.operations[openAPI3OperationKey(method, path)]
+	if !ok {
+		return
+	}
+	oc.count++
+	// The response for the status code is selected in the order of the exact code, the range ( such as "4XX" ) and "default".
+	for _, s := range []string{fmt.Sprintf("%d", status), fmt.Sprintf("%dXX", status/100), openAPI3DefaultResponse} {
+		if _, ok := oc.responses[s]; ok {
+			oc.responses[s]++
+			return
+		}
+	}
+}
+
+func (c *openAPI3Coverage) Simplify() openAPI3CoverageSimplified {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := openAPI3CoverageSimplified{
+		Results: []*openAPI3OperationCoverageSimplified{},
+	}
+	for _, oc := range c.operations {
+		so := &openAPI3OperationCoverageSimplified{
+			Method:    oc.method,
+			Path:      oc.path,
+			Covered:   oc.count > 0,
+			Count:     oc.count,
+			Responses: []*openAPI3ResponseCoverageSimplified{},
+		}
+		s.Operations++
+		if so.Covered {
+			s.CoveredOperations++
+		}
+		for st, n := range oc.responses {
+			so.Responses = append(so.Responses, &openAPI3ResponseCoverageSimplified{
+				Status:  st,
+				Covered: n > 0,
+				Count:   n,
+			})
+			s.Responses++
+			if n > 0 {
+				s.CoveredResponses++
+			}
+		}
+		sort.Slice(so.Responses, func(i, j int) bool {
+			return so.Responses[i].Status < so.Responses[j].Status
+		})
+		s.Results = append(s.Results, so)
+	}
+	sort.Slice(s.Results, func(i, j int) bool {
+		if s.Results[i].Path == s.Results[j].Path {
+			return s.Results[i].Method < s.Results[j].Method
+		}
+		return s.Results[i].Path < s.Results[j].Path
+	})
+	return s
+}
+
+// Out outputs the coverage report.
+func (c *openAPI3Coverage) Out(out io.Writer) error {
+	s := c.Simplify()
+	_, _ = fmt.Fprintf(out, "OpenAPI 3 coverage: %d/%d operations (%s), %d/%d responses (%s)\n", s.CoveredOperations, s.Operations, percent(s.CoveredOperations, s.Operations), s.CoveredResponses, s.Responses, percent(s.CoveredResponses, s.Responses))
+	for _, so := range s.Results {
+		_, _ = fmt.Fprintf(out, "  %s %s %s\n", coveredMark(so.Covered), so.Method, so.Path)
+		for _, rs := range so.Responses {
+			_, _ = fmt.Fprintf(out, "      %s %s\n", coveredMark(rs.Covered), rs.Status)
+		}
+	}
+	return nil
+}
+
+// OutJSON outputs the coverage report as JSON.
+func (c *openAPI3Coverage) OutJSON(out io.Writer) error {
+	s := c.Simplify()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(b); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(out, "\n"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// coverOpenAPI3 records the operation of the request to the coverage.
+func (rnr *httpRunner) coverOpenAPI3(req *http.Request, res *http.Response) {
+	v, ok := rnr.validator.(*openApi3Validator)
+	if !ok {
+		return
+	}
+	route, _, err := v.findRoute(req)
+	if err != nil {
+		// The operation is not documented
+		return
+	}
+	rnr.operator.openAPI3Coverage.cover(route.Method, route.Path, res.StatusCode)
+}
+
+func openAPI3OperationKey(method, path string) string {
+	return fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+}
+
+func coveredMark(covered bool) string {
+	if covered {
+		return green("✔")
+	}
+	return red("✘")
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)/float64(total)*100)
+}
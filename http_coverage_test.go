@@ -0,0 +1,105 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+)
+
+func TestOpenAPI3Coverage(t *testing.T) {
+	ts := testutil.HTTPServer(t)
+	t.Setenv("TEST_HTTP_END_POINT", ts.URL)
+	cov := NewOpenAPI3Coverage()
+	ops, err := Load("testdata/book/http.yml", OpenAPI3Coverage(cov))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ops.RunN(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s := cov.Simplify()
+	if s.Operations != 8 || s.CoveredOperations != 8 {
+		t.Errorf("got %d/%d operations\nwant 8/8", s.CoveredOperations, s.Operations)
+	}
+	if s.Responses != 12 || s.CoveredResponses != 9 {
+		t.Errorf("got %d/%d responses\nwant 9/12", s.CoveredResponses, s.Responses)
+	}
+	out := new(bytes.Buffer)
+	if err := cov.Out(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "GET /users/{id}") {
+		t.Errorf("invalid report: %s", out.String())
+	}
+}
+
+func TestOpenAPI3CoverageCover(t *testing.T) {
+	spec := `
+openapi: 3.0.3
+info:
+  title: test
+  version: 0.0.1
+paths:
+  /users:
+    get:
+      responses:
+        '200':
+          description: OK
+        4xx:
+          description: Client error
+        default:
+          description: Error
+    post:
+      responses:
+        '201':
+          description: Created
+`
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cov := NewOpenAPI3Coverage()
+	cov.register(doc)
+	cov.register(doc)
+	cov.cover("GET", "/users", 200)
+	cov.cover("get", "/users", 404)
+	cov.cover("GET", "/users", 503)
+	cov.cover("GET", "/users/{id}", 200)
+
+	want := openAPI3CoverageSimplified{
+		Operations:        2,
+		CoveredOperations: 1,
+		Responses:         4,
+		CoveredResponses:  3,
+		Results: []*openAPI3OperationCoverageSimplified{
+			{
+				Method:  "GET",
+				Path:    "/users",
+				Covered: true,
+				Count:   3,
+				Responses: []*openAPI3ResponseCoverageSimplified{
+					{Status: "200", Covered: true, Count: 1},
+					{Status: "4XX", Covered: true, Count: 1},
+					{Status: "default", Covered: true, Count: 1},
+				},
+			},
+			{
+				Method:  "POST",
+				Path:    "/users",
+				Covered: false,
+				Count:   0,
+				Responses: []*openAPI3ResponseCoverageSimplified{
+					{Status: "201", Covered: false, Count: 0},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(cov.Simplify(), want, nil); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,206 @@
+package runn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	xmlAttrPrefix = "@"
+	xmlTextKey    = "#text"
+)
+
+// HTTPResponseDecodeFunc decodes the body of an HTTP response into the value stored in `res.body`.
+type HTTPResponseDecodeFunc func(b []byte) (any, error)
+
+type httpResponseDecoders map[string]HTTPResponseDecodeFunc
+
+var builtinHTTPResponseDecoders = httpResponseDecoders{
+	MediaTypeApplicationJSON:           decodeJSONBody,
+	MediaTypeApplicationXML:            decodeXMLBody,
+	MediaTypeTextXML:                   decodeXMLBody,
+	MediaTypeApplicationYAML:           decodeYAMLBody,
+	MediaTypeApplicationXYAML:          decodeYAMLBody,
+	MediaTypeTextYAML:                  decodeYAMLBody,
+	MediaTypeApplicationFormUrlencoded: decodeFormBody,
+	MediaTypeApplicationXMsgpack:       decodeMsgPackBody,
+	MediaTypeApplicationMsgpack:        decodeMsgPackBody,
+}
+
+// lookup returns the decoder for the Content-Type of the response.
+// Decoders registered by the user take precedence over the built-in decoders.
+func (ds httpResponseDecoders) lookup(contentType string) (HTTPResponseDecodeFunc, bool) {
+	if contentType == "" {
+		return nil, false
+	}
+	candidates := mediaTypeCandidates(contentType)
+	for _, c := range candidates {
+		if fn, ok := ds[c]; ok {
+			return fn, true
+		}
+		if fn, ok := builtinHTTPResponseDecoders[c]; ok {
+			return fn, true
+		}
+	}
+	// For compatibility, all media types containing "json" are decoded as JSON.
+	if strings.Contains(candidates[0], "json") {
+		return decodeJSONBody, true
+	}
+	return nil, false
+}
+
+// mediaTypeCandidates returns the media type without parameters and the media type for its structured syntax suffix (RFC 6839).
+// e.g. application/vnd.api+json; charset=utf-8 -> application/vnd.api+json, application/json.
+func mediaTypeCandidates(contentType string) []string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	candidates := []string{mt}
+	if i := strings.LastIndex(mt, "+"); i >= 0 {
+		candidates = append(candidates, "application/"+mt[i+1:])
+	}
+	return candidates
+}
+
+func (ds httpResponseDecoders) decode(contentType string, b []byte) (any, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	fn, ok := ds.lookup(contentType)
+	if !ok {
+		return nil, nil
+	}
+	return fn(b)
+}
+
+func decodeJSONBody(b []byte) (any, error) {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func decodeYAMLBody(b []byte) (any, error) {
+	var v any
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return normalizeBody(v)
+}
+
+func decodeFormBody(b []byte) (any, error) {
+	q, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil, err
+	}
+	v := map[string]any{}
+	for k, vs := range q {
+		if len(vs) == 1 {
+			v[k] = vs[0]
+			continue
+		}
+		s := make([]any, 0, len(vs))
+		for _, vv := range vs {
+			s = append(s, vv)
+		}
+		v[k] = s
+	}
+	return v, nil
+}
+
+func decodeMsgPackBody(b []byte) (any, error) {
+	var v any
+	if err := msgpack.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return normalizeBody(v)
+}
+
+// decodeXMLBody decodes XML into map.
+// Attributes are stored with the `@` prefix, and the text of an element that has attributes or child elements is stored with the `#text` key.
+// Repeated elements are stored as a list.
+func decodeXMLBody(b []byte) (any, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		t, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("invalid xml: no root element")
+			}
+			return nil, err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			v, err := decodeXMLElement(d, se)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{se.Name.Local: v}, nil
+		}
+	}
+}
+
+func decodeXMLElement(d *xml.Decoder, se xml.StartElement) (any, error) {
+	m := map[string]any{}
+	for _, a := range se.Attr {
+		m[xmlAttrPrefix+a.Name.Local] = a.Value
+	}
+	var text strings.Builder
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tt := t.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(d, tt)
+			if err != nil {
+				return nil, err
+			}
+			k := tt.Name.Local
+			switch cur := m[k].(type) {
+			case nil:
+				m[k] = v
+			case []any:
+				m[k] = append(cur, v)
+			default:
+				m[k] = []any{cur, v}
+			}
+		case xml.CharData:
+			text.Write(tt)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m[xmlTextKey] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// normalizeBody converts values to match behavior with json.Unmarshal.
+func normalizeBody(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize body: %w", err)
+	}
+	var n any
+	if err := json.Unmarshal(b, &n); err != nil {
+		return nil, fmt.Errorf("failed to normalize body: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,139 @@
+package runn
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestHTTPResponseDecoders(t *testing.T) {
+	mp, err := msgpack.Marshal(map[string]any{"id": 1, "name": "alice", "tags": []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		contentType string
+		in          []byte
+		want        any
+	}{
+		{"application/json", []byte(`{"id":1}`), map[string]any{"id": float64(1)}},
+		{"application/json; charset=utf-8", []byte(`[1,2]`), []any{float64(1), float64(2)}},
+		{"application/vnd.api+json", []byte(`{"id":1}`), map[string]any{"id": float64(1)}},
+		{"application/x-ndjson-ish", []byte(`{"id":1}`), map[string]any{"id": float64(1)}},
+		{"text/plain", []byte(`hello`), nil},
+		{"", []byte(`hello`), nil},
+		{"application/xml", []byte{}, nil},
+		{
+			"application/xml",
+			[]byte(`<?xml version="1.0"?><user id="1"><name>alice</name><tag>a</tag><tag>b</tag></user>`),
+			map[string]any{"user": map[string]any{"@id": "1", "name": "alice", "tag": []any{"a", "b"}}},
+		},
+		{
+			"text/xml; charset=utf-8",
+			[]byte(`<msg lang="en">hello</msg>`),
+			map[string]any{"msg": map[string]any{"@lang": "en", "#text": "hello"}},
+		},
+		{
+			"application/atom+xml",
+			[]byte(`<feed><title>runn</title></feed>`),
+			map[string]any{"feed": map[string]any{"title": "runn"}},
+		},
+		{
+			"application/yaml",
+			[]byte("id: 1\nname: alice\ntags:\n  - a\n  - b\n"),
+			map[string]any{"id": float64(1), "name": "alice", "tags": []any{"a", "b"}},
+		},
+		{
+			"application/x-www-form-urlencoded",
+			[]byte(`name=alice&tag=a&tag=b`),
+			map[string]any{"name": "alice", "tag": []any{"a", "b"}},
+		},
+		{
+			"application/x-msgpack",
+			mp,
+			map[string]any{"id": float64(1), "name": "alice", "tags": []any{"a", "b"}},
+		},
+	}
+	ds := httpResponseDecoders{}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, err := ds.decode(tt.contentType, tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, tt.want, nil); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestHTTPResponseDecodersInvalid(t *testing.T) {
+	tests := []struct {
+		contentType string
+		in          []byte
+	}{
+		{"application/json", []byte(`{"id":`)},
+		{"application/xml", []byte(`<user><name>alice</user>`)},
+		{"application/xml", []byte(`   `)},
+	}
+	ds := httpResponseDecoders{}
+	for _, tt := range tests {
+		if _, err := ds.decode(tt.contentType, tt.in); err == nil {
+			t.Errorf("want error: %s %s", tt.contentType, string(tt.in))
+		}
+	}
+}
+
+func TestHTTPResponseDecoderOption(t *testing.T) {
+	ctx := context.Background()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/csv":
+			w.Header().Set("Content-Type", "text/csv")
+			_, _ = w.Write([]byte("alice,bob"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"alice"}`))
+		}
+	})
+	o, err := New(
+		HTTPRunnerWithHandler("req", h),
+		HTTPResponseDecoder("text/csv", func(b []byte) (any, error) {
+			var l []any
+			for _, s := range strings.Split(string(b), ",") {
+				l = append(l, s)
+			}
+			return l, nil
+		}),
+		HTTPResponseDecoder("application/json", func(b []byte) (any, error) {
+			return "overridden", nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"/csv", []any{"alice", "bob"}},
+		{"/json", "overridden"},
+	}
+	for _, tt := range tests {
+		if err := o.httpRunners["req"].Run(ctx, &httpRequest{path: tt.path, method: http.MethodGet}); err != nil {
+			t.Fatal(err)
+		}
+		res, ok := o.store.latest()["res"].(map[string]any)
+		if !ok {
+			t.Fatalf("invalid res: %#v", o.store.latest()["res"])
+		}
+		if diff := cmp.Diff(res["body"], tt.want, nil); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package runn
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ajg/form"
+	"github.com/goccy/go-json"
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cast"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// HTTPRequestEncodeFunc encodes the value of `body:` into the body of an HTTP request.
+type HTTPRequestEncodeFunc func(body any) ([]byte, error)
+
+type httpRequestEncoders map[string]HTTPRequestEncodeFunc
+
+var builtinHTTPRequestEncoders = httpRequestEncoders{
+	MediaTypeApplicationJSON:           encodeJSONBody,
+	MediaTypeApplicationXML:            encodeXMLBody,
+	MediaTypeTextXML:                   encodeXMLBody,
+	MediaTypeApplicationYAML:           encodeYAMLBody,
+	MediaTypeApplicationXYAML:          encodeYAMLBody,
+	MediaTypeTextYAML:                  encodeYAMLBody,
+	MediaTypeApplicationFormUrlencoded: encodeFormBody,
+	MediaTypeApplicationXMsgpack:       encodeMsgPackBody,
+	MediaTypeApplicationMsgpack:        encodeMsgPackBody,
+	MediaTypeTextPlain:                 encodeTextBody,
+}
+
+// lookup returns the encoder for the media type of the request.
+// Encoders registered by the user take precedence over the built-in encoders.
+func (es httpRequestEncoders) lookup(mediaType string) (HTTPRequestEncodeFunc, bool) {
+	if mediaType == "" {
+		return nil, false
+	}
+	for _, c := range mediaTypeCandidates(mediaType) {
+		if fn, ok := es[c]; ok {
+			return fn, true
+		}
+		if fn, ok := builtinHTTPRequestEncoders[c]; ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+func encodeJSONBody(body any) ([]byte, error) {
+	return json.Marshal(body)
+}
+
+func encodeYAMLBody(body any) ([]byte, error) {
+	if s, ok := body.(string); ok {
+		return []byte(s), nil
+	}
+	return yaml.Marshal(body)
+}
+
+func encodeFormBody(body any) ([]byte, error) {
+	values, ok := body.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid body: %v", body)
+	}
+	buf := new(bytes.Buffer)
+	if err := form.NewEncoder(buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeTextBody(body any) ([]byte, error) {
+	s, ok := body.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid body: %v", body)
+	}
+	return []byte(s), nil
+}
+
+func encodeMsgPackBody(body any) ([]byte, error) {
+	return msgpack.Marshal(body)
+}
+
+// encodeXMLBody encodes map into XML. It is the reverse of decodeXMLBody.
+// A string body is used verbatim.
+func encodeXMLBody(body any) ([]byte, error) {
+	switch v := body.(type) {
+	case string:
+		return []byte(v), nil
+	case map[string]any:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("invalid body: XML requires exactly one root element: %v", body)
+		}
+		buf := new(bytes.Buffer)
+		buf.WriteString(xml.Header)
+		e := xml.NewEncoder(buf)
+		for k, vv := range v {
+			if err := encodeXMLElement(e, k, vv); err != nil {
+				return nil, err
+			}
+		}
+		if err := e.Flush(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("invalid body: %v", body)
+	}
+}
+
+func encodeXMLElement(e *xml.Encoder, name string, v any) error {
+	if l, ok := v.([]any); ok {
+		// Repeated elements
+		for _, vv := range l {
+			if err := encodeXMLElement(e, name, vv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	se := xml.StartElement{Name: xml.Name{Local: name}}
+	var (
+		text     string
+		children []string
+	)
+	switch vv := v.(type) {
+	case nil:
+	case map[string]any:
+		for k, vvv := range vv {
+			switch {
+			case strings.HasPrefix(k, xmlAttrPrefix):
+				se.Attr = append(se.Attr, xml.Attr{Name: xml.Name{Local: strings.TrimPrefix(k, xmlAttrPrefix)}, Value: cast.ToString(vvv)})
+			case k == xmlTextKey:
+				text = cast.ToString(vvv)
+			default:
+				children = append(children, k)
+			}
+		}
+		sort.Slice(se.Attr, func(i, j int) bool {
+			return se.Attr[i].Name.Local < se.Attr[j].Name.Local
+		})
+		sort.Strings(children)
+	default:
+		text = cast.ToString(vv)
+	}
+	if err := e.EncodeToken(se); err != nil {
+		return err
+	}
+	if text != "" {
+		if err := e.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	if m, ok := v.(map[string]any); ok {
+		for _, k := range children {
+			if err := encodeXMLElement(e, k, m[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(se.End())
+}
@@ -0,0 +1,195 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestRequestBodyWithEncoders(t *testing.T) {
+	dummy, err := os.ReadFile("testdata/dummy.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in        string
+		mediaType string
+		want      string
+	}{
+		{
+			`
+user:
+  '@id': 1
+  name: alice
+  tag:
+    - a
+    - b`,
+			MediaTypeApplicationXML,
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<user id="1"><name>alice</name><tag>a</tag><tag>b</tag></user>`,
+		},
+		{
+			`
+msg:
+  '@lang': en
+  '#text': 'a < b'`,
+			"text/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<msg lang="en">a &lt; b</msg>`,
+		},
+		{
+			`'<Envelope><Body/></Envelope>'`,
+			"application/soap+xml",
+			`<Envelope><Body/></Envelope>`,
+		},
+		{
+			`
+data:
+  one: ichi`,
+			"application/vnd.api+json",
+			`{"data":{"one":"ichi"}}`,
+		},
+		{
+			`
+data:
+  one: ichi`,
+			"application/json; charset=utf-8",
+			`{"data":{"one":"ichi"}}`,
+		},
+		{
+			`'BEGIN:VCALENDAR'`,
+			"text/calendar",
+			`BEGIN:VCALENDAR`,
+		},
+		{
+			`testdata/dummy.png`,
+			MediaTypeApplicationOctetStream,
+			string(dummy),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			var b any
+			if err := yaml.Unmarshal([]byte(tt.in), &b); err != nil {
+				t.Fatal(err)
+			}
+			r := &httpRequest{
+				mediaType: tt.mediaType,
+				body:      b,
+			}
+			body, err := r.encodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := new(bytes.Buffer)
+			if _, err := io.Copy(buf, body); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if got != tt.want {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestBodyWithEncodersRoundTrip(t *testing.T) {
+	in := map[string]any{
+		"user": map[string]any{
+			"@id":  "1",
+			"name": "alice",
+			"tag":  []any{"a", "b"},
+		},
+	}
+	tests := []struct {
+		mediaType string
+	}{
+		{MediaTypeApplicationXML},
+		{MediaTypeApplicationXMsgpack},
+		{MediaTypeApplicationYAML},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			r := &httpRequest{
+				mediaType: tt.mediaType,
+				body:      in,
+			}
+			body, err := r.encodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := httpResponseDecoders{}.decode(tt.mediaType, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, any(in), nil); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRequestBodyWithEncodersInvalid(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		body      any
+	}{
+		{MediaTypeApplicationXML, map[string]any{"a": "1", "b": "2"}},
+		{MediaTypeApplicationXML, []any{"a"}},
+		{MediaTypeApplicationOctetStream, "testdata/not_exist.bin"},
+		{MediaTypeApplicationOctetStream, map[string]any{"a": "1"}},
+		{"application/vnd.unknown", map[string]any{"a": "1"}},
+	}
+	for _, tt := range tests {
+		r := &httpRequest{
+			mediaType: tt.mediaType,
+			body:      tt.body,
+		}
+		if _, err := r.encodeBody(); err == nil {
+			t.Errorf("want error: %s %v", tt.mediaType, tt.body)
+		}
+	}
+}
+
+func TestHTTPRequestEncoderOption(t *testing.T) {
+	ctx := context.Background()
+	var got []byte
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	})
+	o, err := New(
+		HTTPRunnerWithHandler("req", h),
+		HTTPRequestEncoder("application/x-custom", func(body any) ([]byte, error) {
+			return msgpack.Marshal(body)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &httpRequest{
+		path:      "/",
+		method:    http.MethodPost,
+		mediaType: "application/x-custom",
+		body:      map[string]any{"name": "alice"},
+	}
+	if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	want, err := msgpack.Marshal(map[string]any{"name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
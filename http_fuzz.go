@@ -0,0 +1,428 @@
+package runn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/spf13/cast"
+)
+
+const (
+	httpStoreFuzzKey = "fuzz"
+
+	httpFuzzSeedKey     = "seed"
+	httpFuzzTotalKey    = "total"
+	httpFuzzResultsKey  = "results"
+	httpFuzzFailuresKey = "failures"
+	httpFuzzVariantKey  = "variant"
+	httpFuzzStatusKey   = "status"
+	httpFuzzErrorKey    = "error"
+)
+
+// Length of the oversized string.
+const httpFuzzOversizedLength = 65536
+
+// httpFuzz is the condition for sending request variants derived from the OpenAPI v3 document of the runner.
+type httpFuzz struct {
+	// seed of the random source to reproduce variants
+	seed int64
+	// max number of variants ( 0 is all )
+	count int
+}
+
+// httpFuzzVariant is the request variant that is expected to be rejected by the server.
+type httpFuzzVariant struct {
+	name  string
+	body  any
+	query url.Values
+}
+
+func parseHTTPFuzz(v any) (*httpFuzz, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid fuzz: %v", v)
+	}
+	f := &httpFuzz{}
+	for k, vv := range m {
+		switch k {
+		case "seed":
+			s, err := cast.ToInt64E(vv)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fuzz.seed: %v", vv)
+			}
+			f.seed = s
+		case "count":
+			c, err := cast.ToIntE(vv)
+			if err != nil || c < 0 {
+				return nil, fmt.Errorf("invalid fuzz.count: %v", vv)
+			}
+			f.count = c
+		default:
+			return nil, fmt.Errorf("invalid fuzz: unknown key: %s", k)
+		}
+	}
+	return f, nil
+}
+
+// runFuzz sends the request variants derived from the schemas of the operation,
+// and reports responses that are 5xx or that violate the documented responses.
+func (rnr *httpRunner) runFuzz(ctx context.Context, r *httpRequest) error {
+	v, ok := rnr.validator.(*openApi3Validator)
+	if !ok {
+		return errors.New("fuzz requires openapi3 of the runner")
+	}
+	req, err := rnr.newRequest(ctx, r, nil)
+	if err != nil {
+		return err
+	}
+	route, _, err := v.findRoute(req)
+	if err != nil {
+		return err
+	}
+	// The query in the path is merged so that variants can replace or remove it.
+	br := *r
+	p, rq, _ := strings.Cut(r.path, "?")
+	q, err := url.ParseQuery(rq)
+	if err != nil {
+		return err
+	}
+	for k, vs := range r.query {
+		q[k] = append(q[k], vs...)
+	}
+	br.path = p
+	br.query = q
+	variants := br.fuzz.variants(&br, route)
+
+	results := []any{}
+	failures := []any{}
+	var errs []string
+	for _, vr := range variants {
+		status, err := rnr.sendFuzzVariant(ctx, &br, vr, v)
+		res := map[string]any{
+			httpFuzzVariantKey: vr.name,
+			httpFuzzStatusKey:  status,
+			httpFuzzErrorKey:   nil,
+		}
+		if err != nil {
+			res[httpFuzzErrorKey] = err.Error()
+			failures = append(failures, res)
+			errs = append(errs, fmt.Sprintf("%s: %s", vr.name, err))
+		}
+		results = append(results, res)
+		rnr.operator.Debugf("fuzz %s %s: %s: %d\n", route.Method, route.Path, vr.name, status)
+	}
+
+	rnr.operator.record(map[string]any{
+		httpStoreFuzzKey: map[string]any{
+			httpFuzzSeedKey:     r.fuzz.seed,
+			httpFuzzTotalKey:    len(variants),
+			httpFuzzResultsKey:  results,
+			httpFuzzFailuresKey: failures,
+		},
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("fuzz found %d failures of %d variants (seed: %d):\n%s", len(errs), len(variants), r.fuzz.seed, strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// sendFuzzVariant sends the request variant without validating the request, and checks the response.
+func (rnr *httpRunner) sendFuzzVariant(ctx context.Context, r *httpRequest, vr *httpFuzzVariant, v *openApi3Validator) (int, error) {
+	vreq := *r
+	vreq.body = vr.body
+	vreq.query = vr.query
+	vreq.multipartWriter = nil
+	body, err := vreq.encodeBody()
+	if err != nil {
+		return 0, err
+	}
+	req, err := rnr.newRequest(ctx, &vreq, body)
+	if err != nil {
+		return 0, err
+	}
+	res, err := rnr.do(req, newHTTPTrace(), nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := decodeResponseBody(res); err != nil {
+		_ = res.Body.Close()
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 500 {
+		return res.StatusCode, fmt.Errorf("server error: %s", res.Status)
+	}
+	if err := v.ValidateResponse(ctx, req, res); err != nil {
+		var target *UnsupportedError
+		if !errors.As(err, &target) {
+			return res.StatusCode, fmt.Errorf("undocumented response: %s", res.Status)
+		}
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return res.StatusCode, nil
+}
+
+// variants returns the request variants of the operation.
+// If count is specified, the variants are sampled using the seed.
+func (f *httpFuzz) variants(r *httpRequest, route *routers.Route) []*httpFuzzVariant {
+	rnd := rand.New(rand.NewSource(f.seed)) //nolint:gosec
+	var variants []*httpFuzzVariant
+	if s := fuzzBodySchema(route.Operation, r.mediaType); s != nil {
+		base, ok := r.body.(map[string]any)
+		if !ok {
+			base, _ = openAPI3SchemaExample(s, 0).(map[string]any)
+		}
+		if r.mediaType == MediaTypeApplicationJSON {
+			variants = append(variants, &httpFuzzVariant{name: "wrong type: body", body: []any{}, query: r.query})
+		}
+		variants = append(variants, fuzzBodyVariants(s, base, r.query, rnd)...)
+	}
+	variants = append(variants, fuzzQueryVariants(route, r.body, r.query, rnd)...)
+	if f.count > 0 && len(variants) > f.count {
+		rnd.Shuffle(len(variants), func(i, j int) {
+			variants[i], variants[j] = variants[j], variants[i]
+		})
+		variants = variants[:f.count]
+	}
+	return variants
+}
+
+func fuzzBodySchema(op *openapi3.Operation, mediaType string) *openapi3.Schema {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	mt := op.RequestBody.Value.Content.Get(mediaType)
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+	return mt.Schema.Value
+}
+
+func fuzzBodyVariants(s *openapi3.Schema, base map[string]any, query url.Values, rnd *rand.Rand) []*httpFuzzVariant {
+	var variants []*httpFuzzVariant
+	props, required := fuzzProperties(s)
+	if props == nil {
+		return nil
+	}
+	add := func(name string, fn func(b map[string]any)) {
+		b := copyMap(base)
+		fn(b)
+		variants = append(variants, &httpFuzzVariant{name: name, body: b, query: query})
+	}
+	for _, k := range sortedKeys(props) {
+		k := k
+		ps := props[k]
+		if contains(required, k) {
+			add(fmt.Sprintf("missing required field: body.%s", k), func(b map[string]any) {
+				delete(b, k)
+			})
+		}
+		if wv, ok := fuzzWrongTypeValue(ps, rnd); ok {
+			add(fmt.Sprintf("wrong type: body.%s", k), func(b map[string]any) {
+				b[k] = wv
+			})
+		}
+		for _, bv := range fuzzBoundaryValues(ps, base[k], rnd) {
+			bv := bv
+			add(fmt.Sprintf("%s: body.%s", bv.name, k), func(b map[string]any) {
+				b[k] = bv.value
+			})
+		}
+	}
+	return variants
+}
+
+func fuzzQueryVariants(route *routers.Route, body any, query url.Values, rnd *rand.Rand) []*httpFuzzVariant {
+	var variants []*httpFuzzVariant
+	params := openapi3.Parameters{}
+	params = append(params, route.Operation.Parameters...)
+	if route.PathItem != nil {
+		for _, p := range route.PathItem.Parameters {
+			if p.Value != nil && route.Operation.Parameters.GetByInAndName(p.Value.In, p.Value.Name) == nil {
+				params = append(params, p)
+			}
+		}
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].Value.Name < params[j].Value.Name
+	})
+	add := func(name string, fn func(q url.Values)) {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = append([]string{}, v...)
+		}
+		fn(q)
+		variants = append(variants, &httpFuzzVariant{name: name, body: body, query: q})
+	}
+	for _, p := range params {
+		p := p.Value
+		if p == nil || p.In != openapi3.ParameterInQuery || p.Schema == nil || p.Schema.Value == nil {
+			continue
+		}
+		if p.Required {
+			add(fmt.Sprintf("missing required query: %s", p.Name), func(q url.Values) {
+				q.Del(p.Name)
+			})
+		}
+		switch p.Schema.Value.Type {
+		case openapi3.TypeInteger, openapi3.TypeNumber, openapi3.TypeBoolean:
+			add(fmt.Sprintf("wrong type: query.%s", p.Name), func(q url.Values) {
+				q.Set(p.Name, randomString(rnd, 8))
+			})
+		}
+		for _, bv := range fuzzBoundaryValues(p.Schema.Value, query.Get(p.Name), rnd) {
+			bv := bv
+			add(fmt.Sprintf("%s: query.%s", bv.name, p.Name), func(q url.Values) {
+				q.Set(p.Name, fmt.Sprintf("%v", bv.value))
+			})
+		}
+	}
+	return variants
+}
+
+// fuzzProperties returns the properties and the required properties of the object schema including allOf.
+func fuzzProperties(s *openapi3.Schema) (map[string]*openapi3.Schema, []string) {
+	if s == nil {
+		return nil, nil
+	}
+	props := map[string]*openapi3.Schema{}
+	required := append([]string{}, s.Required...)
+	for _, ss := range s.AllOf {
+		p, r := fuzzProperties(ss.Value)
+		for k, v := range p {
+			props[k] = v
+		}
+		required = append(required, r...)
+	}
+	for k, p := range s.Properties {
+		if p.Value != nil && !p.Value.ReadOnly {
+			props[k] = p.Value
+		}
+	}
+	if len(props) == 0 && s.Type != openapi3.TypeObject {
+		return nil, nil
+	}
+	return props, required
+}
+
+// fuzzWrongTypeValue returns the value of the type different from the schema.
+func fuzzWrongTypeValue(s *openapi3.Schema, rnd *rand.Rand) (any, bool) {
+	var candidates []any
+	switch s.Type {
+	case openapi3.TypeString:
+		candidates = []any{rnd.Intn(math.MaxInt16), true, []any{}, map[string]any{}}
+	case openapi3.TypeInteger:
+		candidates = []any{randomString(rnd, 8), true, 0.5}
+	case openapi3.TypeNumber:
+		candidates = []any{randomString(rnd, 8), true}
+	case openapi3.TypeBoolean:
+		candidates = []any{randomString(rnd, 8), rnd.Intn(math.MaxInt16)}
+	case openapi3.TypeObject:
+		candidates = []any{randomString(rnd, 8), []any{}}
+	case openapi3.TypeArray:
+		candidates = []any{randomString(rnd, 8), map[string]any{}}
+	default:
+		return nil, false
+	}
+	return candidates[rnd.Intn(len(candidates))], true
+}
+
+type fuzzBoundaryValue struct {
+	name  string
+	value any
+}
+
+// fuzzBoundaryValues returns the values just outside the constraints of the schema and the oversized string.
+func fuzzBoundaryValues(s *openapi3.Schema, current any, rnd *rand.Rand) []*fuzzBoundaryValue {
+	var values []*fuzzBoundaryValue
+	switch s.Type {
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		if s.Min != nil {
+			values = append(values, &fuzzBoundaryValue{"boundary value", fuzzNumber(s, *s.Min-1)})
+		}
+		if s.Max != nil {
+			values = append(values, &fuzzBoundaryValue{"boundary value", fuzzNumber(s, *s.Max+1)})
+		}
+		if s.Max == nil && s.Format == "int32" {
+			values = append(values, &fuzzBoundaryValue{"boundary value", int64(math.MaxInt32) + 1})
+		}
+	case openapi3.TypeString:
+		if len(s.Enum) > 0 {
+			values = append(values, &fuzzBoundaryValue{"not in enum", randomString(rnd, 8)})
+		}
+		if s.MinLength > 0 {
+			values = append(values, &fuzzBoundaryValue{"boundary value", randomString(rnd, int(s.MinLength)-1)})
+		}
+		if s.MaxLength != nil && *s.MaxLength < httpFuzzOversizedLength {
+			values = append(values, &fuzzBoundaryValue{"boundary value", randomString(rnd, int(*s.MaxLength)+1)})
+		}
+		if s.MaxLength == nil || *s.MaxLength < httpFuzzOversizedLength {
+			values = append(values, &fuzzBoundaryValue{"oversized string", randomString(rnd, httpFuzzOversizedLength)})
+		}
+	case openapi3.TypeArray:
+		item := current
+		if vs, ok := current.([]any); ok && len(vs) > 0 {
+			item = vs[0]
+		} else if s.Items != nil {
+			item = openAPI3SchemaExample(s.Items.Value, 0)
+		}
+		if s.MinItems > 0 {
+			values = append(values, &fuzzBoundaryValue{"boundary value", repeatValue(item, int(s.MinItems)-1)})
+		}
+		if s.MaxItems != nil {
+			values = append(values, &fuzzBoundaryValue{"boundary value", repeatValue(item, int(*s.MaxItems)+1)})
+		}
+	}
+	return values
+}
+
+func fuzzNumber(s *openapi3.Schema, v float64) any {
+	if s.Type == openapi3.TypeInteger {
+		return int64(v)
+	}
+	return v
+}
+
+func randomString(rnd *rand.Rand, n int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+func repeatValue(v any, n int) []any {
+	vs := make([]any, n)
+	for i := range vs {
+		vs[i] = v
+	}
+	return vs
+}
+
+func copyMap(m map[string]any) map[string]any {
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,222 @@
+package runn
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/google/go-cmp/cmp"
+)
+
+const fuzzTestSpec = `
+openapi: 3.0.3
+info:
+  title: fuzz
+  version: 0.0.1
+paths:
+  /users:
+    post:
+      parameters:
+        - in: query
+          name: limit
+          required: true
+          schema:
+            type: integer
+            maximum: 100
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                username:
+                  type: string
+                  maxLength: 8
+                age:
+                  type: integer
+                  minimum: 0
+                  maximum: 150
+              required:
+                - username
+      responses:
+        '201':
+          description: Created
+        '400':
+          description: Bad Request
+`
+
+func TestHTTPRunnerFuzz(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		wantErr      bool
+		wantFailures int
+	}{
+		{
+			"robust server",
+			func(w http.ResponseWriter, r *http.Request) {
+				var b map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				l, err := strconv.Atoi(r.URL.Query().Get("limit"))
+				if err != nil || l > 100 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				u, ok := b["username"].(string)
+				if !ok || len(u) > 8 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if a, ok := b["age"]; ok {
+					af, ok := a.(float64)
+					if !ok || af < 0 || af > 150 {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+				}
+				w.WriteHeader(http.StatusCreated)
+			},
+			false,
+			0,
+		},
+		{
+			"server error and undocumented response",
+			func(w http.ResponseWriter, r *http.Request) {
+				var b map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if _, ok := b["username"]; !ok {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			},
+			true,
+			2,
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			t.Cleanup(ts.Close)
+			o, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := newHTTPRunner("req", ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.operator = o
+			c := &httpRunnerConfig{}
+			if err := OpenApi3FromData([]byte(fuzzTestSpec))(c); err != nil {
+				t.Fatal(err)
+			}
+			v, err := newHttpValidator(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.validator = v
+			req := &httpRequest{
+				path:      "/users?limit=10",
+				method:    http.MethodPost,
+				mediaType: MediaTypeApplicationJSON,
+				headers:   map[string]string{},
+				body:      map[string]any{"username": "alice", "age": 20},
+				fuzz:      &httpFuzz{seed: 1},
+			}
+			if err := r.Run(ctx, req); (err != nil) != tt.wantErr {
+				t.Errorf("got %v\nwantErr %v", err, tt.wantErr)
+			}
+			f, ok := o.store.steps[0][httpStoreFuzzKey].(map[string]any)
+			if !ok {
+				t.Fatalf("invalid steps fuzz: %v", o.store.steps[0])
+			}
+			if got := len(f[httpFuzzFailuresKey].([]any)); got != tt.wantFailures {
+				t.Errorf("got %v failures\nwant %v: %v", got, tt.wantFailures, f[httpFuzzFailuresKey])
+			}
+		})
+	}
+}
+
+func TestHTTPRunnerFuzzWithoutOpenAPI3(t *testing.T) {
+	o, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newHTTPRunner("req", "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.operator = o
+	req := &httpRequest{
+		path:    "/users",
+		method:  http.MethodPost,
+		headers: map[string]string{},
+		fuzz:    &httpFuzz{},
+	}
+	if err := r.Run(context.Background(), req); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestHTTPFuzzVariants(t *testing.T) {
+	c := &httpRunnerConfig{}
+	if err := OpenApi3FromData([]byte(fuzzTestSpec))(c); err != nil {
+		t.Fatal(err)
+	}
+	item := c.openApi3Doc.Paths["/users"]
+	route := &routers.Route{
+		Path:      "/users",
+		Method:    http.MethodPost,
+		Operation: item.Post,
+		PathItem:  item,
+	}
+	r := &httpRequest{
+		mediaType: MediaTypeApplicationJSON,
+		body:      map[string]any{"username": "alice"},
+	}
+	names := func(f *httpFuzz) []string {
+		var n []string
+		for _, v := range f.variants(r, route) {
+			n = append(n, v.name)
+		}
+		return n
+	}
+
+	want := []string{
+		"wrong type: body",
+		"wrong type: body.age",
+		"boundary value: body.age",
+		"boundary value: body.age",
+		"missing required field: body.username",
+		"wrong type: body.username",
+		"boundary value: body.username",
+		"oversized string: body.username",
+		"missing required query: limit",
+		"wrong type: query.limit",
+		"boundary value: query.limit",
+	}
+	if diff := cmp.Diff(names(&httpFuzz{seed: 1}), want, nil); diff != "" {
+		t.Error(diff)
+	}
+
+	// Sampling is reproducible with the same seed
+	got := names(&httpFuzz{seed: 3, count: 4})
+	if len(got) != 4 {
+		t.Errorf("got %v\nwant 4 variants", got)
+	}
+	if diff := cmp.Diff(names(&httpFuzz{seed: 3, count: 4}), got, nil); diff != "" {
+		t.Error(diff)
+	}
+}
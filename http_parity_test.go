@@ -0,0 +1,157 @@
+package runn
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+)
+
+// TestHTTPRunnerParity tests that requests to http.Handler are the same as requests to the server.
+func TestHTTPRunnerParity(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cret", Path: "/api"})
+		echoRequest(w, r)
+	})
+	mux.HandleFunc("/digest", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="runn", qop="auth", nonce="n0nce"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(b)
+	})
+	mux.HandleFunc("/", echoRequest)
+
+	tests := []struct {
+		name     string
+		endpoint string
+		opts     []httpRunnerOption
+		reqs     []*httpRequest
+	}{
+		{
+			"base path and query merging",
+			"/api?lang=ja",
+			nil,
+			[]*httpRequest{
+				{path: "/users?page=2&lang=en", method: http.MethodGet},
+			},
+		},
+		{
+			"query",
+			"/api?lang=ja",
+			nil,
+			[]*httpRequest{
+				{path: "/users?page=2", method: http.MethodGet, query: url.Values{"q": []string{"a b"}, "lang": []string{"en"}}},
+			},
+		},
+		{
+			"multipart",
+			"/",
+			[]httpRunnerOption{MultipartBoundary(testutil.MultipartBoundary)},
+			[]*httpRequest{
+				{path: "/upload", method: http.MethodPost, mediaType: MediaTypeMultipartFormData, body: map[string]any{"name": "bob"}},
+			},
+		},
+		{
+			"form",
+			"/",
+			nil,
+			[]*httpRequest{
+				{path: "/users", method: http.MethodPost, mediaType: MediaTypeApplicationFormUrlencoded, body: map[string]any{"name": "bob"}},
+			},
+		},
+		{
+			"cookies",
+			"/",
+			[]httpRunnerOption{UseCookie(true)},
+			[]*httpRequest{
+				{path: "/api/login", method: http.MethodGet},
+				{path: "/api/users", method: http.MethodGet},
+				{path: "/other", method: http.MethodGet},
+			},
+		},
+		{
+			"host header",
+			"/",
+			nil,
+			[]*httpRequest{
+				{path: "/users", method: http.MethodGet, headers: map[string]string{"Host": "api.example.com", "X-Custom": "value"}},
+			},
+		},
+		{
+			"digest auth with body",
+			"/",
+			[]httpRunnerOption{HTTPDigestAuth("alice", "passw0rd")},
+			[]*httpRequest{
+				{path: "/digest", method: http.MethodPost, mediaType: MediaTypeApplicationJSON, body: map[string]any{"name": "bob"}},
+			},
+		},
+		{
+			"auth",
+			"/",
+			[]httpRunnerOption{HTTPBearerAuth("t0ken")},
+			[]*httpRequest{
+				{path: "/users", method: http.MethodGet},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(mux)
+			t.Cleanup(ts.Close)
+			endpoint := ts.URL + tt.endpoint
+			sopts := append([]httpRunnerOption{}, tt.opts...)
+			hopts := append([]httpRunnerOption{HandlerEndpoint(endpoint)}, tt.opts...)
+			got := runParityRequests(t, HTTPRunnerWithHandler("req", mux, hopts...), tt.reqs)
+			want := runParityRequests(t, HTTPRunner("req", endpoint, ts.Client(), sopts...), tt.reqs)
+			if diff := cmp.Diff(got, want, nil); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func runParityRequests(t *testing.T, opt Option, reqs []*httpRequest) []any {
+	t.Helper()
+	ctx := context.Background()
+	o, err := New(opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []any
+	for _, tmpl := range reqs {
+		req := *tmpl
+		if err := o.httpRunners["req"].Run(ctx, &req); err != nil {
+			t.Fatal(err)
+		}
+		res := o.store.latest()["res"].(map[string]any)
+		got = append(got, res["status"], res["body"], res["cookies"].(map[string]*http.Cookie)["session"])
+	}
+	return got
+}
+
+func echoRequest(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"method":        r.Method,
+		"uri":           r.RequestURI,
+		"host":          r.Host,
+		"contentType":   r.Header.Get("Content-Type"),
+		"contentLength": r.ContentLength,
+		"cookie":        r.Header.Get("Cookie"),
+		"authorization": r.Header.Get("Authorization"),
+		"custom":        r.Header.Get("X-Custom"),
+		"body":          string(b),
+	})
+}
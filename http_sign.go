@@ -0,0 +1,431 @@
+package runn
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HTTPSignTypeSigV4 = "sigv4"
+	HTTPSignTypeHMAC  = "hmac"
+)
+
+const (
+	HMACComponentMethod    = "method"
+	HMACComponentPath      = "path"
+	HMACComponentQuery     = "query"
+	HMACComponentHost      = "host"
+	HMACComponentTimestamp = "timestamp"
+	HMACComponentBody      = "body"
+	// HMACComponentHeaderPrefix is the prefix of the component of the header value such as `header:X-Request-Id`.
+	HMACComponentHeaderPrefix = "header:"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	sigV4DateFormat = "20060102"
+
+	defaultHMACAlgorithm       = "sha256"
+	defaultHMACHeader          = "X-Signature"
+	defaultHMACTimestampHeader = "X-Timestamp"
+	defaultHMACTimestampFormat = "unix"
+	defaultHMACEncoding        = "hex"
+	defaultHMACSeparator       = "\n"
+)
+
+var defaultHMACComponents = []string{HMACComponentMethod, HMACComponentPath, HMACComponentTimestamp, HMACComponentBody}
+
+// Headers that are not signed with SigV4 because they may be changed on the way.
+var sigV4IgnoredHeaders = map[string]struct{}{
+	"Authorization":   {},
+	"User-Agent":      {},
+	"X-Amzn-Trace-Id": {},
+	"Expect":          {},
+}
+
+type httpSignConfig struct {
+	Type string `yaml:"type"`
+	// SigV4
+	Region          string `yaml:"region,omitempty"`
+	Service         string `yaml:"service,omitempty"`
+	AccessKeyID     string `yaml:"accessKeyID,omitempty"`
+	SecretAccessKey string `yaml:"secretAccessKey,omitempty"`
+	SessionToken    string `yaml:"sessionToken,omitempty"`
+	// HMAC
+	Secret          string   `yaml:"secret,omitempty"`
+	Algorithm       string   `yaml:"algorithm,omitempty"`
+	Components      []string `yaml:"components,omitempty"`
+	Separator       *string  `yaml:"separator,omitempty"`
+	Encoding        string   `yaml:"encoding,omitempty"`
+	Header          string   `yaml:"header,omitempty"`
+	Prefix          string   `yaml:"prefix,omitempty"`
+	TimestampHeader string   `yaml:"timestampHeader,omitempty"`
+	TimestampFormat string   `yaml:"timestampFormat,omitempty"`
+}
+
+// httpSign signs HTTP requests.
+// Values of the config are expanded at run time, so `{{ vars.* }}` and `{{ env.* }}` can be used.
+type httpSign struct {
+	config *httpSignConfig
+	now    func() time.Time
+}
+
+func (c *httpSignConfig) validate() error {
+	switch c.Type {
+	case HTTPSignTypeSigV4:
+		if c.Service == "" {
+			return errors.New("sigv4 sign requires service")
+		}
+	case HTTPSignTypeHMAC:
+		if c.Secret == "" {
+			return errors.New("hmac sign requires secret")
+		}
+		if _, err := hmacHash(c.Algorithm); err != nil {
+			return err
+		}
+		for _, cp := range c.Components {
+			switch {
+			case cp == HMACComponentMethod, cp == HMACComponentPath, cp == HMACComponentQuery, cp == HMACComponentHost, cp == HMACComponentTimestamp, cp == HMACComponentBody:
+			case strings.HasPrefix(cp, HMACComponentHeaderPrefix) && len(cp) > len(HMACComponentHeaderPrefix):
+			default:
+				return fmt.Errorf("unsupported component of hmac sign: %s", cp)
+			}
+		}
+		switch c.Encoding {
+		case "", "hex", "base64":
+		default:
+			return fmt.Errorf("unsupported encoding of hmac sign: %s", c.Encoding)
+		}
+		switch c.TimestampFormat {
+		case "", "unix", "unixMilli", "rfc3339":
+		default:
+			return fmt.Errorf("unsupported timestampFormat of hmac sign: %s", c.TimestampFormat)
+		}
+	default:
+		return fmt.Errorf("unsupported sign type: %s", c.Type)
+	}
+	return nil
+}
+
+// newHTTPSign returns the signer of the config. auth is the config of the auth of the same runner.
+func newHTTPSign(c *httpSignConfig, auth *httpAuthConfig) (*httpSign, error) {
+	if c == nil {
+		return nil, nil
+	}
+	c.Type = strings.ToLower(c.Type)
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid sign: %w", err)
+	}
+	// The signature would overwrite the Authorization header set by auth.
+	if auth != nil && (c.Type == HTTPSignTypeSigV4 || (c.Type == HTTPSignTypeHMAC && strings.EqualFold(c.Header, "Authorization"))) {
+		return nil, fmt.Errorf("invalid sign: %s sign cannot be used with auth because both set the Authorization header", c.Type)
+	}
+	return &httpSign{config: c, now: time.Now}, nil
+}
+
+// expandConfig expands values of the config, and fills credentials of SigV4 from environment variables.
+func (s *httpSign) expandConfig(expand func(any) (any, error)) (*httpSignConfig, error) {
+	c := *s.config
+	for _, v := range []*string{&c.Region, &c.Service, &c.AccessKeyID, &c.SecretAccessKey, &c.SessionToken, &c.Secret} {
+		if *v == "" {
+			continue
+		}
+		e, err := expand(*v)
+		if err != nil {
+			return nil, err
+		}
+		ev, ok := e.(string)
+		if !ok {
+			ev = fmt.Sprintf("%v", e)
+		}
+		*v = ev
+	}
+	if c.Type != HTTPSignTypeSigV4 {
+		return &c, nil
+	}
+	if c.Region == "" {
+		c.Region = os.Getenv("AWS_REGION")
+	}
+	if c.Region == "" {
+		c.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if c.AccessKeyID == "" && c.SecretAccessKey == "" {
+		c.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if c.SessionToken == "" {
+			c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	if c.Region == "" {
+		return nil, errors.New("sigv4 sign requires region")
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return nil, errors.New("sigv4 sign requires credentials")
+	}
+	return &c, nil
+}
+
+// sign signs the request. The body of the request is read and restored.
+func (s *httpSign) sign(req *http.Request, expand func(any) (any, error)) error {
+	c, err := s.expandConfig(expand)
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	t := s.now().UTC()
+	switch c.Type {
+	case HTTPSignTypeSigV4:
+		signSigV4(req, body, c, t)
+	case HTTPSignTypeHMAC:
+		if err := signHMAC(req, body, c, t); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+	return nil
+}
+
+// signSigV4 signs the request with AWS Signature Version 4.
+// ref: https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func signSigV4(req *http.Request, body []byte, c *httpSignConfig, t time.Time) {
+	amzDate := t.Format(sigV4TimeFormat)
+	date := t.Format(sigV4DateFormat)
+	payloadHash := hexSHA256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+	if c.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, vs := range req.Header {
+		if _, ok := sigV4IgnoredHeaders[http.CanonicalHeaderKey(k)]; ok {
+			continue
+		}
+		trimmed := make([]string, len(vs))
+		for i, v := range vs {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(k)] = strings.Join(trimmed, ",")
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var ch strings.Builder
+	for _, k := range keys {
+		ch.WriteString(fmt.Sprintf("%s:%s\n", k, headers[k]))
+	}
+	signedHeaders := strings.Join(keys, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if c.Service != "s3" {
+		// Services other than S3 require the path to be encoded twice.
+		path = sigV4Escape(path, true)
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		sigV4CanonicalQuery(req.URL.Query()),
+		ch.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, c.Region, c.Service)
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, c.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", sigV4Algorithm, c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// signHMAC sets the timestamp header and the HMAC signature of the components of the request.
+func signHMAC(req *http.Request, body []byte, c *httpSignConfig, t time.Time) error {
+	h, err := hmacHash(c.Algorithm)
+	if err != nil {
+		return err
+	}
+	var ts string
+	switch c.TimestampFormat {
+	case "unixMilli":
+		ts = strconv.FormatInt(t.UnixMilli(), 10)
+	case "rfc3339":
+		ts = t.Format(time.RFC3339)
+	default:
+		ts = strconv.FormatInt(t.Unix(), 10)
+	}
+	components := c.Components
+	if len(components) == 0 {
+		components = defaultHMACComponents
+	}
+	tsHeader := c.TimestampHeader
+	if tsHeader == "" {
+		tsHeader = defaultHMACTimestampHeader
+	}
+	if contains(components, HMACComponentTimestamp) {
+		req.Header.Set(tsHeader, ts)
+	}
+	values := make([]string, 0, len(components))
+	for _, cp := range components {
+		switch {
+		case cp == HMACComponentMethod:
+			values = append(values, req.Method)
+		case cp == HMACComponentPath:
+			values = append(values, req.URL.EscapedPath())
+		case cp == HMACComponentQuery:
+			values = append(values, req.URL.RawQuery)
+		case cp == HMACComponentHost:
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			values = append(values, host)
+		case cp == HMACComponentTimestamp:
+			values = append(values, ts)
+		case cp == HMACComponentBody:
+			values = append(values, string(body))
+		case strings.HasPrefix(cp, HMACComponentHeaderPrefix):
+			values = append(values, req.Header.Get(strings.TrimPrefix(cp, HMACComponentHeaderPrefix)))
+		}
+	}
+	sep := defaultHMACSeparator
+	if c.Separator != nil {
+		sep = *c.Separator
+	}
+	mac := hmac.New(h, []byte(c.Secret))
+	_, _ = mac.Write([]byte(strings.Join(values, sep)))
+	var signature string
+	switch c.Encoding {
+	case "base64":
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	default:
+		signature = hex.EncodeToString(mac.Sum(nil))
+	}
+	header := c.Header
+	if header == "" {
+		header = defaultHMACHeader
+	}
+	req.Header.Set(header, c.Prefix+signature)
+	return nil
+}
+
+// readRequestBody reads the body of the request and restores it.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	b, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	return b, nil
+}
+
+func hmacHash(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "", defaultHMACAlgorithm:
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm of hmac sign: %s", algorithm)
+	}
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func sigV4CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		vs := append([]string{}, q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			params = append(params, fmt.Sprintf("%s=%s", sigV4Escape(k, false), sigV4Escape(v, false)))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// sigV4Escape encodes the string except unreserved characters of RFC 3986 ( and '/' if keepSlash ).
+func sigV4Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,227 @@
+package runn
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignSigV4(t *testing.T) {
+	// ref: get-vanilla of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &httpSignConfig{
+		Type:            HTTPSignTypeSigV4,
+		Region:          "us-east-1",
+		Service:         "service",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	signSigV4(req, nil, c, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("got %v\nwant %v", got, "20150830T123600Z")
+	}
+}
+
+func TestHTTPSignSigV4(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv("AWS_SESSION_TOKEN", "t0ken")
+	t.Setenv("AWS_REGION", "ap-northeast-1")
+	var (
+		gotAuthorization string
+		gotToken         string
+	)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		gotToken = r.Header.Get("X-Amz-Security-Token")
+		w.WriteHeader(http.StatusOK)
+	})
+	o, err := New(HTTPRunnerWithHandler("req", h, HTTPSignSigV4("", "execute-api", "", "", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &httpRequest{
+		path:      "/users",
+		method:    http.MethodPost,
+		mediaType: MediaTypeApplicationJSON,
+		headers:   map[string]string{},
+		body:      map[string]any{"username": "alice"},
+	}
+	if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"
+	if !strings.HasPrefix(gotAuthorization, wantPrefix) || !strings.Contains(gotAuthorization, "/ap-northeast-1/execute-api/aws4_request") {
+		t.Errorf("invalid authorization: %v", gotAuthorization)
+	}
+	if !strings.Contains(gotAuthorization, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token") {
+		t.Errorf("invalid signed headers: %v", gotAuthorization)
+	}
+	if gotToken != "t0ken" {
+		t.Errorf("got %v\nwant %v", gotToken, "t0ken")
+	}
+}
+
+func TestHTTPSignHMAC(t *testing.T) {
+	ctx := context.Background()
+	const secret = "s3cret"
+	// The handler verifies the signature over method, path, timestamp and body.
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msg := strings.Join([]string{r.Method, r.URL.Path, r.Header.Get("X-Timestamp"), string(b)}, "\n")
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write([]byte(msg))
+		if !hmac.Equal([]byte(r.Header.Get("X-Signature")), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		opt  httpRunnerOption
+		want int
+	}{
+		{HTTPSignHMAC("{{ vars.secret }}", "sha256"), http.StatusOK},
+		{HTTPSignHMAC(secret, "", HMACComponentMethod, HMACComponentPath, HMACComponentTimestamp, HMACComponentBody), http.StatusOK},
+		{HTTPSignHMAC("wrong", "sha256"), http.StatusUnauthorized},
+		{HTTPSignHMAC(secret, "sha512"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		o, err := New(HTTPRunnerWithHandler("req", h, tt.opt), Var("secret", secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &httpRequest{
+			path:      "/users",
+			method:    http.MethodPost,
+			mediaType: MediaTypeApplicationJSON,
+			headers:   map[string]string{},
+			body:      map[string]any{"username": "alice"},
+		}
+		if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		res, ok := o.store.steps[0][httpStoreResponseKey].(map[string]any)
+		if !ok {
+			t.Fatalf("invalid steps res: %v", o.store.steps[0])
+		}
+		if got := res[httpStoreStatusKey].(int); got != tt.want {
+			t.Errorf("got %v\nwant %v", got, tt.want)
+		}
+	}
+}
+
+func TestSignHMACScheme(t *testing.T) {
+	sep := ":"
+	c := &httpSignConfig{
+		Type:            HTTPSignTypeHMAC,
+		Secret:          "s3cret",
+		Components:      []string{HMACComponentMethod, HMACComponentHost, HMACComponentQuery, "header:X-Request-Id", HMACComponentTimestamp},
+		Separator:       &sep,
+		Encoding:        "base64",
+		Header:          "Authorization",
+		Prefix:          "HMAC ",
+		TimestampHeader: "Date",
+		TimestampFormat: "rfc3339",
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/users?page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Request-Id", "abc")
+	if err := signHMAC(req, nil, c, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Date"); got != "2023-05-01T00:00:00Z" {
+		t.Errorf("got %v\nwant %v", got, "2023-05-01T00:00:00Z")
+	}
+	// echo -n 'GET:example.com:page=2:abc:2023-05-01T00:00:00Z' | openssl dgst -sha256 -hmac s3cret -binary | base64
+	want := "HMAC xYE1W14hiMYNGbX+OhWXbPxjcmrCTHLLt2juAEhtUsU="
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestHTTPSignConfigInvalid(t *testing.T) {
+	tests := []*httpSignConfig{
+		{Type: "unknown"},
+		{Type: HTTPSignTypeSigV4},
+		{Type: HTTPSignTypeHMAC},
+		{Type: HTTPSignTypeHMAC, Secret: "s", Algorithm: "md5"},
+		{Type: HTTPSignTypeHMAC, Secret: "s", Components: []string{"cookie"}},
+		{Type: HTTPSignTypeHMAC, Secret: "s", Components: []string{"header:"}},
+		{Type: HTTPSignTypeHMAC, Secret: "s", Encoding: "base32"},
+		{Type: HTTPSignTypeHMAC, Secret: "s", TimestampFormat: "unixNano"},
+	}
+	for _, tt := range tests {
+		if _, err := newHTTPSign(tt, nil); err == nil {
+			t.Errorf("want error: %#v", tt)
+		}
+	}
+}
+
+func TestHTTPSignWithAuth(t *testing.T) {
+	auth := &httpAuthConfig{Type: HTTPAuthTypeBearer, Token: "t0ken"}
+	tests := []struct {
+		c       *httpSignConfig
+		wantErr bool
+	}{
+		{&httpSignConfig{Type: HTTPSignTypeSigV4, Service: "execute-api"}, true},
+		{&httpSignConfig{Type: HTTPSignTypeHMAC, Secret: "s", Header: "authorization"}, true},
+		{&httpSignConfig{Type: HTTPSignTypeHMAC, Secret: "s"}, false},
+	}
+	for _, tt := range tests {
+		_, err := newHTTPSign(tt.c, auth)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("got %v\nwantErr %v", err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseHTTPRunnerWithSign(t *testing.T) {
+	bk := newBook()
+	if err := bk.parseRunner("req", map[string]any{
+		"endpoint": "https://example.com",
+		"sign": map[string]any{
+			"type":       "hmac",
+			"secret":     "{{ env.HMAC_SECRET }}",
+			"components": []any{"method", "path", "timestamp", "body"},
+			"separator":  "",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := bk.httpRunners["req"].sign
+	if s == nil {
+		t.Fatal("sign is not set")
+	}
+	if s.config.Type != HTTPSignTypeHMAC || s.config.Secret != "{{ env.HMAC_SECRET }}" || len(s.config.Components) != 4 || s.config.Separator == nil || *s.config.Separator != "" {
+		t.Errorf("invalid sign config: %#v", s.config)
+	}
+	if err := bk.parseRunner("req", map[string]any{
+		"endpoint": "https://example.com",
+		"sign": map[string]any{
+			"type": "sigv4",
+		},
+	}); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,257 @@
+package runn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/spf13/cast"
+)
+
+const (
+	httpStoreEventsKey = "events"
+
+	httpEventIDKey    = "id"
+	httpEventEventKey = "event"
+	httpEventDataKey  = "data"
+)
+
+// defaultSSEEventType is the event type when the `event` field is not specified.
+const defaultSSEEventType = "message"
+
+// httpStream is the condition for reading a streaming response ( Server-Sent Events or line-delimited such as NDJSON ).
+// Reading stops when any of the conditions is met, or the stream ends.
+type httpStream struct {
+	// number of events
+	count int
+	// condition evaluated every time an event is received
+	until string
+	// reading time from sending the request
+	duration time.Duration
+}
+
+func parseHTTPStream(v any) (*httpStream, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid stream: %v", v)
+	}
+	s := &httpStream{}
+	for k, vv := range m {
+		switch k {
+		case "count":
+			c, err := cast.ToIntE(vv)
+			if err != nil || c < 0 {
+				return nil, fmt.Errorf("invalid stream.count: %v", vv)
+			}
+			s.count = c
+		case "until":
+			u, ok := vv.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid stream.until: %v", vv)
+			}
+			s.until = u
+		case "duration":
+			d, err := parseDuration(cast.ToString(vv))
+			if err != nil {
+				return nil, fmt.Errorf("invalid stream.duration: %v: %w", vv, err)
+			}
+			s.duration = d
+		default:
+			return nil, fmt.Errorf("invalid stream: unknown key: %s", k)
+		}
+	}
+	return s, nil
+}
+
+// isSSE returns whether the response is Server-Sent Events.
+func isSSE(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == MediaTypeTextEventStream
+}
+
+// IsStreamMediaType returns whether the media type is the one of streaming responses (Server-Sent Events or NDJSON).
+func IsStreamMediaType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mt {
+	case MediaTypeTextEventStream, MediaTypeApplicationXNDJSON, MediaTypeApplicationJSONLine:
+		return true
+	default:
+		return false
+	}
+}
+
+// readEvents reads events from the body of the streaming response until the condition of the stream is met.
+// It returns the events and the raw body that has been read.
+// ctx is the context of the step, not the context of the request bounded by stream.duration.
+func (rnr *httpRunner) readEvents(ctx context.Context, res *http.Response, s *httpStream) ([]any, []byte, error) {
+	var (
+		events []any
+		raw    bytes.Buffer
+	)
+	sse := isSSE(res.Header.Get("Content-Type"))
+	br := bufio.NewReader(res.Body)
+	ev := newSSEEvent()
+	for {
+		line, err := br.ReadString('\n')
+		raw.WriteString(line)
+		if err != nil && !errors.Is(err, io.EOF) {
+			if res.Request != nil && streamExpired(ctx, res.Request.Context(), s) {
+				return events, raw.Bytes(), nil
+			}
+			return nil, nil, err
+		}
+		eof := errors.Is(err, io.EOF)
+		line = strings.TrimRight(line, "\r\n")
+		var e map[string]any
+		switch {
+		case sse:
+			// An incomplete event at the end of the stream is discarded.
+			if !eof || line != "" {
+				e = ev.feed(line)
+			}
+		case line != "":
+			e = map[string]any{
+				httpEventIDKey:    "",
+				httpEventEventKey: "",
+				httpEventDataKey:  decodeJSONOrString(line),
+			}
+		}
+		if e != nil {
+			events = append(events, e)
+			done, err := rnr.streamDone(res, s, events)
+			if err != nil {
+				return nil, nil, err
+			}
+			if done {
+				return events, raw.Bytes(), nil
+			}
+		}
+		if eof {
+			return events, raw.Bytes(), nil
+		}
+	}
+}
+
+// streamExpired returns whether stream.duration has elapsed.
+// ctx is the context of the step and sctx is the context of the request bounded by stream.duration.
+func streamExpired(ctx, sctx context.Context, s *httpStream) bool {
+	return s != nil && s.duration > 0 && sctx.Err() != nil && ctx.Err() == nil
+}
+
+// recordExpiredStream records the empty streaming response when stream.duration has elapsed before the response headers arrive.
+func (rnr *httpRunner) recordExpiredStream() {
+	rnr.operator.record(map[string]any{
+		string(httpStoreResponseKey): map[string]any{
+			httpStoreStatusKey:  0,
+			httpStoreBodyKey:    nil,
+			httpStoreEventsKey:  []any{},
+			httpStoreRawBodyKey: "",
+			httpStoreHeaderKey:  http.Header{},
+			httpStoreCookieKey:  map[string]*http.Cookie{},
+		},
+	})
+}
+
+// streamDone returns whether the condition of the stream is met.
+func (rnr *httpRunner) streamDone(res *http.Response, s *httpStream, events []any) (bool, error) {
+	if s.count > 0 && len(events) >= s.count {
+		return true, nil
+	}
+	if s.until == "" {
+		return false, nil
+	}
+	store := rnr.operator.store.toMap()
+	store[storeIncludedKey] = rnr.operator.included
+	store[storePreviousKey] = rnr.operator.store.latest()
+	store[storeCurrentKey] = map[string]any{
+		httpStoreResponseKey: map[string]any{
+			httpStoreStatusKey: res.StatusCode,
+			httpStoreHeaderKey: res.Header,
+			httpStoreEventsKey: events,
+		},
+	}
+	done, err := EvalCond(s.until, store)
+	if err != nil {
+		return false, fmt.Errorf("invalid stream.until: %w", err)
+	}
+	return done, nil
+}
+
+// sseEvent is the buffer for parsing Server-Sent Events.
+// ref: https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type sseEvent struct {
+	// The last event ID is kept across events.
+	id    string
+	event string
+	data  []string
+}
+
+func newSSEEvent() *sseEvent {
+	return &sseEvent{}
+}
+
+// feed processes a line of the stream and returns the event when the event is dispatched.
+func (ev *sseEvent) feed(line string) map[string]any {
+	if line == "" {
+		return ev.dispatch()
+	}
+	if strings.HasPrefix(line, ":") {
+		// comment
+		return nil
+	}
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "id":
+		if !strings.Contains(value, "\x00") {
+			ev.id = value
+		}
+	case "event":
+		ev.event = value
+	case "data":
+		ev.data = append(ev.data, value)
+	}
+	return nil
+}
+
+func (ev *sseEvent) dispatch() map[string]any {
+	defer func() {
+		ev.event = ""
+		ev.data = nil
+	}()
+	if ev.data == nil {
+		return nil
+	}
+	typ := ev.event
+	if typ == "" {
+		typ = defaultSSEEventType
+	}
+	return map[string]any{
+		httpEventIDKey:    ev.id,
+		httpEventEventKey: typ,
+		httpEventDataKey:  decodeJSONOrString(strings.Join(ev.data, "\n")),
+	}
+}
+
+// decodeJSONOrString decodes the data as JSON when possible.
+func decodeJSONOrString(data string) any {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return data
+	}
+	return v
+}
@@ -0,0 +1,265 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+)
+
+func TestHTTPRunnerStream(t *testing.T) {
+	ctx := context.Background()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sse":
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprint(w, ": comment\n\nid: 1\ndata: {\"v\": 1}\n\nevent: multi\ndata: a\ndata: b\n\nid: 3\nevent: done\ndata: end\n\ndata: incomplete")
+		case "/ndjson":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			_, _ = fmt.Fprint(w, "{\"v\": 1}\r\n\n{\"v\": 2}\nplain\n{\"v\": 4}")
+		case "/infinite":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 1; ; i++ {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+				_, _ = fmt.Fprintf(w, "{\"v\": %d}\n", i)
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+			}
+		}
+	})
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name   string
+		path   string
+		stream *httpStream
+		want   []any
+	}{
+		{
+			"sse",
+			"/sse",
+			&httpStream{},
+			[]any{
+				map[string]any{"id": "1", "event": "message", "data": map[string]any{"v": float64(1)}},
+				map[string]any{"id": "1", "event": "multi", "data": "a\nb"},
+				map[string]any{"id": "3", "event": "done", "data": "end"},
+			},
+		},
+		{
+			"sse count",
+			"/sse",
+			&httpStream{count: 2},
+			[]any{
+				map[string]any{"id": "1", "event": "message", "data": map[string]any{"v": float64(1)}},
+				map[string]any{"id": "1", "event": "multi", "data": "a\nb"},
+			},
+		},
+		{
+			"sse until",
+			"/sse",
+			&httpStream{until: `current.res.events[-1].event == "multi"`},
+			[]any{
+				map[string]any{"id": "1", "event": "message", "data": map[string]any{"v": float64(1)}},
+				map[string]any{"id": "1", "event": "multi", "data": "a\nb"},
+			},
+		},
+		{
+			"ndjson",
+			"/ndjson",
+			&httpStream{},
+			[]any{
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(1)}},
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(2)}},
+				map[string]any{"id": "", "event": "", "data": "plain"},
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(4)}},
+			},
+		},
+		{
+			"infinite count",
+			"/infinite",
+			&httpStream{count: 2},
+			[]any{
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(1)}},
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(2)}},
+			},
+		},
+		{
+			"infinite until",
+			"/infinite",
+			&httpStream{until: `current.res.events[-1].data.v >= 3`},
+			[]any{
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(1)}},
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(2)}},
+				map[string]any{"id": "", "event": "", "data": map[string]any{"v": float64(3)}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		opts := map[string]Option{
+			"server":  HTTPRunner("req", ts.URL, ts.Client()),
+			"handler": HTTPRunnerWithHandler("req", h),
+		}
+		for k, opt := range opts {
+			if k == "handler" && tt.path == "/infinite" {
+				// http.Handler does not return until the stream ends
+				continue
+			}
+			opt := opt
+			t.Run(fmt.Sprintf("%s with %s", tt.name, k), func(t *testing.T) {
+				o, err := New(opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req := &httpRequest{path: tt.path, method: http.MethodGet, stream: tt.stream}
+				if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+					t.Fatal(err)
+				}
+				res := o.store.latest()["res"].(map[string]any)
+				if diff := cmp.Diff(res["events"], tt.want, nil); diff != "" {
+					t.Error(diff)
+				}
+				if res["body"] != nil {
+					t.Errorf("got %v\nwant nil", res["body"])
+				}
+			})
+		}
+	}
+}
+
+func TestHTTPRunnerStreamDuration(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.HTTPServer(t)
+	out := new(bytes.Buffer)
+	o, err := New(HTTPRunner("req", ts.URL, ts.Client()), Capture(NewDebugger(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &httpRequest{path: "/events.ndjson", method: http.MethodGet, stream: &httpStream{duration: 100 * time.Millisecond}}
+	if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	res := o.store.latest()["res"].(map[string]any)
+	events := res["events"].([]any)
+	if len(events) == 0 || len(events) >= 100 {
+		t.Errorf("invalid number of events: %d", len(events))
+	}
+	if got := strings.Count(res["rawBody"].(string), "\n"); got != len(events) {
+		t.Errorf("got %v\nwant %v", got, len(events))
+	}
+	if got := strings.Count(out.String(), "-----START HTTP RESPONSE EVENT-----"); got != len(events) {
+		t.Errorf("got %v\nwant %v", got, len(events))
+	}
+}
+
+func TestHTTPRunnerStreamDurationOverClientTimeout(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			// The response headers do not arrive within stream.duration.
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 1; ; i++ {
+			if _, err := fmt.Fprintf(w, "{\"v\": %d}\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantEvents bool
+	}{
+		{"/infinite", http.StatusOK, true},
+		{"/slow", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			client := ts.Client()
+			client.Timeout = 50 * time.Millisecond
+			o, err := New(HTTPRunner("req", ts.URL, client))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &httpRequest{path: tt.path, method: http.MethodGet, stream: &httpStream{duration: 200 * time.Millisecond}}
+			if err := o.httpRunners["req"].Run(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			res := o.store.latest()["res"].(map[string]any)
+			if got := res["status"]; got != tt.wantStatus {
+				t.Errorf("got %v\nwant %v", got, tt.wantStatus)
+			}
+			events := res["events"].([]any)
+			if got := len(events) > 5; got != tt.wantEvents {
+				t.Errorf("invalid number of events: %d", len(events))
+			}
+		})
+	}
+}
+
+func TestHTTPStreamRunbook(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.HTTPServer(t)
+	o, err := New(Book(filepath.Join(testutil.Testdata(), "book", "http_stream.yml")), HTTPRunner("req", ts.URL, ts.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseHTTPStream(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    *httpStream
+		wantErr bool
+	}{
+		{map[string]any{"count": uint64(3)}, &httpStream{count: 3}, false},
+		{map[string]any{"until": "len(current.res.events) > 1", "duration": "10sec"}, &httpStream{until: "len(current.res.events) > 1", duration: 10 * time.Second}, false},
+		{map[string]any{"duration": uint64(5)}, &httpStream{duration: 5 * time.Second}, false},
+		{map[string]any{"count": "three"}, nil, true},
+		{map[string]any{"limit": 3}, nil, true},
+		{true, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseHTTPStream(tt.in)
+		if err != nil {
+			if !tt.wantErr {
+				t.Errorf("got error: %v", err)
+			}
+			continue
+		}
+		if tt.wantErr {
+			t.Errorf("want error: %v", tt.in)
+		}
+		if diff := cmp.Diff(got, tt.want, cmp.AllowUnexported(httpStream{})); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
@@ -385,6 +385,34 @@ func TestMergeURL(t *testing.T) {
 	}
 }
 
+func TestMergeQuery(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		path     string
+		query    url.Values
+		want     string
+	}{
+		{"https://git.example.com/api/v3", "/search", nil, "https://git.example.com/api/v3/search"},
+		{"https://git.example.com/api/v3", "/search", url.Values{"q": []string{"a b&c"}}, "https://git.example.com/api/v3/search?q=a+b%26c"},
+		{"https://git.example.com/api/v3?lang=ja", "/search?page=2", url.Values{"tag": []string{"go", "yaml"}, "lang": []string{"en"}}, "https://git.example.com/api/v3/search?lang=ja&lang=en&page=2&tag=go&tag=yaml"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := mergeURL(u, tt.path)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		mergeQuery(got, tt.query)
+		if got.String() != tt.want {
+			t.Errorf("got %v\nwant %v", got.String(), tt.want)
+		}
+	}
+}
+
 func TestHTTPRunnerWithHandler(t *testing.T) {
 	tests := []struct {
 		req         *httpRequest
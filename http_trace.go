@@ -0,0 +1,202 @@
+package runn
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	httpStoreTimingKey = "timing"
+	httpStoreTLSKey    = "tls"
+)
+
+// HTTPTiming is the timing breakdown of the HTTP request.
+// DNS, Connect and TLSHandshake are zero when the connection is reused.
+type HTTPTiming struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// Time from the start of the request to the first byte of the response
+	TTFB time.Duration
+	// Time from the first byte of the response to the end of reading the response body
+	Transfer time.Duration
+	Total    time.Duration
+}
+
+// HTTPTLSInfo is the information of the TLS connection of the HTTP request.
+type HTTPTLSInfo struct {
+	Version     string
+	CipherSuite string
+	ServerName  string
+	// Subject of the peer (leaf) certificate
+	Subject string
+	// Issuer of the peer (leaf) certificate
+	Issuer string
+	// NotAfter of the peer (leaf) certificate
+	NotAfter time.Time
+}
+
+// httpTrace records the timing of the HTTP request using net/http/httptrace.
+type httpTrace struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	end          time.Time
+	mu           sync.Mutex
+}
+
+func newHTTPTrace() *httpTrace {
+	return &httpTrace{start: time.Now()}
+}
+
+// withClientTrace returns the request traced by the httpTrace.
+func (t *httpTrace) withClientTrace(req *http.Request) *http.Request {
+	ct := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.set(&t.dnsStart, false)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.set(&t.dnsDone, true)
+		},
+		ConnectStart: func(string, string) {
+			t.set(&t.connectStart, false)
+		},
+		ConnectDone: func(string, string, error) {
+			t.set(&t.connectDone, true)
+		},
+		TLSHandshakeStart: func() {
+			t.set(&t.tlsStart, false)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.set(&t.tlsDone, true)
+		},
+		GotFirstResponseByte: func() {
+			t.set(&t.firstByte, true)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), ct))
+}
+
+// set sets the current time to v.
+// If overwrite is false, v keeps the first time ( e.g. multiple dialing of Happy Eyeballs ).
+func (t *httpTrace) set(v *time.Time, overwrite bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !v.IsZero() && !overwrite {
+		return
+	}
+	*v = time.Now()
+}
+
+func (t *httpTrace) gotFirstByte() {
+	t.set(&t.firstByte, true)
+}
+
+func (t *httpTrace) done() {
+	t.set(&t.end, true)
+}
+
+func (t *httpTrace) timing() *HTTPTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := t.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	firstByte := t.firstByte
+	if firstByte.IsZero() {
+		firstByte = end
+	}
+	return &HTTPTiming{
+		DNS:          between(t.dnsStart, t.dnsDone),
+		Connect:      between(t.connectStart, t.connectDone),
+		TLSHandshake: between(t.tlsStart, t.tlsDone),
+		TTFB:         between(t.start, firstByte),
+		Transfer:     between(firstByte, end),
+		Total:        between(t.start, end),
+	}
+}
+
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// toStore returns the values of the timing to be recorded (milliseconds).
+func (tm *HTTPTiming) toStore() map[string]any {
+	return map[string]any{
+		"dns":      toMilliseconds(tm.DNS),
+		"connect":  toMilliseconds(tm.Connect),
+		"tls":      toMilliseconds(tm.TLSHandshake),
+		"ttfb":     toMilliseconds(tm.TTFB),
+		"transfer": toMilliseconds(tm.Transfer),
+		"total":    toMilliseconds(tm.Total),
+	}
+}
+
+func toMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newHTTPTLSInfo(cs *tls.ConnectionState) *HTTPTLSInfo {
+	if cs == nil {
+		return nil
+	}
+	i := &HTTPTLSInfo{
+		Version:     tlsVersionName(cs.Version),
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		ServerName:  cs.ServerName,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		c := cs.PeerCertificates[0]
+		i.Subject = c.Subject.String()
+		i.Issuer = c.Issuer.String()
+		i.NotAfter = c.NotAfter
+	}
+	return i
+}
+
+// toStore returns the values of the TLS connection to be recorded.
+func (i *HTTPTLSInfo) toStore() map[string]any {
+	v := map[string]any{
+		"version":    i.Version,
+		"cipher":     i.CipherSuite,
+		"serverName": i.ServerName,
+		"subject":    i.Subject,
+		"issuer":     i.Issuer,
+		"expiry":     nil,
+		"expiresIn":  nil,
+	}
+	if !i.NotAfter.IsZero() {
+		v["expiry"] = i.NotAfter
+		// seconds until the peer certificate expires
+		v["expiresIn"] = int64(time.Until(i.NotAfter) / time.Second)
+	}
+	return v
+}
+
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", v)
+	}
+}
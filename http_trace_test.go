@@ -0,0 +1,106 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPTiming(t *testing.T) {
+	ctx := context.Background()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	tts := httptest.NewTLSServer(h)
+	t.Cleanup(tts.Close)
+
+	tests := []struct {
+		name    string
+		opt     Option
+		wantTLS bool
+	}{
+		{"http", HTTPRunner("req", ts.URL, ts.Client()), false},
+		{"https", HTTPRunner("req", tts.URL, tts.Client()), true},
+		{"handler", HTTPRunnerWithHandler("req", h), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			o, err := New(tt.opt, Capture(NewDebugger(out)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.httpRunners["req"].Run(ctx, &httpRequest{path: "/", method: http.MethodGet}); err != nil {
+				t.Fatal(err)
+			}
+			res := o.store.latest()["res"].(map[string]any)
+			timing, ok := res["timing"].(map[string]any)
+			if !ok {
+				t.Fatalf("invalid timing: %#v", res["timing"])
+			}
+			for _, k := range []string{"dns", "connect", "tls", "ttfb", "transfer", "total"} {
+				v, ok := timing[k].(float64)
+				if !ok || v < 0 {
+					t.Errorf("invalid timing.%s: %#v", k, timing[k])
+				}
+			}
+			if timing["ttfb"].(float64) < 10 {
+				t.Errorf("got %v\nwant >= 10", timing["ttfb"])
+			}
+			if timing["total"].(float64) < timing["ttfb"].(float64) {
+				t.Errorf("total (%v) should be greater than or equal to ttfb (%v)", timing["total"], timing["ttfb"])
+			}
+			if !tt.wantTLS {
+				if res["tls"] != nil {
+					t.Errorf("got %v\nwant nil", res["tls"])
+				}
+			} else {
+				if timing["tls"].(float64) <= 0 {
+					t.Errorf("got %v\nwant > 0", timing["tls"])
+				}
+				tlsInfo, ok := res["tls"].(map[string]any)
+				if !ok {
+					t.Fatalf("invalid tls: %#v", res["tls"])
+				}
+				if got := tlsInfo["version"]; got != "TLS 1.3" {
+					t.Errorf("got %v\nwant %v", got, "TLS 1.3")
+				}
+				if got := tlsInfo["cipher"].(string); got == "" {
+					t.Error("cipher is empty")
+				}
+				if got := tlsInfo["subject"].(string); !strings.Contains(got, "Acme Co") {
+					t.Errorf("got %v\nwant to contain %v", got, "Acme Co")
+				}
+				if got := tlsInfo["expiresIn"].(int64); got <= 0 {
+					t.Errorf("got %v\nwant > 0", got)
+				}
+			}
+			if !strings.Contains(out.String(), "-----START HTTP TIMING-----") {
+				t.Errorf("debugger does not show timing: %s", out.String())
+			}
+		})
+	}
+}
+
+func TestTLSVersionName(t *testing.T) {
+	tests := []struct {
+		in   uint16
+		want string
+	}{
+		{0x0303, "TLS 1.2"},
+		{0x0304, "TLS 1.3"},
+		{0x0000, "0x0000"},
+	}
+	for _, tt := range tests {
+		if got := tlsVersionName(tt.in); got != tt.want {
+			t.Errorf("got %v\nwant %v", got, tt.want)
+		}
+	}
+}
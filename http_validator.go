@@ -13,6 +13,7 @@ import (
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
 	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
 )
 
@@ -62,33 +63,9 @@ type openApi3Validator struct {
 
 func newOpenApi3Validator(c *httpRunnerConfig) (*openApi3Validator, error) {
 	if c.OpenApi3DocLocation != "" {
-		l := c.OpenApi3DocLocation
-		ctx := context.Background()
-		loader := openapi3.NewLoader()
-		var doc *openapi3.T
-		switch {
-		case strings.HasPrefix(l, "https://") || strings.HasPrefix(l, "http://"):
-			u, err := url.Parse(l)
-			if err != nil {
-				return nil, err
-			}
-			doc, err = loader.LoadFromURI(u)
-			if err != nil {
-				return nil, err
-			}
-		default:
-			b, err := readFile(l)
-			if err != nil {
-				return nil, err
-			}
-			doc, err = loader.LoadFromData(b)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		if err := doc.Validate(ctx); err != nil {
-			return nil, fmt.Errorf("openapi3 document validation error: %w", err)
+		doc, err := loadOpenApi3Doc(c.OpenApi3DocLocation)
+		if err != nil {
+			return nil, err
 		}
 		c.openApi3Doc = doc
 	}
@@ -103,6 +80,38 @@ func newOpenApi3Validator(c *httpRunnerConfig) (*openApi3Validator, error) {
 	}, nil
 }
 
+// loadOpenApi3Doc loads and validates the OpenAPI v3 document from the file path or URL.
+func loadOpenApi3Doc(l string) (*openapi3.T, error) {
+	ctx := context.Background()
+	loader := openapi3.NewLoader()
+	var doc *openapi3.T
+	switch {
+	case strings.HasPrefix(l, "https://") || strings.HasPrefix(l, "http://"):
+		u, err := url.Parse(l)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = loader.LoadFromURI(u)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		b, err := readFile(l)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = loader.LoadFromData(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("openapi3 document validation error: %w", err)
+	}
+	return doc, nil
+}
+
 // FIXME: better to depend on any library
 // currently refer to https://developer.mozilla.org/en-US/docs/Web/HTTP/Basics_of_HTTP/MIME_types
 var registerBodyMimeTypes = []string{
@@ -134,11 +143,38 @@ func (v *openApi3Validator) ValidateRequest(ctx context.Context, req *http.Reque
 }
 
 func (v *openApi3Validator) requestInput(req *http.Request) (*openapi3filter.RequestValidationInput, error) {
+	route, pathParams, err := v.findRoute(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(contentEncodings(req.Header)) > 0 {
+		// The compressed body is validated after decoding.
+		b, err := decodeRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Header.Del("Content-Encoding")
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.ContentLength = int64(len(b))
+	}
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	}, nil
+}
+
+// findRoute finds the operation of the request in the document.
+func (v *openApi3Validator) findRoute(req *http.Request) (*routers.Route, map[string]string, error) {
 	// skip scheme://host:port validation
 	for _, server := range v.doc.Servers {
 		su, err := url.Parse(server.URL)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		su.Host = req.URL.Host
 		su.Opaque = req.URL.Opaque
@@ -147,21 +183,14 @@ func (v *openApi3Validator) requestInput(req *http.Request) (*openapi3filter.Req
 	}
 	router, err := legacyrouter.NewRouter(v.doc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	route, pathParams, err := router.FindRoute(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find route: %w (%s %s)", err, req.Method, req.URL.Path)
+		return nil, nil, fmt.Errorf("failed to find route: %w (%s %s)", err, req.Method, req.URL.Path)
 	}
-	return &openapi3filter.RequestValidationInput{
-		Request:    req,
-		PathParams: pathParams,
-		Route:      route,
-		Options: &openapi3filter.Options{
-			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
-		},
-	}, nil
+	return route, pathParams, nil
 }
 
 func (v *openApi3Validator) responseInput(req *http.Request, res *http.Response) (*openapi3filter.ResponseValidationInput, error) {
@@ -117,6 +117,9 @@ func (rnr *includeRunner) Run(ctx context.Context, c *includeConfig) error {
 	for _, r := range oo.grpcRunners {
 		r.operator = rnr.operator
 	}
+	for _, r := range oo.wsRunners {
+		r.operator = rnr.operator
+	}
 	for _, r := range oo.sshRunners {
 		r.operator = rnr.operator
 	}
@@ -139,6 +142,9 @@ func (o *operator) newNestedOperator(parent *step, opts ...Option) (*operator, e
 	for k, r := range o.grpcRunners {
 		popts = append(popts, runnGrpcRunner(k, r))
 	}
+	for k, r := range o.wsRunners {
+		popts = append(popts, runnWSRunner(k, r))
+	}
 	for k, r := range o.sshRunners {
 		popts = append(popts, runnSSHRunner(k, r))
 	}
@@ -150,6 +156,13 @@ func (o *operator) newNestedOperator(parent *step, opts ...Option) (*operator, e
 	for k, f := range o.store.funcs {
 		popts = append(popts, Func(k, f))
 	}
+	for mt, fn := range o.httpRequestEncoders {
+		popts = append(popts, HTTPRequestEncoder(mt, fn))
+	}
+	for mt, fn := range o.httpResponseDecoders {
+		popts = append(popts, HTTPResponseDecoder(mt, fn))
+	}
+	popts = append(popts, OpenAPI3Coverage(o.openAPI3Coverage))
 	// Prefer child runbook opts
 	opts = append(popts, opts...)
 	oo, err := New(opts...)
@@ -33,6 +33,7 @@ type operator struct {
 	httpRunners map[string]*httpRunner
 	dbRunners   map[string]*dbRunner
 	grpcRunners map[string]*grpcRunner
+	wsRunners   map[string]*wsRunner
 	cdpRunners  map[string]*cdpRunner
 	sshRunners  map[string]*sshRunner
 	steps       []*step
@@ -67,6 +68,12 @@ type operator struct {
 	sw            *stopw.Span
 	capturers     capturers
 	runResult     *RunResult
+	// Encoders of HTTP request body registered by HTTPRequestEncoder option
+	httpRequestEncoders httpRequestEncoders
+	// Decoders of HTTP response body registered by HTTPResponseDecoder option
+	httpResponseDecoders httpResponseDecoders
+	// Coverage of OpenAPI v3 documents shared by runbooks
+	openAPI3Coverage *openAPI3Coverage
 
 	mu sync.Mutex
 }
@@ -167,17 +174,9 @@ func (o *operator) runStep(ctx context.Context, i int, s *step) error {
 			}
 			run = true
 		case s.dbRunner != nil && s.dbQuery != nil:
-			e, err := o.expandBeforeRecord(s.dbQuery)
+			query, err := parseDBQuery(s.dbQuery, o.expandBeforeRecord)
 			if err != nil {
-				return err
-			}
-			q, ok := e.(map[string]any)
-			if !ok {
-				return fmt.Errorf("invalid %s: %v", o.stepName(i), e)
-			}
-			query, err := parseDBQuery(q)
-			if err != nil {
-				return fmt.Errorf("invalid %s: %v: %w", o.stepName(i), q, err)
+				return fmt.Errorf("invalid %s: %v: %w", o.stepName(i), s.dbQuery, err)
 			}
 			if err := s.dbRunner.Run(ctx, query); err != nil {
 				return fmt.Errorf("db query failed on %s: %w", o.stepName(i), err)
@@ -192,6 +191,15 @@ func (o *operator) runStep(ctx context.Context, i int, s *step) error {
 				return fmt.Errorf("gRPC request failed on %s: %w", o.stepName(i), err)
 			}
 			run = true
+		case s.wsRunner != nil && s.wsRequest != nil:
+			req, err := parseWSRequest(s.wsRequest, o.expandBeforeRecord)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v: %w", o.stepName(i), s.wsRequest, err)
+			}
+			if err := s.wsRunner.Run(ctx, req); err != nil {
+				return fmt.Errorf("WebSocket request failed on %s: %w", o.stepName(i), err)
+			}
+			run = true
 		case s.cdpRunner != nil && s.cdpActions != nil:
 			cas, err := parseCDPActions(s.cdpActions, o.expandBeforeRecord)
 			if err != nil {
@@ -423,6 +431,7 @@ func New(opts ...Option) (*operator, error) {
 		httpRunners: map[string]*httpRunner{},
 		dbRunners:   map[string]*dbRunner{},
 		grpcRunners: map[string]*grpcRunner{},
+		wsRunners:   map[string]*wsRunner{},
 		cdpRunners:  map[string]*cdpRunner{},
 		sshRunners:  map[string]*sshRunner{},
 		store: store{
@@ -433,29 +442,32 @@ func New(opts ...Option) (*operator, error) {
 			bindVars: map[string]any{},
 			useMap:   bk.useMap,
 		},
-		useMap:      bk.useMap,
-		desc:        bk.desc,
-		debug:       bk.debug,
-		profile:     bk.profile,
-		interval:    bk.interval,
-		loop:        bk.loop,
-		concurrency: bk.concurrency,
-		t:           bk.t,
-		thisT:       bk.t,
-		force:       bk.force,
-		failFast:    bk.failFast,
-		included:    bk.included,
-		ifCond:      bk.ifCond,
-		skipTest:    bk.skipTest,
-		stdout:      bk.stdout,
-		stderr:      bk.stderr,
-		newOnly:     bk.loadOnly,
-		bookPath:    bk.path,
-		beforeFuncs: bk.beforeFuncs,
-		afterFuncs:  bk.afterFuncs,
-		sw:          stopw.New(),
-		capturers:   bk.capturers,
-		runResult:   newRunResult(bk.desc, bk.path),
+		useMap:               bk.useMap,
+		desc:                 bk.desc,
+		debug:                bk.debug,
+		profile:              bk.profile,
+		interval:             bk.interval,
+		loop:                 bk.loop,
+		concurrency:          bk.concurrency,
+		t:                    bk.t,
+		thisT:                bk.t,
+		force:                bk.force,
+		failFast:             bk.failFast,
+		included:             bk.included,
+		ifCond:               bk.ifCond,
+		skipTest:             bk.skipTest,
+		stdout:               bk.stdout,
+		stderr:               bk.stderr,
+		newOnly:              bk.loadOnly,
+		bookPath:             bk.path,
+		beforeFuncs:          bk.beforeFuncs,
+		afterFuncs:           bk.afterFuncs,
+		sw:                   stopw.New(),
+		capturers:            bk.capturers,
+		runResult:            newRunResult(bk.desc, bk.path),
+		httpRequestEncoders:  bk.httpRequestEncoders,
+		httpResponseDecoders: bk.httpResponseDecoders,
+		openAPI3Coverage:     bk.openAPI3Coverage,
 	}
 
 	if o.debug {
@@ -474,6 +486,11 @@ func New(opts ...Option) (*operator, error) {
 	for k, v := range bk.httpRunners {
 		v.operator = o
 		o.httpRunners[k] = v
+		if o.openAPI3Coverage != nil {
+			if vv, ok := v.validator.(*openApi3Validator); ok {
+				o.openAPI3Coverage.register(vv.doc)
+			}
+		}
 	}
 	for k, v := range bk.dbRunners {
 		v.operator = o
@@ -487,8 +504,14 @@ func New(opts ...Option) (*operator, error) {
 		}
 		v.protos = append([]string{}, bk.grpcProtos...)
 		v.importPaths = append([]string{}, bk.grpcImportPaths...)
+		v.protosets = unique(append(v.protosets, bk.grpcProtosets...))
+		v.bufImages = unique(append(v.bufImages, bk.grpcBufImages...))
 		o.grpcRunners[k] = v
 	}
+	for k, v := range bk.wsRunners {
+		v.operator = o
+		o.wsRunners[k] = v
+	}
 	for k, v := range bk.cdpRunners {
 		v.operator = o
 		o.cdpRunners[k] = v
@@ -514,6 +537,12 @@ func New(opts ...Option) (*operator, error) {
 		}
 		keys[k] = struct{}{}
 	}
+	for k := range o.wsRunners {
+		if _, ok := keys[k]; ok {
+			return nil, fmt.Errorf("duplicate runner names (%s): %s", o.bookPath, k)
+		}
+		keys[k] = struct{}{}
+	}
 	for k := range o.cdpRunners {
 		if _, ok := keys[k]; ok {
 			return nil, fmt.Errorf("duplicate runner names (%s): %s", o.bookPath, k)
@@ -705,6 +734,16 @@ func (o *operator) AppendStep(key string, s map[string]any) error {
 				step.grpcRequest = vv
 				detected = true
 			}
+			wc, ok := o.wsRunners[k]
+			if ok && !detected {
+				step.wsRunner = wc
+				vv, ok := v.(map[string]any)
+				if !ok {
+					return fmt.Errorf("invalid WebSocket request: %v", v)
+				}
+				step.wsRequest = vv
+				detected = true
+			}
 			cc, ok := o.cdpRunners[k]
 			if ok && !detected {
 				step.cdpRunner = cc
@@ -785,12 +824,40 @@ func (o *operator) clearResult() {
 	}
 }
 
-func (o *operator) run(ctx context.Context) error {
+// inheritedDBRunner returns whether the DB runner is re-used from the parent runbook.
+func (o *operator) inheritedDBRunner(r *dbRunner) bool {
+	if !o.included || o.parent == nil || o.parent.parent == nil {
+		return false
+	}
+	for _, pr := range o.parent.parent.dbRunners {
+		if pr == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *operator) run(ctx context.Context) (err error) {
 	defer o.sw.Start(o.trails().toInterfaceSlice()...).Stop()
 	if o.newOnly {
 		return errors.New("this runbook is not allowed to run")
 	}
-	var err error
+	// The transactions spanning multiple steps and the rows of the fixtures do not outlive the runbook.
+	defer func() {
+		for _, r := range o.dbRunners {
+			if o.inheritedDBRunner(r) {
+				// The runners re-used from the parent runbook are closed by the parent.
+				continue
+			}
+			if cerr := r.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("failed to close %s of %s: %w", r.name, o.bookPathOrID(), cerr)
+				// The runbook that failed to clean up is also failure in the result.
+				if o.runResult != nil && o.runResult.Err == nil {
+					o.runResult.Err = err
+				}
+			}
+		}
+	}()
 	if o.t != nil {
 		// As test helper
 		o.t.Helper()
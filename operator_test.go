@@ -194,6 +194,10 @@ func TestRun(t *testing.T) {
 		book string
 	}{
 		{"testdata/book/db.yml"},
+		{"testdata/book/db_args.yml"},
+		{"testdata/book/db_transaction.yml"},
+		{"testdata/book/db_stmt.yml"},
+		{"testdata/book/db_fixtures.yml"},
 		{"testdata/book/only_if_included.yml"},
 		{"testdata/book/if.yml"},
 		{"testdata/book/previous.yml"},
@@ -586,7 +590,7 @@ func TestShard(t *testing.T) {
 			}
 			sortOperators(got)
 			allow := []any{
-				operator{}, httpRunner{}, dbRunner{}, grpcRunner{}, cdpRunner{}, sshRunner{},
+				operator{}, httpRunner{}, dbRunner{}, grpcRunner{}, cdpRunner{}, sshRunner{}, wsRunner{},
 			}
 			ignore := []any{
 				step{}, store{}, sql.DB{}, os.File{}, stopw.Span{}, debugger{}, nest.DB{}, Loop{},
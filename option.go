@@ -65,6 +65,9 @@ func Overlay(path string) Option {
 		for k, r := range loaded.grpcRunners {
 			bk.grpcRunners[k] = r
 		}
+		for k, r := range loaded.wsRunners {
+			bk.wsRunners[k] = r
+		}
 		for k, r := range loaded.cdpRunners {
 			bk.cdpRunners[k] = r
 		}
@@ -130,6 +133,11 @@ func Underlay(path string) Option {
 				bk.grpcRunners[k] = r
 			}
 		}
+		for k, r := range loaded.wsRunners {
+			if _, ok := bk.wsRunners[k]; !ok {
+				bk.wsRunners[k] = r
+			}
+		}
 		for k, r := range loaded.cdpRunners {
 			if _, ok := bk.cdpRunners[k]; !ok {
 				bk.cdpRunners[k] = r
@@ -203,6 +211,21 @@ func Runner(name, dsn string, opts ...httpRunnerOption) Option {
 				return fmt.Errorf("timeout in HttpRunnerConfig is invalid: %w", err)
 			}
 		}
+		r.useCookie = c.UseCookie
+		r.auth, err = newHTTPAuth(c.Auth)
+		if err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
+		r.sign, err = newHTTPSign(c.Sign, c.Auth)
+		if err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
+		if err := r.setDialer(c.Proxy, c.Resolve); err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
 		if c.OpenApi3DocLocation != "" {
 			v, err := newHttpValidator(c)
 			if err != nil {
@@ -277,6 +300,20 @@ func HTTPRunner(name, endpoint string, client *http.Client, opts ...httpRunnerOp
 			}
 		}
 		r.useCookie = c.UseCookie
+		r.auth, err = newHTTPAuth(c.Auth)
+		if err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
+		r.sign, err = newHTTPSign(c.Sign, c.Auth)
+		if err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
+		if err := r.setDialer(c.Proxy, c.Resolve); err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
 
 		hv, err := newHttpValidator(c)
 		if err != nil {
@@ -309,13 +346,36 @@ func HTTPRunnerWithHandler(name string, h http.Handler, opts ...httpRunnerOption
 				bk.runnerErrs[name] = errors.New("HTTPRunnerWithHandler does not support option NotFollowRedirect")
 				return nil
 			}
+			if c.Proxy != "" || len(c.Resolve) > 0 {
+				bk.runnerErrs[name] = errors.New("HTTPRunnerWithHandler does not support option HTTPProxy and HTTPResolve")
+				return nil
+			}
+			if c.Endpoint != "" {
+				u, err := url.Parse(c.Endpoint)
+				if err != nil {
+					bk.runnerErrs[name] = err
+					return nil
+				}
+				r.endpoint = u
+			}
 			r.multipartBoundary = c.MultipartBoundary
+			r.useCookie = c.UseCookie
 			if c.Timeout != "" {
 				r.client.Timeout, err = duration.Parse(c.Timeout)
 				if err != nil {
 					return fmt.Errorf("timeout in HttpRunnerConfig is invalid: %w", err)
 				}
 			}
+			r.auth, err = newHTTPAuth(c.Auth)
+			if err != nil {
+				bk.runnerErrs[name] = err
+				return nil
+			}
+			r.sign, err = newHTTPSign(c.Sign, c.Auth)
+			if err != nil {
+				bk.runnerErrs[name] = err
+				return nil
+			}
 			v, err := newHttpValidator(c)
 			if err != nil {
 				bk.runnerErrs[name] = err
@@ -329,17 +389,34 @@ func HTTPRunnerWithHandler(name string, h http.Handler, opts ...httpRunnerOption
 }
 
 // DBRunner - Set DB runner to runbook.
-func DBRunner(name string, client Querier) Option {
+func DBRunner(name string, client Querier, opts ...dbRunnerOption) Option {
 	return func(bk *book) error {
 		delete(bk.runnerErrs, name)
-		nt, err := nestTx(client)
+		db, err := sqlDB(client)
 		if err != nil {
 			return err
 		}
-		bk.dbRunners[name] = &dbRunner{
-			name:   name,
-			client: nt,
+		nt, err := nestTx(db)
+		if err != nil {
+			return err
+		}
+		c := &dbRunnerConfig{}
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				bk.runnerErrs[name] = err
+				return nil
+			}
+		}
+		r := &dbRunner{
+			name:    name,
+			client:  nt,
+			dialect: dbDialectOf(db),
+		}
+		if err := r.setConfig(db, c); err != nil {
+			bk.runnerErrs[name] = err
+			return nil
 		}
+		bk.dbRunners[name] = r
 		return nil
 	}
 }
@@ -362,10 +439,10 @@ func GrpcRunner(name string, cc *grpc.ClientConn) Option {
 func GrpcRunnerWithOptions(name, target string, opts ...grpcRunnerOption) Option {
 	return func(bk *book) error {
 		delete(bk.runnerErrs, name)
-		r := &grpcRunner{
-			name:   name,
-			target: target,
-			mds:    map[string]protoreflect.MethodDescriptor{},
+		r, err := newGrpcRunner(name, target)
+		if err != nil {
+			bk.runnerErrs[name] = err
+			return nil
 		}
 		if len(opts) > 0 {
 			c := &grpcRunnerConfig{}
@@ -408,13 +485,73 @@ func GrpcRunnerWithOptions(name, target string, opts ...grpcRunnerOption) Option
 			}
 			r.importPaths = c.ImportPaths
 			r.protos = c.Protos
+			r.protosets = c.Protosets
+			if c.BufImage != "" {
+				r.bufImages = []string{c.BufImage}
+			}
 			r.skipVerify = c.SkipVerify
+			if err := r.setDialer(c.Proxy, c.Resolve); err != nil {
+				bk.runnerErrs[name] = err
+				return nil
+			}
+			if err := r.setConnConfig(c); err != nil {
+				bk.runnerErrs[name] = err
+				return nil
+			}
 		}
 		bk.grpcRunners[name] = r
 		return nil
 	}
 }
 
+// WSRunner - Set WebSocket runner to runbook.
+func WSRunner(name, endpoint string, opts ...wsRunnerOption) Option {
+	return func(bk *book) error {
+		delete(bk.runnerErrs, name)
+		r, err := newWSRunner(name, endpoint)
+		if err != nil {
+			bk.runnerErrs[name] = err
+			return nil
+		}
+		if len(opts) > 0 {
+			c := &wsRunnerConfig{}
+			for _, opt := range opts {
+				if err := opt(c); err != nil {
+					bk.runnerErrs[name] = err
+					return nil
+				}
+			}
+			if c.CACert != "" {
+				b, err := readFile(c.CACert)
+				if err != nil {
+					bk.runnerErrs[name] = err
+					return nil
+				}
+				r.cacert = b
+			}
+			if c.Cert != "" {
+				b, err := readFile(c.Cert)
+				if err != nil {
+					bk.runnerErrs[name] = err
+					return nil
+				}
+				r.cert = b
+			}
+			if c.Key != "" {
+				b, err := readFile(c.Key)
+				if err != nil {
+					bk.runnerErrs[name] = err
+					return nil
+				}
+				r.key = b
+			}
+			r.skipVerify = c.SkipVerify
+		}
+		bk.wsRunners[name] = r
+		return nil
+	}
+}
+
 // SSHRunner - Set SSH runner to runbook.
 func SSHRunner(name string, client *ssh.Client) Option {
 	return func(bk *book) error {
@@ -656,6 +793,66 @@ func GRPCImportPaths(paths []string) Option {
 	}
 }
 
+// GRPCProtosets - Set the path of protoset files (FileDescriptorSet) for all gRPC runners.
+func GRPCProtosets(paths []string) Option {
+	return func(bk *book) error {
+		bk.grpcProtosets = paths
+		return nil
+	}
+}
+
+// GRPCBufImages - Set the path of buf image files for all gRPC runners.
+func GRPCBufImages(paths []string) Option {
+	return func(bk *book) error {
+		bk.grpcBufImages = paths
+		return nil
+	}
+}
+
+// HTTPRequestEncoder - Register the encoder for HTTP request body of the media type.
+func HTTPRequestEncoder(mediaType string, fn HTTPRequestEncodeFunc) Option {
+	return func(bk *book) error {
+		if mediaType == "" {
+			return errors.New("media type of HTTP request encoder is empty")
+		}
+		if fn == nil {
+			return fmt.Errorf("HTTP request encoder for %s is nil", mediaType)
+		}
+		if bk.httpRequestEncoders == nil {
+			bk.httpRequestEncoders = httpRequestEncoders{}
+		}
+		bk.httpRequestEncoders[strings.ToLower(mediaType)] = fn
+		return nil
+	}
+}
+
+// HTTPResponseDecoder - Register the decoder for HTTP response body of the media type.
+func HTTPResponseDecoder(mediaType string, fn HTTPResponseDecodeFunc) Option {
+	return func(bk *book) error {
+		if mediaType == "" {
+			return errors.New("media type of HTTP response decoder is empty")
+		}
+		if fn == nil {
+			return fmt.Errorf("HTTP response decoder for %s is nil", mediaType)
+		}
+		if bk.httpResponseDecoders == nil {
+			bk.httpResponseDecoders = httpResponseDecoders{}
+		}
+		bk.httpResponseDecoders[strings.ToLower(mediaType)] = fn
+		return nil
+	}
+}
+
+// OpenAPI3Coverage - Record operations of OpenAPI v3 documents exercised by HTTP runners to the coverage ( use NewOpenAPI3Coverage ).
+func OpenAPI3Coverage(c *openAPI3Coverage) Option {
+	return func(bk *book) error {
+		if c != nil {
+			bk.openAPI3Coverage = c
+		}
+		return nil
+	}
+}
+
 // BeforeFunc - Register the function to be run before the runbook is run.
 func BeforeFunc(fn func(*RunResult) error) Option {
 	return func(bk *book) error {
@@ -899,6 +1096,13 @@ func runnGrpcRunner(name string, r *grpcRunner) Option {
 	}
 }
 
+func runnWSRunner(name string, r *wsRunner) Option {
+	return func(bk *book) error {
+		bk.wsRunners[name] = r
+		return nil
+	}
+}
+
 func runnSSHRunner(name string, r *sshRunner) Option {
 	return func(bk *book) error {
 		bk.sshRunners[name] = r
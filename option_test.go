@@ -57,6 +57,7 @@ func TestOptionOverlay(t *testing.T) {
 				},
 				dbRunners:   map[string]*dbRunner{},
 				grpcRunners: map[string]*grpcRunner{},
+				wsRunners:   map[string]*wsRunner{},
 				cdpRunners:  map[string]*cdpRunner{},
 				sshRunners:  map[string]*sshRunner{},
 				runnerErrs:  map[string]error{},
@@ -101,6 +102,7 @@ func TestOptionOverlay(t *testing.T) {
 				},
 				dbRunners:   map[string]*dbRunner{},
 				grpcRunners: map[string]*grpcRunner{},
+				wsRunners:   map[string]*wsRunner{},
 				cdpRunners:  map[string]*cdpRunner{},
 				sshRunners:  map[string]*sshRunner{},
 				runnerErrs:  map[string]error{},
@@ -151,9 +153,10 @@ func TestOptionOverlay(t *testing.T) {
 					"req": {name: "req"},
 				},
 				dbRunners: map[string]*dbRunner{
-					"db": {name: "db"},
+					"db": {name: "db", dialect: dbDialectMySQL},
 				},
 				grpcRunners: map[string]*grpcRunner{},
+				wsRunners:   map[string]*wsRunner{},
 				cdpRunners:  map[string]*cdpRunner{},
 				sshRunners:  map[string]*sshRunner{},
 				runnerErrs:  map[string]error{},
@@ -220,6 +223,7 @@ func TestOptionUnderlay(t *testing.T) {
 				},
 				dbRunners:   map[string]*dbRunner{},
 				grpcRunners: map[string]*grpcRunner{},
+				wsRunners:   map[string]*wsRunner{},
 				cdpRunners:  map[string]*cdpRunner{},
 				sshRunners:  map[string]*sshRunner{},
 				runnerErrs:  map[string]error{},
@@ -264,6 +268,7 @@ func TestOptionUnderlay(t *testing.T) {
 				},
 				dbRunners:   map[string]*dbRunner{},
 				grpcRunners: map[string]*grpcRunner{},
+				wsRunners:   map[string]*wsRunner{},
 				cdpRunners:  map[string]*cdpRunner{},
 				sshRunners:  map[string]*sshRunner{},
 				runnerErrs:  map[string]error{},
@@ -314,9 +319,10 @@ func TestOptionUnderlay(t *testing.T) {
 					"req": {name: "req"},
 				},
 				dbRunners: map[string]*dbRunner{
-					"db": {name: "db"},
+					"db": {name: "db", dialect: dbDialectMySQL},
 				},
 				grpcRunners: map[string]*grpcRunner{},
+				wsRunners:   map[string]*wsRunner{},
 				cdpRunners:  map[string]*cdpRunner{},
 				sshRunners:  map[string]*sshRunner{},
 				runnerErrs:  map[string]error{},
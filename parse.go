@@ -1,13 +1,18 @@
 package runn
 
 import (
+	"database/sql"
 	"fmt"
+	"net/http"
+	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/goccy/go-yaml"
 	"github.com/k1LoW/duration"
+	"github.com/spf13/cast"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -51,6 +56,13 @@ func parseHTTPRequest(v map[string]any) (*httpRequest, error) {
 					}
 				}
 			}
+			qm, ok := vvvvv["query"]
+			if ok && qm != nil {
+				req.query, err = parseHTTPQuery(qm)
+				if err != nil {
+					return nil, fmt.Errorf("invalid request: %s: %w", string(part), err)
+				}
+			}
 			bm, ok := vvvvv["body"]
 			if ok {
 				switch v := bm.(type) {
@@ -80,6 +92,31 @@ func parseHTTPRequest(v map[string]any) (*httpRequest, error) {
 					}
 				}
 			}
+			sm, ok := vvvvv["stream"]
+			if ok && sm != nil {
+				req.stream, err = parseHTTPStream(sm)
+				if err != nil {
+					return nil, fmt.Errorf("invalid request: %s: %w", string(part), err)
+				}
+			}
+			fm, ok := vvvvv["fuzz"]
+			if ok && fm != nil {
+				req.fuzz, err = parseHTTPFuzz(fm)
+				if err != nil {
+					return nil, fmt.Errorf("invalid request: %s: %w", string(part), err)
+				}
+			}
+			cm, ok := vvvvv["compress"]
+			if ok && cm != nil {
+				enc, ok := cm.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid request: %s", string(part))
+				}
+				if err := validateContentEncoding(enc); err != nil {
+					return nil, fmt.Errorf("invalid request: %s: %w", string(part), err)
+				}
+				req.compress = enc
+			}
 		}
 
 		break
@@ -90,27 +127,160 @@ func parseHTTPRequest(v map[string]any) (*httpRequest, error) {
 	return req, nil
 }
 
-func parseDBQuery(v map[string]any) (*dbQuery, error) {
+// parseHTTPQuery parses `query:` of the HTTP request. The value is a scalar or a list of scalars.
+func parseHTTPQuery(v any) (url.Values, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid query: %v", v)
+	}
+	q := url.Values{}
+	for k, vv := range m {
+		switch vs := vv.(type) {
+		case []any:
+			for _, vvv := range vs {
+				s, err := cast.ToStringE(vvv)
+				if err != nil {
+					return nil, fmt.Errorf("invalid query.%s: %v", k, vv)
+				}
+				q.Add(k, s)
+			}
+		default:
+			s, err := cast.ToStringE(vs)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query.%s: %v", k, vv)
+			}
+			q.Add(k, s)
+		}
+	}
+	return q, nil
+}
+
+func parseDBQuery(v map[string]any, expand func(any) (any, error)) (*dbQuery, error) {
 	q := &dbQuery{}
 	part, err := yaml.Marshal(v)
 	if err != nil {
 		return nil, err
 	}
-	if len(v) != 1 {
-		return nil, fmt.Errorf("invalid query: %s", string(part))
+	for _, op := range []string{dbTxBegin, dbTxCommit, dbTxRollback} {
+		b, ok := v[op]
+		if !ok {
+			continue
+		}
+		if len(v) != 1 || b != true {
+			return nil, fmt.Errorf("invalid query: %s", string(part))
+		}
+		q.txOp = op
+		return q, nil
+	}
+	if f, ok := v["fixtures"]; ok {
+		return parseDBFixtures(v, f, expand)
 	}
 	s, ok := v["query"]
 	if !ok {
 		return nil, fmt.Errorf("invalid query: %s", string(part))
 	}
-	stmt, ok := s.(string)
+	for k := range v {
+		if k != "query" && k != "args" && k != "mode" {
+			return nil, fmt.Errorf("invalid query: %s", string(part))
+		}
+	}
+	if m, ok := v["mode"]; ok {
+		switch m {
+		case dbModeQuery, dbModeExec:
+			q.mode = m.(string)
+		default:
+			return nil, fmt.Errorf("invalid mode: %v", m)
+		}
+	}
+	e, err := expand(s)
+	if err != nil {
+		return nil, err
+	}
+	stmt, ok := e.(string)
 	if !ok || strings.Trim(stmt, " ") == "" {
 		return nil, fmt.Errorf("invalid query: %s", string(part))
 	}
 	q.stmt = strings.Trim(stmt, " \n")
+	a, ok := v["args"]
+	if !ok || a == nil {
+		return q, nil
+	}
+	// Each arg is expanded individually so that the value is bound as it is, instead of being embedded in the statement.
+	switch aa := a.(type) {
+	case []any:
+		for _, arg := range aa {
+			e, err := expand(arg)
+			if err != nil {
+				return nil, err
+			}
+			q.args = append(q.args, e)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(aa))
+		for k := range aa {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			e, err := expand(aa[k])
+			if err != nil {
+				return nil, err
+			}
+			q.args = append(q.args, sql.Named(k, e))
+		}
+	default:
+		return nil, fmt.Errorf("invalid args: %s", string(part))
+	}
 	return q, nil
 }
 
+func parseDBFixtures(v map[string]any, f any, expand func(any) (any, error)) (*dbQuery, error) {
+	part, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	for k := range v {
+		if k != "fixtures" && k != "strategy" {
+			return nil, fmt.Errorf("invalid fixtures: %s", string(part))
+		}
+	}
+	fx := &dbFixtures{
+		strategy: dbFixtureInsert,
+	}
+	if st, ok := v["strategy"]; ok {
+		switch st {
+		case dbFixtureInsert, dbFixtureTruncate, dbFixtureUpsert, dbFixtureDeleteAfterRun:
+			fx.strategy = st.(string)
+		default:
+			return nil, fmt.Errorf("invalid strategy: %v", st)
+		}
+	}
+	var files []any
+	switch ff := f.(type) {
+	case string:
+		files = []any{ff}
+	case []any:
+		files = ff
+	default:
+		return nil, fmt.Errorf("invalid fixtures: %s", string(part))
+	}
+	for _, file := range files {
+		e, err := expand(file)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := e.(string)
+		if !ok || p == "" {
+			return nil, fmt.Errorf("invalid fixtures: %s", string(part))
+		}
+		fx.files = append(fx.files, p)
+	}
+	if len(fx.files) == 0 {
+		return nil, fmt.Errorf("invalid fixtures: %s", string(part))
+	}
+	return &dbQuery{fixtures: fx}, nil
+}
+
 func parseGrpcRequest(v map[string]any, expand func(any) (any, error)) (*grpcRequest, error) {
 	v = trimDelimiter(v)
 	req := &grpcRequest{
@@ -234,6 +404,135 @@ func parseGrpcRequest(v map[string]any, expand func(any) (any, error)) (*grpcReq
 	return req, nil
 }
 
+func parseWSRequest(v map[string]any, expand func(any) (any, error)) (*wsRequest, error) {
+	v = trimDelimiter(v)
+	req := &wsRequest{
+		headers: http.Header{},
+	}
+	part, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) != 1 {
+		return nil, fmt.Errorf("invalid request: %s", string(part))
+	}
+	for k, vv := range v {
+		pe, err := expand(k)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := pe.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid request: %s", string(part))
+		}
+		req.path = p
+		vvv, ok := vv.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid request: %s", string(part))
+		}
+		hm, ok := vvv["headers"]
+		if ok {
+			hme, err := expand(hm)
+			if err != nil {
+				return nil, err
+			}
+			hm, ok := hme.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid request: %s", string(part))
+			}
+			for k, v := range hm {
+				vs, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid request: %s", string(part))
+				}
+				req.headers.Add(k, vs)
+			}
+		}
+		sm, ok := vvv["subprotocols"]
+		if ok {
+			sme, err := expand(sm)
+			if err != nil {
+				return nil, err
+			}
+			sms, ok := sme.([]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid request: %s", string(part))
+			}
+			for _, s := range sms {
+				ss, ok := s.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid request: %s", string(part))
+				}
+				req.subprotocols = append(req.subprotocols, ss)
+			}
+		}
+		tm, ok := vvv["timeout"]
+		if ok {
+			tme, err := expand(tm)
+			if err != nil {
+				return nil, err
+			}
+			tms, ok := tme.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid request: %s", string(part))
+			}
+			req.timeout, err = duration.Parse(tms)
+			if err != nil {
+				return nil, fmt.Errorf("invalid request: %s: %w", string(part), err)
+			}
+		}
+		// `message:` and `messages:` expand at run time so not here
+		mm, ok := vvv["message"]
+		if ok {
+			switch mm.(type) {
+			case map[string]any, string:
+			default:
+				return nil, fmt.Errorf("invalid request: %s", string(part))
+			}
+			// Send a message and receive a message
+			req.messages = append(req.messages, &wsMessage{
+				op:     WSOpMessage,
+				params: mm,
+			}, &wsMessage{
+				op: WSOpReceive,
+			})
+		} else {
+			mm, ok := vvv["messages"]
+			if ok {
+				mms, ok := mm.([]any)
+				if !ok {
+					return nil, fmt.Errorf("invalid request: %s", string(part))
+				}
+				for _, mm := range mms {
+					switch v := mm.(type) {
+					case string:
+						op := WSOp(v)
+						if op != WSOpClose && op != WSOpReceive {
+							// The string other than the operations is the text message.
+							req.messages = append(req.messages, &wsMessage{
+								op:     WSOpMessage,
+								params: v,
+							})
+							continue
+						}
+						req.messages = append(req.messages, &wsMessage{
+							op: op,
+						})
+					case map[string]any:
+						req.messages = append(req.messages, &wsMessage{
+							op:     WSOpMessage,
+							params: v,
+						})
+					default:
+						return nil, fmt.Errorf("invalid request: %s", string(part))
+					}
+				}
+			}
+		}
+	}
+	return req, nil
+}
+
 func parseCDPActions(v map[string]any, expand func(any) (any, error)) (CDPActions, error) {
 	v = trimDelimiter(v)
 	cas := CDPActions{}
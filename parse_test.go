@@ -1,7 +1,9 @@
 package runn
 
 import (
+	"database/sql"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
@@ -55,6 +57,41 @@ func TestParseHTTPRequest(t *testing.T) {
 		},
 		{
 			`
+/users:
+  get:
+    query:
+      q: "a b&c"
+      limit: 10
+      tags:
+        - go
+        - yaml
+    body: null
+`,
+			&httpRequest{
+				path:    "/users",
+				method:  http.MethodGet,
+				headers: map[string]string{},
+				query: url.Values{
+					"q":     []string{"a b&c"},
+					"limit": []string{"10"},
+					"tags":  []string{"go", "yaml"},
+				},
+			},
+			false,
+		},
+		{
+			`
+/users:
+  get:
+    query:
+      filter:
+        name: alice
+`,
+			nil,
+			true,
+		},
+		{
+			`
 /users/k1LoW:
   get: null
 `,
@@ -114,6 +151,104 @@ func TestParseHTTPRequest(t *testing.T) {
 			},
 			false,
 		},
+		{
+			`
+/events:
+  get:
+    body: null
+    stream:
+      count: 3
+      until: current.res.events[-1].event == "done"
+`,
+			&httpRequest{
+				path:      "/events",
+				method:    http.MethodGet,
+				mediaType: "",
+				headers:   map[string]string{},
+				body:      nil,
+				stream: &httpStream{
+					count: 3,
+					until: `current.res.events[-1].event == "done"`,
+				},
+			},
+			false,
+		},
+		{
+			`
+/events:
+  get:
+    body: null
+    stream: 3
+`,
+			nil,
+			true,
+		},
+		{
+			`
+/users:
+  post:
+    body:
+      application/json:
+        username: alice
+    fuzz:
+      seed: 42
+      count: 10
+`,
+			&httpRequest{
+				path:      "/users",
+				method:    http.MethodPost,
+				mediaType: MediaTypeApplicationJSON,
+				headers:   map[string]string{},
+				body:      map[string]any{"username": "alice"},
+				fuzz: &httpFuzz{
+					seed:  42,
+					count: 10,
+				},
+			},
+			false,
+		},
+		{
+			`
+/users:
+  post:
+    body: null
+    fuzz:
+      rounds: 3
+`,
+			nil,
+			true,
+		},
+		{
+			`
+/users:
+  post:
+    body:
+      application/json:
+        username: alice
+    compress: br
+`,
+			&httpRequest{
+				path:      "/users",
+				method:    http.MethodPost,
+				mediaType: MediaTypeApplicationJSON,
+				headers:   map[string]string{},
+				body:      map[string]any{"username": "alice"},
+				compress:  HTTPContentEncodingBrotli,
+			},
+			false,
+		},
+		{
+			`
+/users:
+  post:
+    body:
+      application/json:
+        username: alice
+    compress: lzma
+`,
+			nil,
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -131,7 +266,7 @@ func TestParseHTTPRequest(t *testing.T) {
 		if tt.wantErr {
 			t.Error("want error")
 		}
-		opts := cmp.AllowUnexported(httpRequest{})
+		opts := cmp.AllowUnexported(httpRequest{}, httpStream{}, httpFuzz{})
 		if diff := cmp.Diff(got, tt.want, opts); diff != "" {
 			t.Errorf("%s", diff)
 		}
@@ -163,14 +298,149 @@ query: |
 			},
 			false,
 		},
+		{
+			`
+query: SELECT * FROM users WHERE id = ? AND username = ?;
+args:
+  - 1
+  - "{{ vars.username }}"
+`,
+			&dbQuery{
+				stmt: "SELECT * FROM users WHERE id = ? AND username = ?;",
+				args: []any{uint64(1), "O'Reilly"},
+			},
+			false,
+		},
+		{
+			`
+query: SELECT * FROM users WHERE id = :id AND username = :username;
+args:
+  username: "{{ vars.username }}"
+  id: 1
+`,
+			&dbQuery{
+				stmt: "SELECT * FROM users WHERE id = :id AND username = :username;",
+				args: []any{sql.Named("id", uint64(1)), sql.Named("username", "O'Reilly")},
+			},
+			false,
+		},
+		{
+			`
+query: SELECT * FROM users WHERE id = ?;
+args: 1
+`,
+			nil,
+			true,
+		},
+		{
+			`
+query: SELECT * FROM users;
+arg:
+  - 1
+`,
+			nil,
+			true,
+		},
+		{
+			`
+begin: true
+`,
+			&dbQuery{
+				txOp: "begin",
+			},
+			false,
+		},
+		{
+			`
+rollback: true
+`,
+			&dbQuery{
+				txOp: "rollback",
+			},
+			false,
+		},
+		{
+			`
+commit: true
+query: SELECT * FROM users;
+`,
+			nil,
+			true,
+		},
+		{
+			`
+query: CALL insert_user('alice');
+mode: exec
+`,
+			&dbQuery{
+				stmt: "CALL insert_user('alice');",
+				mode: "exec",
+			},
+			false,
+		},
+		{
+			`
+query: SELECT * FROM users;
+mode: select
+`,
+			nil,
+			true,
+		},
+		{
+			`
+fixtures: fixtures/users.yml
+`,
+			&dbQuery{
+				fixtures: &dbFixtures{
+					files:    []string{"fixtures/users.yml"},
+					strategy: "insert",
+				},
+			},
+			false,
+		},
+		{
+			`
+fixtures:
+  - fixtures/users.csv
+  - fixtures/posts.csv
+strategy: delete-after-run
+`,
+			&dbQuery{
+				fixtures: &dbFixtures{
+					files:    []string{"fixtures/users.csv", "fixtures/posts.csv"},
+					strategy: "delete-after-run",
+				},
+			},
+			false,
+		},
+		{
+			`
+fixtures: fixtures/users.yml
+strategy: merge
+`,
+			nil,
+			true,
+		},
+		{
+			`
+fixtures: fixtures/users.yml
+query: SELECT * FROM users;
+`,
+			nil,
+			true,
+		},
 	}
 
+	o, err := New(Var("username", "O'Reilly"))
+	if err != nil {
+		t.Fatal(err)
+	}
 	for _, tt := range tests {
 		var v map[string]any
 		if err := yaml.Unmarshal([]byte(tt.in), &v); err != nil {
 			t.Fatal(err)
 		}
-		got, err := parseDBQuery(v)
+		got, err := parseDBQuery(v, o.expandBeforeRecord)
 		if err != nil {
 			if !tt.wantErr {
 				t.Error(err)
@@ -180,7 +450,7 @@ query: |
 		if tt.wantErr {
 			t.Error("want error")
 		}
-		opts := cmp.AllowUnexported(dbQuery{})
+		opts := cmp.AllowUnexported(dbQuery{}, dbFixtures{}, sql.NamedArg{})
 		if diff := cmp.Diff(got, tt.want, opts); diff != "" {
 			t.Errorf("%s", diff)
 		}
@@ -349,6 +619,168 @@ my.custom.server.Service/Method:
 	}
 }
 
+func TestParseWSRequest(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    *wsRequest
+		wantErr bool
+	}{
+		{
+			`
+/echo:
+  message:
+    key: value
+`,
+			&wsRequest{
+				path:    "/echo",
+				headers: http.Header{},
+				messages: []*wsMessage{
+					{
+						op: WSOpMessage,
+						params: map[string]any{
+							"key": "value",
+						},
+					},
+					{
+						op: WSOpReceive,
+					},
+				},
+			},
+			false,
+		},
+		{
+			`
+"{{ vars.path }}":
+  headers:
+    Authorization: "Bearer {{ vars.token }}"
+  subprotocols:
+    - chat.v1
+    - chat.v2
+  timeout: 3sec
+  messages:
+    -
+      key: value
+    -
+      receive
+    -
+      close
+`,
+			&wsRequest{
+				path: "/chat",
+				headers: http.Header{
+					"Authorization": []string{"Bearer t0ken"},
+				},
+				subprotocols: []string{"chat.v1", "chat.v2"},
+				timeout:      3 * time.Second,
+				messages: []*wsMessage{
+					{
+						op: WSOpMessage,
+						params: map[string]any{
+							"key": "value",
+						},
+					},
+					{
+						op: WSOpReceive,
+					},
+					{
+						op: WSOpClose,
+					},
+				},
+			},
+			false,
+		},
+		{
+			`
+/chat:
+  messages:
+    -
+      hello
+    -
+      receive
+`,
+			&wsRequest{
+				path:    "/chat",
+				headers: http.Header{},
+				messages: []*wsMessage{
+					{
+						op:     WSOpMessage,
+						params: "hello",
+					},
+					{
+						op: WSOpReceive,
+					},
+				},
+			},
+			false,
+		},
+		{
+			`
+/echo:
+  message: "hello {{ vars.token }}"
+`,
+			&wsRequest{
+				path:    "/echo",
+				headers: http.Header{},
+				messages: []*wsMessage{
+					{
+						op:     WSOpMessage,
+						params: "hello {{ vars.token }}",
+					},
+					{
+						op: WSOpReceive,
+					},
+				},
+			},
+			false,
+		},
+		{
+			`
+/chat:
+  messages:
+    -
+      - hello
+`,
+			nil,
+			true,
+		},
+		{
+			`
+/chat:
+  subprotocols: chat.v1
+`,
+			nil,
+			true,
+		},
+	}
+
+	o, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.store.vars = map[string]any{"path": "/chat", "token": "t0ken"}
+
+	for _, tt := range tests {
+		var v map[string]any
+		if err := yaml.Unmarshal([]byte(tt.in), &v); err != nil {
+			t.Fatal(err)
+		}
+		got, err := parseWSRequest(v, o.expandBeforeRecord)
+		if err != nil {
+			if !tt.wantErr {
+				t.Error(err)
+			}
+			continue
+		}
+		if tt.wantErr {
+			t.Error("want error")
+		}
+		opts := cmp.AllowUnexported(wsRequest{}, wsMessage{})
+		if diff := cmp.Diff(got, tt.want, opts); diff != "" {
+			t.Errorf("%s", diff)
+		}
+	}
+}
+
 func TestParseExecCommand(t *testing.T) {
 	tests := []struct {
 		in      string
@@ -0,0 +1,132 @@
+package runn
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// harLog is the subset of HAR 1.2 used to generate steps.
+// ref: http://www.softwareishard.com/blog/har-12-spec/
+type harLog struct {
+	Log struct {
+		Entries []*harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request *harRequest `json:"request"`
+}
+
+type harRequest struct {
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Headers  []*harNameValue `json:"headers"`
+	PostData *harPostData    `json:"postData,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string          `json:"mimeType"`
+	Text     string          `json:"text"`
+	Params   []*harNameValue `json:"params"`
+	Encoding string          `json:"encoding,omitempty"`
+}
+
+// AppendStepsFromHAR appends HTTP steps converted from the entries of the HAR.
+// Only entries matching hosts and methods are converted ( all entries if empty ).
+func (rb *runbook) AppendStepsFromHAR(in io.Reader, hosts, methods []string) error {
+	h := &harLog{}
+	if err := json.NewDecoder(in).Decode(h); err != nil {
+		return fmt.Errorf("invalid HAR: %w", err)
+	}
+	for i, e := range h.Log.Entries {
+		if e.Request == nil {
+			continue
+		}
+		req, err := e.Request.toHTTPRequest()
+		if err != nil {
+			return fmt.Errorf("invalid HAR: entries[%d]: %w", i, err)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			// data:, blob:, ws: and so on
+			continue
+		}
+		if len(hosts) > 0 && !contains(hosts, req.URL.Host) && !contains(hosts, req.URL.Hostname()) {
+			continue
+		}
+		if len(methods) > 0 && !containsFold(methods, req.Method) {
+			continue
+		}
+		if rb.useMap {
+			key := fmt.Sprintf("%s%d", strings.ToLower(req.Method), len(rb.stepKeys))
+			rb.stepKeys = append(rb.stepKeys, key)
+		}
+		dsn := fmt.Sprintf("%s://%s", req.URL.Scheme, req.URL.Host)
+		key := rb.setRunner(dsn)
+		step, err := CreateHTTPStepMapSlice(key, req)
+		if err != nil {
+			return err
+		}
+		rb.Steps = append(rb.Steps, step)
+	}
+	return nil
+}
+
+func (r *harRequest) toHTTPRequest() (*http.Request, error) {
+	var body io.Reader
+	contentType := ""
+	if r.PostData != nil {
+		contentType = r.PostData.MimeType
+		switch {
+		case r.PostData.Text != "" && r.PostData.Encoding == "base64":
+			b, err := base64.StdEncoding.DecodeString(r.PostData.Text)
+			if err != nil {
+				return nil, err
+			}
+			body = bytes.NewReader(b)
+		case r.PostData.Text != "":
+			body = strings.NewReader(r.PostData.Text)
+		case len(r.PostData.Params) > 0:
+			vs := url.Values{}
+			for _, p := range r.PostData.Params {
+				vs.Add(p.Name, p.Value)
+			}
+			body = strings.NewReader(vs.Encode())
+		}
+	}
+	req, err := http.NewRequest(r.Method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range r.Headers {
+		// Pseudo-headers of HTTP/2 and headers computed when sending the request are skipped.
+		if strings.HasPrefix(h.Name, ":") || strings.EqualFold(h.Name, "Content-Length") {
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+	if req.Header.Get("Content-Type") == "" && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+func containsFold(s []string, e string) bool {
+	for _, v := range s {
+		if strings.EqualFold(v, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,440 @@
+package runn
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// OpenAPI3GroupByOperation generates a runbook per operation.
+	OpenAPI3GroupByOperation = "operation"
+	// OpenAPI3GroupByTag generates a runbook per tag of operations.
+	OpenAPI3GroupByTag = "tag"
+)
+
+const (
+	openAPI3RunnerKey     = "req"
+	openAPI3DummyEndpoint = "https://example.com"
+	openAPI3DefaultTag    = "default"
+	// Max depth of nested schemas to synthesize examples ( for recursive schemas ).
+	openAPI3ExampleMaxDepth = 8
+)
+
+// Order of methods of operations in the runbook.
+var openAPI3Methods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodConnect,
+}
+
+var openAPI3NameRe = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+type openAPI3Operation struct {
+	method string
+	path   string
+	item   *openapi3.PathItem
+	op     *openapi3.Operation
+}
+
+// NewRunbooksFromOpenAPI3 generates runbook skeletons from the OpenAPI v3 document ( file path or URL ).
+// The runbooks are generated per operation or per tag, and the key of the returned map is the name of the runbook ( operationId or tag, in which characters other than alphanumerics are replaced with _ ).
+// If dir, the directory where the runbooks are placed, is specified, the file path of the document in the runbooks is relative to it.
+func NewRunbooksFromOpenAPI3(l, groupBy, dir string) (map[string]*runbook, error) {
+	doc, err := loadOpenApi3Doc(l)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := openAPI3Endpoint(doc)
+	loc := l
+	if dir != "" && !strings.HasPrefix(l, "https://") && !strings.HasPrefix(l, "http://") {
+		loc, err = relPath(dir, l)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ops := openAPI3Operations(doc)
+
+	rbs := map[string]*runbook{}
+	// origins are the names before being sanitized to detect the collision of the names.
+	origins := map[string]string{}
+	for _, o := range ops {
+		var name, desc string
+		switch groupBy {
+		case OpenAPI3GroupByOperation, "":
+			name = o.name()
+			desc = o.desc()
+		case OpenAPI3GroupByTag:
+			name = openAPI3DefaultTag
+			if len(o.op.Tags) > 0 {
+				name = o.op.Tags[0]
+			}
+			desc = name
+			if t := doc.Tags.Get(name); t != nil && t.Description != "" {
+				desc = t.Description
+			}
+		default:
+			return nil, fmt.Errorf("invalid group of runbooks: %s", groupBy)
+		}
+		// The name is used as the file name of the runbook.
+		n := openAPI3SafeName(name)
+		if n == "" {
+			return nil, fmt.Errorf("invalid name of runbook: %q", name)
+		}
+		if orig, ok := origins[n]; ok && (orig != name || groupBy != OpenAPI3GroupByTag) {
+			return nil, fmt.Errorf("duplicate name of runbook: %q and %q are both %q", orig, name, n)
+		}
+		origins[n] = name
+		name = n
+		rb, ok := rbs[name]
+		if !ok {
+			rb = NewRunbook(desc)
+			rb.Runners[openAPI3RunnerKey] = yaml.MapSlice{
+				{Key: "endpoint", Value: endpoint},
+				{Key: "openapi3", Value: loc},
+			}
+			rbs[name] = rb
+		}
+		rb.Steps = append(rb.Steps, o.toStep(openAPI3RunnerKey, rb.Vars))
+	}
+	return rbs, nil
+}
+
+// relPath returns the path of the file relative to the directory.
+func relPath(dir, p string) (string, error) {
+	ad, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	ap, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(ad, ap)
+}
+
+// openAPI3Endpoint returns the URL of the first server of the document.
+func openAPI3Endpoint(doc *openapi3.T) string {
+	if len(doc.Servers) == 0 {
+		return openAPI3DummyEndpoint
+	}
+	s := doc.Servers[0]
+	u := s.URL
+	for k, v := range s.Variables {
+		u = strings.ReplaceAll(u, fmt.Sprintf("{%s}", k), v.Default)
+	}
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		// Relative server URL such as "/api/v1"
+		return openAPI3DummyEndpoint + "/" + strings.TrimPrefix(u, "/")
+	}
+	return u
+}
+
+// openAPI3Operations returns the operations of the document in the order of paths and methods.
+func openAPI3Operations(doc *openapi3.T) []*openAPI3Operation {
+	var paths []string
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var ops []*openAPI3Operation
+	for _, p := range paths {
+		item := doc.Paths[p]
+		opm := item.Operations()
+		for _, m := range openAPI3Methods {
+			op, ok := opm[m]
+			if !ok {
+				continue
+			}
+			ops = append(ops, &openAPI3Operation{
+				method: m,
+				path:   p,
+				item:   item,
+				op:     op,
+			})
+		}
+	}
+	return ops
+}
+
+func (o *openAPI3Operation) name() string {
+	if o.op.OperationID != "" {
+		return o.op.OperationID
+	}
+	return openAPI3SafeName(fmt.Sprintf("%s_%s", strings.ToLower(o.method), o.path))
+}
+
+// openAPI3SafeName replaces characters other than alphanumerics with _ so that the name can be used as the file name.
+func openAPI3SafeName(name string) string {
+	return strings.Trim(openAPI3NameRe.ReplaceAllString(name, "_"), "_")
+}
+
+func (o *openAPI3Operation) desc() string {
+	if o.op.Summary != "" {
+		return o.op.Summary
+	}
+	if o.op.OperationID != "" {
+		return o.op.OperationID
+	}
+	return fmt.Sprintf("%s %s", o.method, o.path)
+}
+
+// parameters returns the parameters of the operation including the common parameters of the path.
+func (o *openAPI3Operation) parameters() []*openapi3.Parameter {
+	var params []*openapi3.Parameter
+	for _, p := range o.op.Parameters {
+		if p.Value != nil {
+			params = append(params, p.Value)
+		}
+	}
+	for _, p := range o.item.Parameters {
+		if p.Value == nil {
+			continue
+		}
+		if o.op.Parameters.GetByInAndName(p.Value.In, p.Value.Name) != nil {
+			continue
+		}
+		params = append(params, p.Value)
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
+
+// toStep generates the step of the operation. Path parameters are set to vars.
+func (o *openAPI3Operation) toStep(key string, vars map[string]any) yaml.MapSlice {
+	p := o.path
+	h := map[string]string{}
+	q := map[string]any{}
+	for _, param := range o.parameters() {
+		v := openAPI3ParameterExample(param)
+		switch param.In {
+		case openapi3.ParameterInPath:
+			if _, ok := vars[param.Name]; !ok {
+				vars[param.Name] = v
+			}
+			p = strings.ReplaceAll(p, fmt.Sprintf("{%s}", param.Name), fmt.Sprintf("{{ vars.%s }}", param.Name))
+		case openapi3.ParameterInQuery:
+			if param.Required || param.Example != nil || len(param.Examples) > 0 {
+				q[param.Name] = v
+			}
+		case openapi3.ParameterInHeader:
+			if param.Required {
+				h[param.Name] = fmt.Sprintf("%v", v)
+			}
+		}
+	}
+
+	hb := yaml.MapSlice{}
+	if len(h) > 0 {
+		hb = append(hb, yaml.MapItem{Key: "headers", Value: h})
+	}
+	if len(q) > 0 {
+		hb = append(hb, yaml.MapItem{Key: "query", Value: q})
+	}
+	var bd yaml.MapSlice
+	if o.op.RequestBody != nil && o.op.RequestBody.Value != nil {
+		mt, v := openAPI3RequestBodyExample(o.op.RequestBody.Value)
+		if mt != "" {
+			bd = yaml.MapSlice{{Key: mt, Value: v}}
+		}
+	}
+	switch {
+	case len(bd) == 0 && (o.method == http.MethodPost || o.method == http.MethodPut || o.method == http.MethodPatch):
+		// The methods sending the body require the media type even if the body is empty, as CreateHTTPStepMapSlice does.
+		hb = append(hb, yaml.MapItem{Key: "body", Value: yaml.MapSlice{{Key: MediaTypeApplicationJSON, Value: nil}}})
+	case len(bd) == 0:
+		hb = append(hb, yaml.MapItem{Key: "body", Value: nil})
+	default:
+		hb = append(hb, yaml.MapItem{Key: "body", Value: bd})
+	}
+
+	step := yaml.MapSlice{
+		{Key: "desc", Value: o.desc()},
+		{Key: key, Value: yaml.MapSlice{
+			{Key: p, Value: yaml.MapSlice{
+				{Key: strings.ToLower(o.method), Value: hb},
+			}},
+		}},
+	}
+	if cond := openAPI3StatusCond(o.op); cond != "" {
+		step = append(step, yaml.MapItem{Key: "test", Value: cond})
+	}
+	return step
+}
+
+// openAPI3StatusCond returns the condition of the documented status codes.
+// The lowest successful status code is expected if documented.
+func openAPI3StatusCond(op *openapi3.Operation) string {
+	var codes []int
+	for s := range op.Responses {
+		c, err := strconv.Atoi(s)
+		if err != nil {
+			continue
+		}
+		codes = append(codes, c)
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	sort.Ints(codes)
+	for _, c := range codes {
+		if c >= 200 && c < 300 {
+			return fmt.Sprintf("current.res.status == %d\n", c)
+		}
+	}
+	s := make([]string, len(codes))
+	for i, c := range codes {
+		s[i] = strconv.Itoa(c)
+	}
+	return fmt.Sprintf("current.res.status in [%s]\n", strings.Join(s, ", "))
+}
+
+func openAPI3ParameterExample(p *openapi3.Parameter) any {
+	if p.Example != nil {
+		return p.Example
+	}
+	if v, ok := firstExample(p.Examples); ok {
+		return v
+	}
+	if p.Schema != nil {
+		return openAPI3SchemaExample(p.Schema.Value, 0)
+	}
+	return ""
+}
+
+// openAPI3RequestBodyExample returns the media type and the example of the request body.
+// application/json is preferred when the request body has multiple media types.
+func openAPI3RequestBodyExample(rb *openapi3.RequestBody) (string, any) {
+	if len(rb.Content) == 0 {
+		return "", nil
+	}
+	mt := MediaTypeApplicationJSON
+	if _, ok := rb.Content[mt]; !ok {
+		var mts []string
+		for k := range rb.Content {
+			mts = append(mts, k)
+		}
+		sort.Strings(mts)
+		mt = mts[0]
+	}
+	c := rb.Content[mt]
+	if c.Example != nil {
+		return mt, c.Example
+	}
+	if v, ok := firstExample(c.Examples); ok {
+		return mt, v
+	}
+	if c.Schema == nil {
+		return mt, nil
+	}
+	return mt, openAPI3SchemaExample(c.Schema.Value, 0)
+}
+
+// openAPI3SchemaExample synthesizes the example value from the schema.
+func openAPI3SchemaExample(s *openapi3.Schema, depth int) any {
+	if s == nil || depth > openAPI3ExampleMaxDepth {
+		return nil
+	}
+	switch {
+	case s.Example != nil:
+		return s.Example
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case len(s.AllOf) > 0:
+		m := map[string]any{}
+		for _, ss := range s.AllOf {
+			v, ok := openAPI3SchemaExample(ss.Value, depth+1).(map[string]any)
+			if !ok {
+				continue
+			}
+			for k, vv := range v {
+				m[k] = vv
+			}
+		}
+		for k, v := range openAPI3PropertiesExample(s, depth) {
+			m[k] = v
+		}
+		return m
+	case len(s.OneOf) > 0:
+		return openAPI3SchemaExample(s.OneOf[0].Value, depth+1)
+	case len(s.AnyOf) > 0:
+		return openAPI3SchemaExample(s.AnyOf[0].Value, depth+1)
+	}
+	switch s.Type {
+	case openapi3.TypeObject:
+		return openAPI3PropertiesExample(s, depth)
+	case openapi3.TypeArray:
+		if s.Items == nil {
+			return []any{}
+		}
+		return []any{openAPI3SchemaExample(s.Items.Value, depth+1)}
+	case openapi3.TypeInteger:
+		if s.Min != nil {
+			return int(*s.Min)
+		}
+		return 0
+	case openapi3.TypeNumber:
+		if s.Min != nil {
+			return *s.Min
+		}
+		return 0.0
+	case openapi3.TypeBoolean:
+		return true
+	case openapi3.TypeString:
+		switch s.Format {
+		case "date-time":
+			return "2006-01-02T15:04:05Z"
+		case "date":
+			return "2006-01-02"
+		case "email":
+			return "alice@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		case "binary":
+			return "path/to/file"
+		}
+		return "string"
+	}
+	if len(s.Properties) > 0 {
+		return openAPI3PropertiesExample(s, depth)
+	}
+	return nil
+}
+
+func openAPI3PropertiesExample(s *openapi3.Schema, depth int) map[string]any {
+	m := map[string]any{}
+	for k, p := range s.Properties {
+		if p.Value != nil && p.Value.ReadOnly {
+			continue
+		}
+		m[k] = openAPI3SchemaExample(p.Value, depth+1)
+	}
+	return m
+}
+
+// firstExample returns the value of the first example in the order of the names.
+func firstExample(examples openapi3.Examples) (any, bool) {
+	var names []string
+	for k, e := range examples {
+		if e != nil && e.Value != nil {
+			names = append(names, k)
+		}
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	sort.Strings(names)
+	return examples[names[0]].Value.Value, true
+}
@@ -0,0 +1,181 @@
+package runn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goyaml "github.com/goccy/go-yaml"
+	"github.com/tenntenn/golden"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewRunbooksFromOpenAPI3(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		want    []string
+	}{
+		{OpenAPI3GroupByOperation, []string{"adoptPet", "createPet", "delete_pets_petId", "listPets", "post_stores_storeId_orders", "showPet"}},
+		{OpenAPI3GroupByTag, []string{"default", "pets"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			dir := t.TempDir()
+			rbs, err := NewRunbooksFromOpenAPI3(filepath.Join("testdata", "openapi3_petstore.yml"), tt.groupBy, "testdata/book")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rbs) != len(tt.want) {
+				t.Errorf("got %v\nwant %v", len(rbs), len(tt.want))
+			}
+			for _, k := range tt.want {
+				rb, ok := rbs[k]
+				if !ok {
+					t.Errorf("runbook %s is not generated", k)
+					continue
+				}
+				b, err := yaml.Marshal(rb)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.yml", k)), b, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				// The generated runbook can be loaded
+				if _, err := parseRunbook(b); err != nil {
+					t.Error(err)
+				}
+			}
+
+			got := golden.Txtar(t, dir)
+			f := fmt.Sprintf("openapi3_petstore.%s.new", tt.groupBy)
+			if os.Getenv("UPDATE_GOLDEN") != "" {
+				golden.Update(t, "testdata", f, got)
+				return
+			}
+			if diff := golden.Diff(t, "testdata", f, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestNewRunbooksFromOpenAPI3BodylessPost(t *testing.T) {
+	rbs, err := NewRunbooksFromOpenAPI3(filepath.Join("testdata", "openapi3_petstore.yml"), OpenAPI3GroupByOperation, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, ok := rbs["adoptPet"]
+	if !ok {
+		t.Fatal("runbook adoptPet is not generated")
+	}
+	b, err := yaml.Marshal(rb.Steps[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var step map[string]any
+	if err := goyaml.Unmarshal(b, &step); err != nil {
+		t.Fatal(err)
+	}
+	r, err := parseHTTPRequest(step[openAPI3RunnerKey].(map[string]any))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewRunbooksFromOpenAPI3InvalidGroupBy(t *testing.T) {
+	if _, err := NewRunbooksFromOpenAPI3(filepath.Join("testdata", "openapi3_petstore.yml"), "path", ""); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestNewRunbooksFromOpenAPI3UnsafeName(t *testing.T) {
+	const tmpl = `openapi: 3.0.3
+info:
+  title: unsafe
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      tags:
+        - %q
+      responses:
+        '200':
+          description: OK
+`
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{"../../etc/x", "etc_x", false},
+		{"Users/Admin", "Users_Admin", false},
+		{"..", "", true},
+	}
+	for _, tt := range tests {
+		p := filepath.Join(t.TempDir(), "openapi3.yml")
+		if err := os.WriteFile(p, []byte(fmt.Sprintf(tmpl, tt.tag)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		rbs, err := NewRunbooksFromOpenAPI3(p, OpenAPI3GroupByTag, "")
+		if err != nil {
+			if !tt.wantErr {
+				t.Error(err)
+			}
+			continue
+		}
+		if tt.wantErr {
+			t.Error("want error")
+			continue
+		}
+		if _, ok := rbs[tt.want]; !ok || len(rbs) != 1 {
+			t.Errorf("got %v\nwant %v", rbs, tt.want)
+		}
+	}
+}
+
+func TestNewRunbooksFromOpenAPI3DuplicateName(t *testing.T) {
+	const doc = `openapi: 3.0.3
+info:
+  title: duplicate
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      operationId: a/b
+      tags:
+        - a/b
+      responses:
+        '200':
+          description: OK
+    post:
+      operationId: a_b
+      tags:
+        - a_b
+      responses:
+        '200':
+          description: OK
+  /posts:
+    get:
+      operationId: listPosts
+      tags:
+        - a_b
+      responses:
+        '200':
+          description: OK
+`
+	p := filepath.Join(t.TempDir(), "openapi3.yml")
+	if err := os.WriteFile(p, []byte(doc), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, groupBy := range []string{OpenAPI3GroupByOperation, OpenAPI3GroupByTag} {
+		if _, err := NewRunbooksFromOpenAPI3(p, groupBy, ""); err == nil {
+			t.Errorf("%s: want error", groupBy)
+		}
+	}
+}
@@ -115,6 +115,58 @@ func TestAppendStep(t *testing.T) {
 	}
 }
 
+func TestAppendStepsFromHAR(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		methods []string
+	}{
+		{"all", nil, nil},
+		{"host", []string{"example.com"}, nil},
+		{"host_with_port", []string{"localhost:8080"}, nil},
+		{"method", nil, []string{"post"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "browser.har"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := f.Close(); err != nil {
+					t.Error(err)
+				}
+			})
+			rb := NewRunbook(tt.name)
+			if err := rb.AppendStepsFromHAR(f, tt.hosts, tt.methods); err != nil {
+				t.Fatal(err)
+			}
+
+			got := new(bytes.Buffer)
+			enc := yaml.NewEncoder(got)
+			if err := enc.Encode(rb); err != nil {
+				t.Error(err)
+			}
+
+			f2 := fmt.Sprintf("browser_har_%s.append_step", tt.name)
+			if os.Getenv("UPDATE_GOLDEN") != "" {
+				golden.Update(t, "testdata", f2, got)
+				return
+			}
+			if diff := golden.Diff(t, "testdata", f2, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAppendStepsFromInvalidHAR(t *testing.T) {
+	rb := NewRunbook("")
+	if err := rb.AppendStepsFromHAR(strings.NewReader("not har"), nil, nil); err == nil {
+		t.Error("want error")
+	}
+}
+
 func TestDetectRunbookAreas(t *testing.T) {
 	tests := []struct {
 		runbook string
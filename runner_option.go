@@ -3,42 +3,73 @@ package runn
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
 type httpRunnerConfig struct {
-	Endpoint             string `yaml:"endpoint"`
-	OpenApi3DocLocation  string `yaml:"openapi3,omitempty"`
-	SkipValidateRequest  bool   `yaml:"skipValidateRequest,omitempty"`
-	SkipValidateResponse bool   `yaml:"skipValidateResponse,omitempty"`
-	NotFollowRedirect    bool   `yaml:"notFollowRedirect,omitempty"`
-	MultipartBoundary    string `yaml:"multipartBoundary,omitempty"`
-	CACert               string `yaml:"cacert,omitempty"`
-	Cert                 string `yaml:"cert,omitempty"`
-	Key                  string `yaml:"key,omitempty"`
-	SkipVerify           bool   `yaml:"skipVerify,omitempty"`
-	Timeout              string `yaml:"timeout,omitempty"`
-	UseCookie            *bool  `yaml:"useCookie,omitempty"`
+	Endpoint             string            `yaml:"endpoint"`
+	OpenApi3DocLocation  string            `yaml:"openapi3,omitempty"`
+	SkipValidateRequest  bool              `yaml:"skipValidateRequest,omitempty"`
+	SkipValidateResponse bool              `yaml:"skipValidateResponse,omitempty"`
+	NotFollowRedirect    bool              `yaml:"notFollowRedirect,omitempty"`
+	MultipartBoundary    string            `yaml:"multipartBoundary,omitempty"`
+	CACert               string            `yaml:"cacert,omitempty"`
+	Cert                 string            `yaml:"cert,omitempty"`
+	Key                  string            `yaml:"key,omitempty"`
+	SkipVerify           bool              `yaml:"skipVerify,omitempty"`
+	Timeout              string            `yaml:"timeout,omitempty"`
+	UseCookie            *bool             `yaml:"useCookie,omitempty"`
+	Auth                 *httpAuthConfig   `yaml:"auth,omitempty"`
+	Sign                 *httpSignConfig   `yaml:"sign,omitempty"`
+	Proxy                string            `yaml:"proxy,omitempty"`
+	Resolve              map[string]string `yaml:"resolve,omitempty"`
 
 	openApi3Doc *openapi3.T
 }
 
 type grpcRunnerConfig struct {
-	Addr        string   `yaml:"addr"`
-	TLS         *bool    `yaml:"tls,omitempty"`
-	CACert      string   `yaml:"cacert,omitempty"`
-	Cert        string   `yaml:"cert,omitempty"`
-	Key         string   `yaml:"key,omitempty"`
-	SkipVerify  bool     `yaml:"skipVerify,omitempty"`
-	ImportPaths []string `yaml:"importPaths,omitempty"`
-	Protos      []string `yaml:"protos,omitempty"`
+	Addr        string            `yaml:"addr"`
+	TLS         *bool             `yaml:"tls,omitempty"`
+	CACert      string            `yaml:"cacert,omitempty"`
+	Cert        string            `yaml:"cert,omitempty"`
+	Key         string            `yaml:"key,omitempty"`
+	SkipVerify  bool              `yaml:"skipVerify,omitempty"`
+	ImportPaths []string          `yaml:"importPaths,omitempty"`
+	Protos      []string          `yaml:"protos,omitempty"`
+	Protosets   []string          `yaml:"protosets,omitempty"`
+	BufImage    string            `yaml:"bufImage,omitempty"`
+	Proxy       string            `yaml:"proxy,omitempty"`
+	Resolve     map[string]string `yaml:"resolve,omitempty"`
+	// Timeout is the default timeout of each call
+	Timeout        string               `yaml:"timeout,omitempty"`
+	Keepalive      *grpcKeepaliveConfig `yaml:"keepalive,omitempty"`
+	MaxSendMsgSize int                  `yaml:"maxSendMsgSize,omitempty"`
+	MaxRecvMsgSize int                  `yaml:"maxRecvMsgSize,omitempty"`
+	Compression    string               `yaml:"compression,omitempty"`
+	Authority      string               `yaml:"authority,omitempty"`
+	Protocol       string               `yaml:"protocol,omitempty"`
 
 	cacert []byte
 	cert   []byte
 	key    []byte
 }
 
+type grpcKeepaliveConfig struct {
+	Time                string `yaml:"time,omitempty"`
+	Timeout             string `yaml:"timeout,omitempty"`
+	PermitWithoutStream bool   `yaml:"permitWithoutStream,omitempty"`
+}
+
+type wsRunnerConfig struct {
+	Endpoint   string `yaml:"endpoint"`
+	CACert     string `yaml:"cacert,omitempty"`
+	Cert       string `yaml:"cert,omitempty"`
+	Key        string `yaml:"key,omitempty"`
+	SkipVerify bool   `yaml:"skipVerify,omitempty"`
+}
+
 type sshRunnerConfig struct {
 	SSHConfig           string       `yaml:"sshConfig,omitempty"`
 	Host                string       `yaml:"host,omitempty"`
@@ -52,6 +83,20 @@ type sshRunnerConfig struct {
 	KeyboardInteractive []*sshAnswer `yaml:"keyboardInteractive,omitempty"`
 }
 
+type dbRunnerConfig struct {
+	DSN             string `yaml:"dsn"`
+	MaxOpenConns    int    `yaml:"maxOpenConns,omitempty"`
+	MaxIdleConns    int    `yaml:"maxIdleConns,omitempty"`
+	ConnMaxLifetime string `yaml:"connMaxLifetime,omitempty"`
+	ConnMaxIdleTime string `yaml:"connMaxIdleTime,omitempty"`
+	// Timeout is the timeout of each query
+	Timeout string `yaml:"timeout,omitempty"`
+	// InitSQL are the statements executed on each new connection
+	InitSQL       []string `yaml:"initSQL,omitempty"`
+	ReadOnly      bool     `yaml:"readOnly,omitempty"`
+	RollbackOnEnd bool     `yaml:"rollbackOnEnd,omitempty"`
+}
+
 type sshAnswer struct {
 	Match  string `yaml:"match"`
 	Answer string `yaml:"answer"`
@@ -61,8 +106,12 @@ type httpRunnerOption func(*httpRunnerConfig) error
 
 type grpcRunnerOption func(*grpcRunnerConfig) error
 
+type wsRunnerOption func(*wsRunnerConfig) error
+
 type sshRunnerOption func(*sshRunnerConfig) error
 
+type dbRunnerOption func(*dbRunnerConfig) error
+
 func (c *sshRunnerConfig) validate() error {
 	if c.Host == "" && c.Hostname == "" {
 		return fmt.Errorf("host or hostname is required")
@@ -170,6 +219,131 @@ func UseCookie(use bool) httpRunnerOption {
 	}
 }
 
+// HTTPBasicAuth sets credentials for Basic authentication.
+func HTTPBasicAuth(username, password string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Auth = &httpAuthConfig{
+			Type:     HTTPAuthTypeBasic,
+			Username: username,
+			Password: password,
+		}
+		return nil
+	}
+}
+
+// HTTPBearerAuth sets the token for Bearer authentication.
+func HTTPBearerAuth(token string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Auth = &httpAuthConfig{
+			Type:  HTTPAuthTypeBearer,
+			Token: token,
+		}
+		return nil
+	}
+}
+
+// HTTPDigestAuth sets credentials for Digest authentication.
+func HTTPDigestAuth(username, password string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Auth = &httpAuthConfig{
+			Type:     HTTPAuthTypeDigest,
+			Username: username,
+			Password: password,
+		}
+		return nil
+	}
+}
+
+// HTTPOAuth2ClientCredentials sets the config to get the token using the OAuth2 client credentials grant.
+func HTTPOAuth2ClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Auth = &httpAuthConfig{
+			Type:         HTTPAuthTypeOAuth2,
+			GrantType:    OAuth2GrantTypeClientCredentials,
+			TokenURL:     tokenURL,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Scopes:       scopes,
+		}
+		return nil
+	}
+}
+
+// HTTPOAuth2Password sets the config to get the token using the OAuth2 resource owner password credentials grant.
+func HTTPOAuth2Password(tokenURL, clientID, clientSecret, username, password string, scopes ...string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Auth = &httpAuthConfig{
+			Type:         HTTPAuthTypeOAuth2,
+			GrantType:    OAuth2GrantTypePassword,
+			TokenURL:     tokenURL,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Username:     username,
+			Password:     password,
+			Scopes:       scopes,
+		}
+		return nil
+	}
+}
+
+// HTTPSignSigV4 sets the config to sign requests with AWS Signature Version 4.
+// If accessKeyID and secretAccessKey are empty, the credentials are read from environment variables.
+func HTTPSignSigV4(region, service, accessKeyID, secretAccessKey, sessionToken string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Sign = &httpSignConfig{
+			Type:            HTTPSignTypeSigV4,
+			Region:          region,
+			Service:         service,
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    sessionToken,
+		}
+		return nil
+	}
+}
+
+// HTTPSignHMAC sets the config to sign requests with HMAC over the components of the request.
+// If components are not specified, method, path, timestamp and body are signed.
+func HTTPSignHMAC(secret, algorithm string, components ...string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Sign = &httpSignConfig{
+			Type:       HTTPSignTypeHMAC,
+			Secret:     secret,
+			Algorithm:  algorithm,
+			Components: components,
+		}
+		return nil
+	}
+}
+
+// HandlerEndpoint sets the endpoint ( scheme, host and base path ) of requests to http.Handler of HTTPRunnerWithHandler.
+// The default is http://example.com ( same as httptest.NewRequest ).
+func HandlerEndpoint(endpoint string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Endpoint = endpoint
+		return nil
+	}
+}
+
+// HTTPProxy sets the proxy URL (http://, https:// or socks5://).
+func HTTPProxy(u string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		c.Proxy = u
+		return nil
+	}
+}
+
+// HTTPResolve overrides the address of host (host or host:port) with addr (ip or ip:port) like `curl --resolve`.
+func HTTPResolve(host, addr string) httpRunnerOption {
+	return func(c *httpRunnerConfig) error {
+		if c.Resolve == nil {
+			c.Resolve = map[string]string{}
+		}
+		c.Resolve[host] = addr
+		return nil
+	}
+}
+
 func TLS(useTLS bool) grpcRunnerOption {
 	return func(c *grpcRunnerConfig) error {
 		c.TLS = &useTLS
@@ -235,6 +409,133 @@ func ImportPaths(paths []string) grpcRunnerOption {
 	}
 }
 
+// Protosets append protoset files (FileDescriptorSet).
+func Protosets(paths []string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Protosets = unique(append(c.Protosets, paths...))
+		return nil
+	}
+}
+
+// BufImage sets the buf image file.
+func BufImage(path string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.BufImage = path
+		return nil
+	}
+}
+
+// Proxy sets the proxy URL (http:// or socks5://).
+func Proxy(u string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Proxy = u
+		return nil
+	}
+}
+
+// Resolve overrides the address of host (host or host:port) with addr (ip or ip:port) like `curl --resolve`.
+func Resolve(host, addr string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		if c.Resolve == nil {
+			c.Resolve = map[string]string{}
+		}
+		c.Resolve[host] = addr
+		return nil
+	}
+}
+
+// GRPCTimeout sets the default timeout of each call.
+func GRPCTimeout(d time.Duration) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Timeout = d.String()
+		return nil
+	}
+}
+
+// GRPCKeepalive sets the keepalive parameters of the connection.
+func GRPCKeepalive(t, timeout time.Duration, permitWithoutStream bool) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Keepalive = &grpcKeepaliveConfig{
+			PermitWithoutStream: permitWithoutStream,
+		}
+		if t > 0 {
+			c.Keepalive.Time = t.String()
+		}
+		if timeout > 0 {
+			c.Keepalive.Timeout = timeout.String()
+		}
+		return nil
+	}
+}
+
+// GRPCMaxSendMsgSize sets the max size of the message the client can send.
+func GRPCMaxSendMsgSize(n int) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.MaxSendMsgSize = n
+		return nil
+	}
+}
+
+// GRPCMaxRecvMsgSize sets the max size of the message the client can receive.
+func GRPCMaxRecvMsgSize(n int) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.MaxRecvMsgSize = n
+		return nil
+	}
+}
+
+// GRPCCompression sets the compressor of the messages (gzip).
+func GRPCCompression(name string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Compression = name
+		return nil
+	}
+}
+
+// GRPCAuthority sets the value of the :authority pseudo-header.
+func GRPCAuthority(a string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Authority = a
+		return nil
+	}
+}
+
+// GRPCProtocol sets the protocol (grpc, grpcweb or connect).
+func GRPCProtocol(p string) grpcRunnerOption {
+	return func(c *grpcRunnerConfig) error {
+		c.Protocol = p
+		return nil
+	}
+}
+
+func WSCACert(path string) wsRunnerOption {
+	return func(c *wsRunnerConfig) error {
+		c.CACert = path
+		return nil
+	}
+}
+
+func WSCert(path string) wsRunnerOption {
+	return func(c *wsRunnerConfig) error {
+		c.Cert = path
+		return nil
+	}
+}
+
+func WSKey(path string) wsRunnerOption {
+	return func(c *wsRunnerConfig) error {
+		c.Key = path
+		return nil
+	}
+}
+
+func WSSkipVerify(skip bool) wsRunnerOption {
+	return func(c *wsRunnerConfig) error {
+		c.SkipVerify = skip
+		return nil
+	}
+}
+
 func SSHConfig(p string) sshRunnerOption {
 	return func(c *sshRunnerConfig) error {
 		c.SSHConfig = p
@@ -297,3 +598,27 @@ func LocalForward(l string) sshRunnerOption {
 		return nil
 	}
 }
+
+// DBRollbackOnEnd sets whether to wrap the whole runbook in one transaction and always roll it back.
+func DBRollbackOnEnd(enable bool) dbRunnerOption {
+	return func(c *dbRunnerConfig) error {
+		c.RollbackOnEnd = enable
+		return nil
+	}
+}
+
+// DBTimeout sets the timeout of each query.
+func DBTimeout(d time.Duration) dbRunnerOption {
+	return func(c *dbRunnerConfig) error {
+		c.Timeout = d.String()
+		return nil
+	}
+}
+
+// DBReadOnly sets whether to reject the statements other than SELECT and the like.
+func DBReadOnly(enable bool) dbRunnerOption {
+	return func(c *dbRunnerConfig) error {
+		c.ReadOnly = enable
+		return nil
+	}
+}
@@ -112,7 +112,34 @@ func setRoutes(r *httpstub.Router) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(fmt.Sprintf(`{"sleep": %d}`, i)))
 	})
+	r.Method(http.MethodGet).Path("/events").Header("Content-Type", "text/event-stream").Handler(func(w http.ResponseWriter, r *http.Request) {
+		// Server-Sent Events that ends with the `done` event
+		for i := 1; i <= 3; i++ {
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: tick\ndata: {\"count\": %d}\n\n", i, i)
+			flush(w)
+		}
+		_, _ = fmt.Fprint(w, ": bye\nevent: done\ndata: bye\n\n")
+		flush(w)
+	})
+	r.Method(http.MethodGet).Path("/events.ndjson").Header("Content-Type", "application/x-ndjson").Handler(func(w http.ResponseWriter, r *http.Request) {
+		// NDJSON that continues until the client disconnects
+		for i := 1; i <= 1000; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+			_, _ = fmt.Fprintf(w, "{\"count\": %d}\n", i)
+			flush(w)
+		}
+	})
 	r.Method(http.MethodGet).Path("/hello").Header("Content-Type", "text/html; charset=utf-8").ResponseString(http.StatusOK, "<h1>Hello</h1>")
 	r.Method(http.MethodPost).Path("/upload").Header("Content-Type", "text/html; charset=utf-8").ResponseString(http.StatusCreated, "<h1>Posted</h1>")
 	r.Method(http.MethodGet).Header("Content-Type", "text/html; charset=utf-8").ResponseString(http.StatusNotFound, "<h1>Not Found</h1>")
 }
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
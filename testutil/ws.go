@@ -0,0 +1,78 @@
+package testutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSServer returns the WebSocket server for testing.
+//
+//	/echo: echo back messages.
+//	/headers: send the Authorization header as the first message, then echo back messages.
+//	/bye: send a message, then close the connection.
+//	/silent: read messages, but never send messages.
+func WSServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{"chat.v1", "chat.v2"},
+	}
+	mux := http.NewServeMux()
+	echo := func(conn *websocket.Conn) {
+		for {
+			typ, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(typ, b); err != nil {
+				return
+			}
+		}
+	}
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		echo(conn)
+	})
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if err := conn.WriteJSON(map[string]string{"authorization": r.Header.Get("Authorization")}); err != nil {
+			return
+		}
+		echo(conn)
+	})
+	mux.HandleFunc("/silent", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/bye", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("bye"))
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "see you"))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		ts.Close()
+	})
+	return ts
+}
@@ -17,6 +17,7 @@ const (
 	RunnerTypeHTTP    RunnerType = "http"
 	RunnerTypeDB      RunnerType = "db"
 	RunnerTypeGRPC    RunnerType = "grpc"
+	RunnerTypeWS      RunnerType = "ws"
 	RunnerTypeCDP     RunnerType = "cdp"
 	RunnerTypeSSH     RunnerType = "ssh"
 	RunnerTypeExec    RunnerType = "exec"
@@ -0,0 +1,293 @@
+package runn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/gorilla/websocket"
+	"github.com/k1LoW/runn/version"
+)
+
+type WSOp string
+
+const (
+	WSOpMessage WSOp = "message"
+	WSOpReceive WSOp = "receive"
+	WSOpClose   WSOp = "close"
+)
+
+const (
+	wsStoreStatusKey      = "status"
+	wsStoreHeaderKey      = "headers"
+	wsStoreSubprotocolKey = "subprotocol"
+	wsStoreMessageKey     = "message"
+	wsStoreMessagesKey    = "messages"
+	wsStoreCloseKey       = "close"
+	wsStoreResponseKey    = "res"
+)
+
+const (
+	wsHandshakeTimeout = 30 * time.Second
+	wsCloseTimeout     = 5 * time.Second
+)
+
+// wsReceiveTimeout is the default timeout of receiving each message when the timeout of the step is not set.
+var wsReceiveTimeout = 30 * time.Second
+
+type wsRunner struct {
+	name       string
+	endpoint   *url.URL
+	cacert     []byte
+	cert       []byte
+	key        []byte
+	skipVerify bool
+	operator   *operator
+}
+
+type wsMessage struct {
+	op WSOp
+	// params is the message sent as JSON (map) or as it is (string).
+	params any
+}
+
+type wsRequest struct {
+	path         string
+	headers      http.Header
+	subprotocols []string
+	messages     []*wsMessage
+	timeout      time.Duration
+}
+
+func newWSRunner(name, endpoint string) (*wsRunner, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("invalid WebSocket endpoint: %s", endpoint)
+	}
+	return &wsRunner{
+		name:     name,
+		endpoint: u,
+	}, nil
+}
+
+func isWSEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "ws://") || strings.HasPrefix(endpoint, "wss://")
+}
+
+func (rnr *wsRunner) Run(ctx context.Context, r *wsRequest) error {
+	u, err := mergeURL(rnr.endpoint, r.path)
+	if err != nil {
+		return err
+	}
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	d := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: wsHandshakeTimeout,
+		Subprotocols:     r.subprotocols,
+	}
+	if u.Scheme == "wss" {
+		tlsc, err := rnr.tlsConfig()
+		if err != nil {
+			return err
+		}
+		d.TLSClientConfig = tlsc
+	}
+	h := r.headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	if h.Get("User-Agent") == "" {
+		h.Set("User-Agent", fmt.Sprintf("runn/%s", version.Version))
+	}
+
+	rnr.operator.capturers.captureWSStart(rnr.name, u.String(), r.subprotocols)
+	defer rnr.operator.capturers.captureWSEnd(rnr.name, u.String())
+	rnr.operator.capturers.captureWSRequestHeaders(r.headers)
+
+	conn, res, err := d.DialContext(ctx, u.String(), h)
+	if err != nil {
+		if res != nil {
+			return fmt.Errorf("failed to connect to %s: %w: %s", u.String(), err, res.Status)
+		}
+		return fmt.Errorf("failed to connect to %s: %w", u.String(), err)
+	}
+	defer conn.Close()
+
+	// Close the connection to interrupt reading and writing when the context is done.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	rnr.operator.capturers.captureWSResponseHeaders(res.Header)
+
+	v := map[string]any{
+		wsStoreStatusKey:      res.StatusCode,
+		wsStoreHeaderKey:      res.Header,
+		wsStoreSubprotocolKey: conn.Subprotocol(),
+		wsStoreMessageKey:     nil,
+		wsStoreCloseKey:       nil,
+	}
+	messages := []any{}
+L:
+	for _, m := range r.messages {
+		switch m.op {
+		case WSOpMessage:
+			msg, err := rnr.expandMessage(m.params)
+			if err != nil {
+				return err
+			}
+			var b []byte
+			switch v := msg.(type) {
+			case string:
+				b = []byte(v)
+			default:
+				b, err = json.Marshal(v)
+				if err != nil {
+					return err
+				}
+			}
+			rnr.operator.capturers.captureWSRequestMessage(msg)
+			if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return ctxErr(ctx, err)
+			}
+		case WSOpReceive:
+			if r.timeout == 0 {
+				// Do not wait forever for the message from the silent server.
+				_ = conn.SetReadDeadline(time.Now().Add(wsReceiveTimeout))
+			}
+			msg, err := readWSMessage(conn)
+			if err != nil {
+				var ce *websocket.CloseError
+				if errors.As(err, &ce) {
+					// The server closed the connection.
+					v[wsStoreCloseKey] = wsCloseToStore(ce)
+					break L
+				}
+				var ne net.Error
+				if r.timeout == 0 && errors.As(err, &ne) && ne.Timeout() {
+					return fmt.Errorf("failed to receive the message within %s: %w", wsReceiveTimeout, err)
+				}
+				return ctxErr(ctx, err)
+			}
+			rnr.operator.capturers.captureWSResponseMessage(msg)
+			v[wsStoreMessageKey] = msg
+			messages = append(messages, msg)
+		case WSOpClose:
+			rnr.operator.capturers.captureWSClientClose()
+			cm := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			if err := conn.WriteControl(websocket.CloseMessage, cm, time.Now().Add(wsCloseTimeout)); err != nil {
+				return ctxErr(ctx, err)
+			}
+			// Wait for the close message from the server. Messages received until then are discarded.
+			_ = conn.SetReadDeadline(time.Now().Add(wsCloseTimeout))
+			for {
+				if _, err := readWSMessage(conn); err != nil {
+					var ce *websocket.CloseError
+					if errors.As(err, &ce) {
+						v[wsStoreCloseKey] = wsCloseToStore(ce)
+					}
+					break
+				}
+			}
+			break L
+		default:
+			return fmt.Errorf("invalid op: %v", m.op)
+		}
+	}
+	v[wsStoreMessagesKey] = messages
+
+	rnr.operator.record(map[string]any{
+		wsStoreResponseKey: v,
+	})
+
+	return nil
+}
+
+// expandMessage expands the message lazily due to the possibility of computing variables between multiple messages.
+func (rnr *wsRunner) expandMessage(message any) (any, error) {
+	e, err := rnr.operator.expandBeforeRecord(message)
+	if err != nil {
+		return nil, err
+	}
+	switch e.(type) {
+	case map[string]any, string:
+		return e, nil
+	default:
+		return nil, fmt.Errorf("invalid message: %v", e)
+	}
+}
+
+func (rnr *wsRunner) tlsConfig() (*tls.Config, error) {
+	tlsc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if rnr.cert != nil {
+		certificate, err := tls.X509KeyPair(rnr.cert, rnr.key)
+		if err != nil {
+			return nil, err
+		}
+		tlsc.Certificates = []tls.Certificate{certificate}
+	}
+	if rnr.skipVerify {
+		//#nosec G402
+		tlsc.InsecureSkipVerify = true
+	} else if rnr.cacert != nil {
+		certpool, err := x509.SystemCertPool()
+		if err != nil {
+			// FIXME for Windows
+			// ref: https://github.com/golang/go/issues/18609
+			certpool = x509.NewCertPool()
+		}
+		if ok := certpool.AppendCertsFromPEM(rnr.cacert); !ok {
+			return nil, errors.New("failed to append cacert")
+		}
+		tlsc.RootCAs = certpool
+	}
+	return tlsc, nil
+}
+
+// readWSMessage reads a message. The text message is decoded as JSON when possible.
+func readWSMessage(conn *websocket.Conn) (any, error) {
+	typ, b, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if typ == websocket.BinaryMessage {
+		return string(b), nil
+	}
+	return decodeJSONOrString(string(b)), nil
+}
+
+func wsCloseToStore(ce *websocket.CloseError) map[string]any {
+	return map[string]any{
+		"code": ce.Code,
+		"text": ce.Text,
+	}
+}
+
+// ctxErr returns the error of the context instead of the error of the closed connection.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package runn
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/k1LoW/runn/testutil"
+)
+
+func TestWSRunner(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.WSServer(t)
+	endpoint := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		name            string
+		req             *wsRequest
+		wantMessages    []any
+		wantSubprotocol string
+		wantClose       any
+	}{
+		{
+			"echo",
+			&wsRequest{
+				path: "/echo",
+				messages: []*wsMessage{
+					{op: WSOpMessage, params: map[string]any{"name": "alice"}},
+					{op: WSOpReceive},
+				},
+			},
+			[]any{map[string]any{"name": "alice"}},
+			"",
+			nil,
+		},
+		{
+			"text message",
+			&wsRequest{
+				path: "/echo",
+				messages: []*wsMessage{
+					{op: WSOpMessage, params: "hello"},
+					{op: WSOpReceive},
+					{op: WSOpMessage, params: `{"name":"carol"}`},
+					{op: WSOpReceive},
+				},
+			},
+			[]any{"hello", map[string]any{"name": "carol"}},
+			"",
+			nil,
+		},
+		{
+			"headers and subprotocols",
+			&wsRequest{
+				path:         "/headers",
+				headers:      http.Header{"Authorization": []string{"Bearer t0ken"}},
+				subprotocols: []string{"chat.v3", "chat.v2"},
+				messages: []*wsMessage{
+					{op: WSOpReceive},
+					{op: WSOpMessage, params: map[string]any{"name": "bob"}},
+					{op: WSOpReceive},
+					{op: WSOpClose},
+				},
+			},
+			[]any{map[string]any{"authorization": "Bearer t0ken"}, map[string]any{"name": "bob"}},
+			"chat.v2",
+			map[string]any{"code": 1000, "text": ""},
+		},
+		{
+			"closed by server",
+			&wsRequest{
+				path: "/bye",
+				messages: []*wsMessage{
+					{op: WSOpReceive},
+					{op: WSOpReceive},
+					{op: WSOpReceive},
+				},
+			},
+			[]any{"bye"},
+			"",
+			map[string]any{"code": 1001, "text": "see you"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			o, err := New(WSRunner("ws", endpoint), Capture(NewDebugger(out)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.wsRunners["ws"].Run(ctx, tt.req); err != nil {
+				t.Fatal(err)
+			}
+			res := o.store.latest()["res"].(map[string]any)
+			if got := res["status"]; got != http.StatusSwitchingProtocols {
+				t.Errorf("got %v\nwant %v", got, http.StatusSwitchingProtocols)
+			}
+			if diff := cmp.Diff(res["messages"], tt.wantMessages, nil); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(res["message"], tt.wantMessages[len(tt.wantMessages)-1], nil); diff != "" {
+				t.Error(diff)
+			}
+			if got := res["subprotocol"]; got != tt.wantSubprotocol {
+				t.Errorf("got %v\nwant %v", got, tt.wantSubprotocol)
+			}
+			if diff := cmp.Diff(res["close"], tt.wantClose, nil); diff != "" {
+				t.Error(diff)
+			}
+			if got := strings.Count(out.String(), "-----START WebSocket RESPONSE MESSAGE-----"); got != len(tt.wantMessages) {
+				t.Errorf("got %v\nwant %v\n%s", got, len(tt.wantMessages), out.String())
+			}
+		})
+	}
+}
+
+func TestWSRunnerReceiveTimeout(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.WSServer(t)
+	endpoint := "ws" + strings.TrimPrefix(ts.URL, "http")
+	orig := wsReceiveTimeout
+	wsReceiveTimeout = 100 * time.Millisecond
+	t.Cleanup(func() {
+		wsReceiveTimeout = orig
+	})
+	o, err := New(WSRunner("ws", endpoint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &wsRequest{
+		path: "/silent",
+		messages: []*wsMessage{
+			{op: WSOpMessage, params: "hello"},
+			{op: WSOpReceive},
+		},
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- o.wsRunners["ws"].Run(ctx, req)
+	}()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("want error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("receiving from the silent server should time out")
+	}
+}
+
+func TestNewWSRunner(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		wantErr  bool
+	}{
+		{"ws://example.com", false},
+		{"wss://example.com/base", false},
+		{"https://example.com", true},
+	}
+	for _, tt := range tests {
+		_, err := newWSRunner("ws", tt.endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("got %v\nwantErr %v", err, tt.wantErr)
+		}
+	}
+}
+
+func TestWSRunbook(t *testing.T) {
+	ctx := context.Background()
+	ts := testutil.WSServer(t)
+	t.Setenv("TEST_WS_END_POINT", "ws"+strings.TrimPrefix(ts.URL, "http"))
+	o, err := New(Book(filepath.Join(testutil.Testdata(), "book", "ws.yml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(ctx); err != nil {
+		t.Error(err)
+	}
+}
@@ -17,8 +17,15 @@ import (
 // CreateHTTPStepMapSlice creates yaml.MapSlice from *http.Request.
 func CreateHTTPStepMapSlice(key string, req *http.Request) (yaml.MapSlice, error) {
 	endpoint := req.URL.Path
+	var query url.Values
 	if req.URL.RawQuery != "" {
-		endpoint = fmt.Sprintf("%s?%s", endpoint, req.URL.RawQuery)
+		q, err := url.ParseQuery(req.URL.RawQuery)
+		if err != nil {
+			// Keep the query string that cannot be represented as `query:` in the path
+			endpoint = fmt.Sprintf("%s?%s", endpoint, req.URL.RawQuery)
+		} else {
+			query = q
+		}
 	}
 	if endpoint == "" {
 		endpoint = "/"
@@ -27,11 +34,19 @@ func CreateHTTPStepMapSlice(key string, req *http.Request) (yaml.MapSlice, error
 	hb := yaml.MapSlice{}
 	// headers
 	contentType := req.Header.Get("Content-Type")
+	// The compressed body is recorded as the decoded body with compress:
+	var compress string
+	if encs := contentEncodings(req.Header); len(encs) == 1 && req.Body != nil && req.Body != http.NoBody {
+		compress = encs[0]
+	}
 	h := map[string]string{}
 	for k, v := range req.Header {
 		if k == "Content-Type" || k == "Host" {
 			continue
 		}
+		if k == "Content-Encoding" && compress != "" {
+			continue
+		}
 		h[k] = v[0]
 	}
 	if len(h) > 0 {
@@ -41,6 +56,22 @@ func CreateHTTPStepMapSlice(key string, req *http.Request) (yaml.MapSlice, error
 		})
 	}
 
+	// query
+	if len(query) > 0 {
+		q := map[string]any{}
+		for k, v := range query {
+			if len(v) == 1 {
+				q[k] = v[0]
+				continue
+			}
+			q[k] = v
+		}
+		hb = append(hb, yaml.MapItem{
+			Key:   "query",
+			Value: q,
+		})
+	}
+
 	// body
 	var bd yaml.MapSlice
 	var (
@@ -51,6 +82,18 @@ func CreateHTTPStepMapSlice(key string, req *http.Request) (yaml.MapSlice, error
 	if err != nil {
 		return nil, fmt.Errorf("failed to drainBody: %w", err)
 	}
+	if compress != "" {
+		compressed := save
+		b, err := decodeRequestBody(&http.Request{Header: req.Header, Body: req.Body})
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode body: %w", err)
+		}
+		save = io.NopCloser(bytes.NewReader(b))
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		defer func() {
+			req.Body = compressed
+		}()
+	}
 	switch {
 	case save == http.NoBody || save == nil:
 		if contentType == "" {
@@ -159,6 +202,12 @@ func CreateHTTPStepMapSlice(key string, req *http.Request) (yaml.MapSlice, error
 			Value: bd,
 		})
 	}
+	if compress != "" {
+		hb = append(hb, yaml.MapItem{
+			Key:   "compress",
+			Value: compress,
+		})
+	}
 
 	m := yaml.MapItem{Key: strings.ToLower(req.Method), Value: nil}
 	if len(hb) > 0 {